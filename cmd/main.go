@@ -2,11 +2,43 @@ package main
 
 import (
 	"monkey/cmd/repl"
+	"monkey/cmd/runner"
 	"os"
 )
 
 func main() {
-	
+
+	if len(os.Args) == 3 {
+		switch os.Args[1] {
+
+		// `build file.mk` compile the file to file.mkc.
+		case "build":
+			os.Exit(runner.Build(os.Args[2], os.Stderr))
+
+		// `disasm file.mk` print the bytecode of the file.
+		case "disasm":
+			os.Exit(runner.Disasm(os.Args[2], os.Stdout, os.Stderr))
+
+		// `typecheck file.mk` print the inferred types of the file.
+		case "typecheck":
+			os.Exit(runner.Typecheck(os.Args[2], os.Stdout, os.Stderr))
+
+		// `analyze file.mk` print the diagnostics of the file.
+		case "analyze":
+			os.Exit(runner.Analyze(os.Args[2], false, os.Stdout, os.Stderr))
+		}
+	}
+
+	// `analyze -json file.mk` print them as JSON, for editors.
+	if len(os.Args) == 4 && os.Args[1] == "analyze" && os.Args[2] == "-json" {
+		os.Exit(runner.Analyze(os.Args[3], true, os.Stdout, os.Stderr))
+	}
+
+	// With a file argument, run the file instead of starting the REPL.
+	if len(os.Args) > 1 {
+		os.Exit(runner.Run(os.Args[1], os.Stdout, os.Stderr))
+	}
+
 	repl.Start(os.Stdin, os.Stdout)
 
 }
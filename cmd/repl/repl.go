@@ -6,6 +6,7 @@ import (
 	"io"
 	"monkey/internal/evaluator"
 	"monkey/internal/lexer"
+	"monkey/internal/object"
 	"monkey/internal/parser"
 	"time"
 )
@@ -15,6 +16,8 @@ const HELP_COMMAND = "help"
 
 func Start(input io.Reader, output io.Writer) {
 	scanner := bufio.NewScanner(input)
+	env := object.NewEnvironment()
+	ev := evaluator.New()
 
 	printDatetime()
 	fmt.Printf("Type %q for more information.\n", HELP_COMMAND)
@@ -42,7 +45,12 @@ func Start(input io.Reader, output io.Writer) {
 			continue
 		}
 
-		result := evaluator.Eval(program)
+		result := ev.Eval(program, env)
+
+		if err, ok := result.(*object.Error); ok {
+			io.WriteString(output, err.StackTrace())
+			continue
+		}
 		
 		if result != nil {
 			io.WriteString(output, result.Inspect())
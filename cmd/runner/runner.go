@@ -0,0 +1,292 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey/internal/analysis"
+	"monkey/internal/ast"
+	"monkey/internal/checker"
+	"monkey/internal/compiler"
+	"monkey/internal/diagnostic"
+	"monkey/internal/evaluator"
+	"monkey/internal/lexer"
+	"monkey/internal/object"
+	"monkey/internal/optimizer"
+	"monkey/internal/parser"
+	"monkey/internal/resolver"
+	"monkey/internal/vm"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MODULE_PATH_VARIABLE is the environment variable holding the list
+// of directories where imported files are looked up, separated like
+// the directories of PATH.
+const MODULE_PATH_VARIABLE = "MONKEY_PATH"
+
+// COMPILED_EXTENSION is the extension of the files holding
+// compiled programs, run by the virtual machine.
+const COMPILED_EXTENSION = ".mkc"
+
+// Run evaluate the Monkey file at path and write the value of its
+// last statement to output. Parsing, resolution, type and runtime
+// errors, the latter with their stack trace, are written to errOutput,
+// along with the resolution warnings.
+// A compiled file is run by the virtual machine instead.
+// It return the exit status of the program.
+func Run(path string, output, errOutput io.Writer) int {
+	if filepath.Ext(path) == COMPILED_EXTENSION {
+		return runCompiled(path, output, errOutput)
+	}
+
+	program, ok := parseFile(path, errOutput)
+
+	if !ok || !resolveProgram(program, errOutput) {
+		return 1
+	}
+
+	ev := evaluator.New()
+	ev.ModulePath = modulePath()
+
+	return report(ev.Eval(program, object.NewEnvironment()), output, errOutput)
+}
+
+// Build compile the Monkey file at path and write the bytecode next to
+// it, in a file with the same name and the COMPILED_EXTENSION.
+// It return the exit status of the compilation.
+func Build(path string, errOutput io.Writer) int {
+	program, ok := parseFile(path, errOutput)
+
+	if !ok || !resolveProgram(program, errOutput) {
+		return 1
+	}
+	bytecode, err := compiler.Compile(program)
+
+	if err != nil {
+		fmt.Fprintln(errOutput, err)
+		return 1
+	}
+	data, err := compiler.Marshal(bytecode)
+
+	if err != nil {
+		fmt.Fprintln(errOutput, err)
+		return 1
+	}
+
+	target := strings.TrimSuffix(path, filepath.Ext(path)) + COMPILED_EXTENSION
+
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		fmt.Fprintln(errOutput, err)
+		return 1
+	}
+
+	return 0
+}
+
+// Disasm write the disassembled bytecode of the Monkey file at path to
+// output, along with the source lines it was compiled from. The file
+// can be a script or a compiled file, whose source is then read from
+// the path recorded at compilation, if it's still there.
+// It return the exit status of the command.
+func Disasm(path string, output, errOutput io.Writer) int {
+	var bytecode *compiler.Bytecode
+
+	if filepath.Ext(path) == COMPILED_EXTENSION {
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			fmt.Fprintln(errOutput, err)
+			return 1
+		}
+
+		if bytecode, err = compiler.Unmarshal(data); err != nil {
+			fmt.Fprintf(errOutput, "%s: %s\n", path, err)
+			return 1
+		}
+	} else {
+		program, ok := parseFile(path, errOutput)
+
+		if !ok {
+			return 1
+		}
+		var err error
+
+		// The listing is the one of the built file, whose local
+		// names are compiled to slots.
+		resolver.Resolve(program)
+
+		if bytecode, err = compiler.Compile(program); err != nil {
+			fmt.Fprintln(errOutput, err)
+			return 1
+		}
+	}
+
+	source, _ := os.ReadFile(bytecode.Main.File)
+	compiler.Disassemble(output, bytecode, string(source))
+
+	return 0
+}
+
+// Typecheck infer the types of the Monkey file at path, annotated or
+// not, and write the signature of each of its top-level bindings to
+// output. Parsing errors, and the diagnostics of the resolution and
+// the inference, are written to errOutput.
+// It return the exit status of the command, 1 if there are errors.
+func Typecheck(path string, output, errOutput io.Writer) int {
+	program, ok := parseFile(path, errOutput)
+
+	if !ok {
+		return 1
+	}
+	signatures, inferred := checker.Infer(program)
+
+	diagnostics := append(resolver.Resolve(program), inferred...)
+	diagnostic.Sort(diagnostics)
+
+	for _, signature := range signatures {
+		io.WriteString(output, signature.String())
+		io.WriteString(output, "\n")
+	}
+
+	for _, diagnostic := range diagnostics {
+		io.WriteString(errOutput, diagnostic.String())
+		io.WriteString(errOutput, "\n")
+	}
+
+	if diagnostic.HasErrors(diagnostics) {
+		return 1
+	}
+
+	return 0
+}
+
+// Analyze write to output the diagnostics of the Monkey file at path:
+// the ones of the resolution and the type checking, and the findings of
+// the analysis of its control and data flow, like unreachable code or
+// unused variables. They are written one per line, or as a JSON array
+// for the tools reading them when asJSON is true. Parsing errors are
+// written to errOutput.
+// It return the exit status of the command, 1 if there are errors.
+func Analyze(path string, asJSON bool, output, errOutput io.Writer) int {
+	program, ok := parseFile(path, errOutput)
+
+	if !ok {
+		return 1
+	}
+	// Not nil, so that a file without findings is written as [], not null.
+	diagnostics := []diagnostic.Diagnostic{}
+	diagnostics = append(diagnostics, resolver.Resolve(program)...)
+	diagnostics = append(diagnostics, checker.Check(program)...)
+	diagnostics = append(diagnostics, analysis.Analyze(program)...)
+	diagnostic.Sort(diagnostics)
+
+	if asJSON {
+		data, err := json.Marshal(diagnostics)
+
+		if err != nil {
+			fmt.Fprintln(errOutput, err)
+			return 1
+		}
+		output.Write(data)
+		io.WriteString(output, "\n")
+	} else {
+		for _, diagnostic := range diagnostics {
+			io.WriteString(output, diagnostic.String())
+			io.WriteString(output, "\n")
+		}
+	}
+
+	if diagnostic.HasErrors(diagnostics) {
+		return 1
+	}
+
+	return 0
+}
+
+func runCompiled(path string, output, errOutput io.Writer) int {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		fmt.Fprintln(errOutput, err)
+		return 1
+	}
+	bytecode, err := compiler.Unmarshal(data)
+
+	if err != nil {
+		fmt.Fprintf(errOutput, "%s: %s\n", path, err)
+		return 1
+	}
+
+	machine := vm.New()
+	machine.ModulePath = modulePath()
+
+	return report(machine.Run(bytecode, object.NewEnvironment()), output, errOutput)
+}
+
+// parseFile parse and optimize the Monkey file at path, writing
+// the parsing errors to errOutput if there are some.
+func parseFile(path string, errOutput io.Writer) (*ast.Program, bool) {
+	source, err := os.ReadFile(path)
+
+	if err != nil {
+		fmt.Fprintln(errOutput, err)
+		return nil, false
+	}
+
+	lex := lexer.NewFile(path, string(source))
+	parser := parser.New(lex)
+
+	program := parser.ParseProgram()
+
+	if len(parser.Errors()) != 0 {
+		for _, errMsg := range parser.Errors() {
+			io.WriteString(errOutput, errMsg)
+			io.WriteString(errOutput, "\n")
+		}
+		return nil, false
+	}
+
+	return optimizer.Optimize(program), true
+}
+
+// resolveProgram resolve the names of program and check its types,
+// writing the diagnostics to errOutput. It return false if some of
+// them are errors.
+func resolveProgram(program *ast.Program, errOutput io.Writer) bool {
+	diagnostics := append(resolver.Resolve(program), checker.Check(program)...)
+	diagnostic.Sort(diagnostics)
+
+	for _, diagnostic := range diagnostics {
+		io.WriteString(errOutput, diagnostic.String())
+		io.WriteString(errOutput, "\n")
+	}
+
+	return !diagnostic.HasErrors(diagnostics)
+}
+
+// report write the result of a program and return its exit status.
+func report(result object.Object, output, errOutput io.Writer) int {
+	if err, ok := result.(*object.Error); ok {
+		io.WriteString(errOutput, err.StackTrace())
+		return 2
+	}
+
+	if result != nil && result != evaluator.NULL {
+		io.WriteString(output, result.Inspect())
+		io.WriteString(output, "\n")
+	}
+
+	return 0
+}
+
+func modulePath() []string {
+	value := os.Getenv(MODULE_PATH_VARIABLE)
+
+	if value == "" {
+		return nil
+	}
+
+	return filepath.SplitList(value)
+}
@@ -0,0 +1,43 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+
+func TestAnalyzeJSON(t *testing.T) {
+	tests := []struct{
+		source		string
+		expected	string
+		status		int
+	}{
+		{ "let x = 1; x + 1", "[]", 0 },
+		{ "fn f() { return 1; 2 } f()", `[{"file":"script.mk","line":1,"column":20,"severity":"warning","message":"unreachable code"}]`, 0 },
+	}
+
+	for i, tt := range tests {
+		path := filepath.Join(t.TempDir(), "script.mk")
+
+		if err := os.WriteFile(path, []byte(tt.source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		output, errOutput := &bytes.Buffer{}, &bytes.Buffer{}
+
+		status := Analyze(path, true, output, errOutput)
+
+		if status != tt.status || errOutput.Len() != 0 {
+			t.Fatalf("[test #%d]: Expecting status %d without error, but got %d and %q\n", i, tt.status, status, errOutput.String())
+		}
+
+		// The diagnostics locate the file with the path it was given.
+		got := strings.ReplaceAll(strings.TrimSpace(output.String()), path, "script.mk")
+
+		if got != tt.expected {
+			t.Fatalf("[test #%d]: Expecting %s, but got %s\n", i, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package analysis
+
+import (
+	"monkey/internal/ast"
+	"monkey/internal/diagnostic"
+	"monkey/internal/token"
+	"strings"
+)
+
+
+// Analyze look for the code of program that is likely a mistake,
+// and return a warning for each finding, in the order of the source:
+//
+//   - a statement no path reach, like one after a return, reported
+//     once for the statements following each other;
+//   - a function returning a value on some paths only, the other ones
+//     reaching the end of its body after a statement without a value;
+//   - a variable declared with `let` or `const` that is never read;
+//   - a parameter that is never used.
+//
+// Exported declarations are used by the modules importing them, and
+// names starting with an underscore are meant to be unused, like the
+// parameter of a callback only taking the second one.
+func Analyze(program *ast.Program) []diagnostic.Diagnostic {
+	a := &analyzer{}
+
+	a.checkFlow(program.Statements, nil)
+
+	a.enter()
+	a.declareStatements(program.Statements)
+	a.useStatements(program.Statements)
+	a.leave()
+
+	diagnostic.Sort(a.diagnostics)
+
+	return a.diagnostics
+}
+
+type analyzer struct {
+	scope			*scope
+	diagnostics		[]diagnostic.Diagnostic
+}
+
+func (a *analyzer) warn(tok token.Token, format string, args ...any) {
+	a.diagnostics = append(a.diagnostics, diagnostic.Warningf(tok, format, args...))
+}
+
+
+// checkFlow report the unreachable statements of a list of statements,
+// the body of fn or the program when fn is nil, and whether fn return a
+// value on every path.
+func (a *analyzer) checkFlow(statements []ast.Statement, fn *ast.FunctionLiteral) {
+	graph := Build(statements)
+	reachable := map[ast.Statement]bool{}
+	returns, noValue := false, false
+
+	for _, block := range graph.Blocks {
+		if !block.Reachable {
+			continue
+		}
+
+		for _, stmt := range block.Statements {
+			reachable[stmt] = true
+		}
+		returns = returns || block.Exit == RETURN
+		noValue = noValue || block.Exit == NO_VALUE
+	}
+	a.checkReachable(statements, reachable, true)
+
+	if fn != nil && noValue && (returns || fn.ReturnType != nil) {
+		name := fn.Name
+
+		if name == "" {
+			name = "the function"
+		}
+		a.warn(fn.Token, "not all paths of %s return a value", name)
+	}
+}
+
+// checkReachable report the first unreachable statement of a list of
+// statements, then look in the blocks of the reachable ones.
+func (a *analyzer) checkReachable(statements []ast.Statement, reachable map[ast.Statement]bool, report bool) {
+	for _, stmt := range statements {
+		if !reachable[stmt] && report {
+			a.warn(statementToken(stmt), "unreachable code")
+			report = false
+		}
+
+		for _, block := range innerBlocks(stmt) {
+			a.checkReachable(block.Statements, reachable, reachable[stmt])
+		}
+	}
+}
+
+// innerBlocks return the blocks of a statement that are part of the same
+// control-flow graph, in the order of the source, as Build walk them.
+func innerBlocks(stmt ast.Statement) []*ast.BlockStatement {
+	var value ast.Expression
+
+	switch stmt := stmt.(type) {
+
+	case *ast.ExpressionStatement:
+		value = stmt.Expression
+
+	case *ast.DeclarationStatement:
+		value = stmt.Value
+
+	case *ast.ReturnStatement:
+		value = stmt.ReturnValue
+
+	case *ast.ThrowStatement:
+		value = stmt.Value
+
+	case *ast.BlockStatement:
+		return []*ast.BlockStatement{ stmt }
+
+	case *ast.ForInStatement:
+		return []*ast.BlockStatement{ stmt.Body }
+
+	case *ast.TryStatement:
+		blocks := []*ast.BlockStatement{ stmt.Block }
+
+		for _, block := range []*ast.BlockStatement{ stmt.Catch, stmt.Finally } {
+			if block != nil {
+				blocks = append(blocks, block)
+			}
+		}
+		return blocks
+	}
+
+	if ifElse, ok := value.(*ast.IfElseExpression); ok {
+		if ifElse.Alternative == nil {
+			return []*ast.BlockStatement{ ifElse.Consequence }
+		}
+		return []*ast.BlockStatement{ ifElse.Consequence, ifElse.Alternative }
+	}
+
+	return nil
+}
+
+func statementToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+
+	case *ast.ExpressionStatement:
+		return stmt.Token
+
+	case *ast.DeclarationStatement:
+		return stmt.Token
+
+	case *ast.ReturnStatement:
+		return stmt.Token
+
+	case *ast.ThrowStatement:
+		return stmt.Token
+
+	case *ast.BlockStatement:
+		return stmt.Token
+
+	case *ast.FunctionDeclaration:
+		return stmt.Token
+
+	case *ast.ForInStatement:
+		return stmt.Token
+
+	case *ast.TryStatement:
+		return stmt.Token
+
+	case *ast.ImportStatement:
+		return stmt.Token
+
+	case *ast.ExportStatement:
+		return stmt.Token
+	}
+
+	return token.Token{}
+}
+
+
+// scope is the environment a block is evaluated in, as the
+// resolver see it, with the bindings declared there.
+type scope struct {
+	bindings	map[string]*binding
+	outer		*scope
+}
+
+type binding struct {
+	token		token.Token
+	kind		string // "let", "const" or "parameter", empty for the bindings not reported
+	used		bool
+}
+
+func (a *analyzer) enter() {
+	a.scope = &scope{ bindings: map[string]*binding{}, outer: a.scope }
+}
+
+// leave report the bindings of the current scope that are never used.
+func (a *analyzer) leave() {
+	for name, binding := range a.scope.bindings {
+		if binding.used || binding.kind == "" || strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		if binding.kind == "parameter" {
+			a.warn(binding.token, "parameter %s is never used", name)
+		} else {
+			a.warn(binding.token, "%s is declared but never used", name)
+		}
+	}
+	a.scope = a.scope.outer
+}
+
+// declare add a binding to the current scope. A name declared twice
+// in the same scope is the same binding, as for the resolver.
+func (a *analyzer) declare(ident *ast.Identifier, kind string) {
+	if _, ok := a.scope.bindings[ident.Value]; !ok {
+		a.scope.bindings[ident.Value] = &binding{ token: ident.Token, kind: kind }
+	}
+}
+
+func (a *analyzer) use(ident *ast.Identifier) {
+	for s := a.scope; s != nil; s = s.outer {
+		if binding, ok := s.bindings[ident.Value]; ok {
+			binding.used = true
+			return
+		}
+	}
+}
+
+// declareStatements declare the names bound by a list of statements
+// before any of them is walked, as they may be used before their
+// declaration, from a function called later.
+func (a *analyzer) declareStatements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+
+		case *ast.DeclarationStatement:
+			a.declare(stmt.Name, stmt.Token.Literal)
+
+		case *ast.FunctionDeclaration:
+			a.declare(stmt.Name, "")
+
+		case *ast.ImportStatement:
+			a.declare(stmt.Alias, "")
+
+		case *ast.ExportStatement:
+			switch inner := stmt.Statement.(type) {
+
+			case *ast.DeclarationStatement:
+				a.declare(inner.Name, "")
+
+			case *ast.FunctionDeclaration:
+				a.declare(inner.Name, "")
+			}
+		}
+	}
+}
+
+func (a *analyzer) useBlock(block *ast.BlockStatement, bindings ...*ast.Identifier) {
+	if block == nil {
+		return
+	}
+	a.enter()
+
+	for _, ident := range bindings {
+		if ident != nil {
+			a.declare(ident, "")
+		}
+	}
+	a.declareStatements(block.Statements)
+	a.useStatements(block.Statements)
+	a.leave()
+}
+
+func (a *analyzer) useFunction(fn *ast.FunctionLiteral) {
+	a.checkFlow(fn.Body.Statements, fn)
+
+	a.enter()
+
+	for _, param := range fn.Params {
+		a.declare(param.Name, "parameter")
+	}
+	a.declareStatements(fn.Body.Statements)
+
+	for _, param := range fn.Params {
+		a.useExpression(param.Default)
+	}
+	a.useStatements(fn.Body.Statements)
+	a.leave()
+}
+
+func (a *analyzer) useStatements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		a.useStatement(stmt)
+	}
+}
+
+func (a *analyzer) useStatement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+
+	case *ast.ExpressionStatement:
+		a.useExpression(stmt.Expression)
+
+	case *ast.DeclarationStatement:
+		a.useExpression(stmt.Value)
+
+	case *ast.ReturnStatement:
+		a.useExpression(stmt.ReturnValue)
+
+	case *ast.ThrowStatement:
+		a.useExpression(stmt.Value)
+
+	case *ast.BlockStatement:
+		a.useStatements(stmt.Statements)
+
+	case *ast.FunctionDeclaration:
+		a.useFunction(stmt.Function)
+
+	case *ast.ExportStatement:
+		a.useStatement(stmt.Statement)
+
+	case *ast.TryStatement:
+		a.useBlock(stmt.Block)
+		a.useBlock(stmt.Catch, stmt.CatchParam)
+		a.useBlock(stmt.Finally)
+
+	case *ast.ForInStatement:
+		a.useExpression(stmt.Iterable)
+		a.useBlock(stmt.Body, stmt.Variable)
+	}
+}
+
+func (a *analyzer) useExpressions(expressions []ast.Expression) {
+	for _, expr := range expressions {
+		a.useExpression(expr)
+	}
+}
+
+func (a *analyzer) useExpression(expr ast.Expression) {
+	switch expr := expr.(type) {
+
+	case *ast.Identifier:
+		a.use(expr)
+
+	case *ast.PrefixExpression:
+		a.useExpression(expr.Right)
+
+	case *ast.InfixExpression:
+		a.useExpression(expr.Left)
+		a.useExpression(expr.Right)
+
+	case *ast.PostfixExpression:
+		a.useExpression(expr.Left)
+
+	case *ast.IfElseExpression:
+		a.useExpression(expr.Condition)
+		a.useBlock(expr.Consequence)
+		a.useBlock(expr.Alternative)
+
+	case *ast.ArrayLiteral:
+		a.useExpressions(expr.Elements)
+
+	case *ast.HashLiteral:
+		a.useExpressions(expr.Keys)
+		a.useExpressions(expr.Values)
+
+	case *ast.FunctionLiteral:
+		a.useFunction(expr)
+
+	case *ast.FunctionCallExpression:
+		a.useExpression(expr.Function)
+		a.useExpressions(expr.Arguments)
+
+	case *ast.SpreadExpression:
+		a.useExpression(expr.Value)
+
+	case *ast.NamedArgument:
+		a.useExpression(expr.Value)
+
+	case *ast.IndexExpression:
+		a.useExpression(expr.Left)
+		a.useExpression(expr.Index)
+
+	case *ast.MemberExpression:
+		a.useExpression(expr.Object)
+
+	case *ast.SliceExpression:
+		a.useExpression(expr.Left)
+		a.useExpression(expr.Start)
+		a.useExpression(expr.Stop)
+		a.useExpression(expr.Step)
+
+	case *ast.RangeExpression:
+		a.useExpression(expr.Start)
+		a.useExpression(expr.End)
+	}
+}
@@ -0,0 +1,104 @@
+package analysis
+
+import (
+	"slices"
+	"testing"
+)
+
+
+func TestAnalyze(t *testing.T) {
+
+	t.Run("it should find nothing in clean programs", func(t *testing.T) {
+		tests := []string{
+			"fn add(a, b) { a + b } add(1, 2)",
+			"fn sign(n) { if (n > 0) { return 1 } if (n < 0) { return -1 } 0 } sign(1)",
+			"fn pick(c) { if (c) { return 1 } else { 2 } } pick(true)",
+			"fn f(c) { if (c) { return 1 } else { return 2 } } f(true)",
+			"fn f(c) { try { return c() } catch (e) { return 0 } } f(fn() { 1 })",
+			"fn f(xs) { for (x in xs) { if (x) { return x } } throw \"none\" } f([1])",
+			"fn log(x) { puts(x); let done = true; done } log(1)",
+			"fn setup() { let config = {}; config } setup()",
+			"[1, 2].map(fn(_, i) { i })",
+			"export let VERSION = 1; export fn helper(x) { x }",
+			"fn outer() { inner() } fn inner() { limit } let limit = 3; outer()",
+			"let n = 0; n++",
+			"import \"m.mk\" as m",
+			"for (i in 0..3) { } try { 1 } catch (e) { }",
+		}
+
+		for i, input := range tests {
+			if diagnostics := Analyze(parse(t, input)); len(diagnostics) != 0 {
+				t.Fatalf("[test #%d] %s\nExpecting no diagnostics, but got %v\n", i, input, diagnostics)
+			}
+		}
+	})
+
+	t.Run("it should report unreachable code", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	[]string
+		}{
+			{ "fn f() { return 1; puts(2); puts(3) } f()", []string{ "<input>:1:20: warning: unreachable code" } },
+			{ "fn f() { throw \"x\"; 1 } f()", []string{ "<input>:1:21: warning: unreachable code" } },
+			{ "fn f(c) { if (c) { return 1 } else { return 2 } 3 } f(true)", []string{ "<input>:1:49: warning: unreachable code" } },
+			{ "fn f(c) { let x = if (c) { return 1 } else { throw \"x\" }; x } f(true)", []string{ "<input>:1:59: warning: unreachable code" } },
+			{ "fn f(xs) { for (x in xs) { return x; puts(x) } 0 } f([])", []string{ "<input>:1:38: warning: unreachable code" } },
+			{ "fn f() { try { return 1 } catch (e) { return 2 } 3 } f()", []string{ "<input>:1:50: warning: unreachable code" } },
+			{ "fn f() { return 1; if (true) { 2; 3 } } f()", []string{ "<input>:1:20: warning: unreachable code" } },
+			{ "return 1; 2", []string{ "<input>:1:11: warning: unreachable code" } },
+		}
+
+		for i, tt := range tests {
+			testDiagnostics(t, i, tt.input, tt.expected)
+		}
+	})
+
+	t.Run("it should report functions not returning a value on every path", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	[]string
+		}{
+			{ "fn sign(n) { if (n > 0) { return 1 } if (n < 0) { return -1 } } sign(1)", []string{ "<input>:1:1: warning: not all paths of sign return a value" } },
+			{ "let f = fn(c) { if (c) { return 1 } let x = 2; }; f(true)", []string{ "<input>:1:9: warning: not all paths of the function return a value", "<input>:1:41: warning: x is declared but never used" } },
+			{ "fn f(c) { if (c) { return 1 } else { } } f(true)", []string{ "<input>:1:1: warning: not all paths of f return a value" } },
+			{ "fn f(c): int { for (x in c) { } } f([])", []string{ "<input>:1:1: warning: not all paths of f return a value" } },
+			{ "fn f() { try { return 1 } catch (e) { } } f()", []string{ "<input>:1:1: warning: not all paths of f return a value" } },
+		}
+
+		for i, tt := range tests {
+			testDiagnostics(t, i, tt.input, tt.expected)
+		}
+	})
+
+	t.Run("it should report unused bindings", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	[]string
+		}{
+			{ "let x = 1; const y = 2", []string{ "<input>:1:5: warning: x is declared but never used", "<input>:1:18: warning: y is declared but never used" } },
+			{ "fn f(a, b) { a } f(1, 2)", []string{ "<input>:1:9: warning: parameter b is never used" } },
+			{ "fn f(a, b = a, ...rest) { b } f(1)", []string{ "<input>:1:19: warning: parameter rest is never used" } },
+			{ "fn f() { let x = 1; fn() { let x = 2; x } } f()", []string{ "<input>:1:14: warning: x is declared but never used" } },
+			{ "if (true) { let hidden = 1 }", []string{ "<input>:1:17: warning: hidden is declared but never used" } },
+			{ "let h = { \"a\": 1 }; h.a; fn f(a) { { a: 1 } } f(1)", []string{} },
+			{ "fn f(name) { g(name: 1) } fn g(name) { name } f(1)", []string{ "<input>:1:6: warning: parameter name is never used" } },
+		}
+
+		for i, tt := range tests {
+			testDiagnostics(t, i, tt.input, tt.expected)
+		}
+	})
+}
+
+
+func testDiagnostics(t *testing.T, i int, input string, expected []string) {
+	got := []string{}
+
+	for _, diagnostic := range Analyze(parse(t, input)) {
+		got = append(got, diagnostic.String())
+	}
+
+	if !slices.Equal(got, expected) {
+		t.Fatalf("[test #%d] %s\nExpecting %q, but got %q\n", i, input, expected, got)
+	}
+}
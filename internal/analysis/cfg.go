@@ -0,0 +1,240 @@
+package analysis
+
+import (
+	"monkey/internal/ast"
+)
+
+
+// Exit tell how a block leave the function it's part of.
+type Exit int
+
+const (
+	NONE		Exit = iota // it doesn't: it goes on with its successors
+	RETURN
+	THROW
+	VALUE		// it reach the end of the body, whose value is the one of its last statement
+	NO_VALUE	// it reach the end of the body after a statement without a value, like a `let`
+)
+
+// Block is a basic block of a control-flow graph: statements always
+// run one after the other. A statement is in the block where it
+// starts, the statements of its own blocks are in other ones.
+type Block struct {
+	Index		int
+	Statements	[]ast.Statement
+	Succs		[]*Block
+	Preds		[]*Block
+	Exit		Exit
+	Reachable	bool // from the entry of the graph
+}
+
+// Graph is the control-flow graph of the body of a function, or of a
+// program. The blocks leaving the function all go to Exit, which has
+// no statements. Function literals have their own graph.
+type Graph struct {
+	Entry		*Block
+	Exit		*Block
+	Blocks		[]*Block
+}
+
+// Build return the control-flow graph of a list of statements.
+//
+// Only the statements decide the flow: an if-else expression branches
+// when it's the expression of a statement or the value of a declaration,
+// a return or a throw, other ones are taken as a whole. A for-in loop
+// can run its body any number of times, and the catch block of a try
+// statement may run from anywhere in the try block. The finally block
+// is built twice: once after the try and catch blocks, and once for an
+// error leaving them, which goes on to the exit.
+func Build(statements []ast.Statement) *Graph {
+	b := &builder{ graph: &Graph{} }
+	b.graph.Entry = b.newBlock()
+	b.graph.Exit = b.newBlock()
+	b.current = b.graph.Entry
+
+	b.statements(statements)
+
+	if b.valued {
+		b.exit(VALUE)
+	} else {
+		b.exit(NO_VALUE)
+	}
+	b.graph.markReachable(b.graph.Entry)
+
+	return b.graph
+}
+
+func (g *Graph) markReachable(block *Block) {
+	if block.Reachable {
+		return
+	}
+	block.Reachable = true
+
+	for _, succ := range block.Succs {
+		g.markReachable(succ)
+	}
+}
+
+type builder struct {
+	graph		*Graph
+	current		*Block
+	// valued tell whether the paths reaching the current block give the
+	// value of a statement, so they return it at the end of the body.
+	valued		bool
+}
+
+func (b *builder) newBlock() *Block {
+	block := &Block{ Index: len(b.graph.Blocks) }
+	b.graph.Blocks = append(b.graph.Blocks, block)
+
+	return block
+}
+
+func link(from, to *Block) {
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+// next start a new block following the current one.
+func (b *builder) next() *Block {
+	block := b.newBlock()
+	link(b.current, block)
+	b.current = block
+
+	return block
+}
+
+// exit make the current block leave the function. After a return or
+// a throw, a block no path reach is started for the statements that
+// may follow. An unreachable path give whatever value is needed, so
+// it's valued.
+func (b *builder) exit(exit Exit) {
+	b.current.Exit = exit
+	link(b.current, b.graph.Exit)
+
+	if exit == RETURN || exit == THROW {
+		b.current = b.newBlock()
+		b.valued = true
+	}
+}
+
+func (b *builder) statements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		b.statement(stmt)
+	}
+}
+
+func (b *builder) statement(stmt ast.Statement) {
+	b.current.Statements = append(b.current.Statements, stmt)
+
+	switch stmt := stmt.(type) {
+
+	case *ast.ExpressionStatement:
+		b.expression(stmt.Expression)
+
+	case *ast.DeclarationStatement:
+		b.expression(stmt.Value)
+		b.valued = false
+
+	case *ast.ReturnStatement:
+		b.expression(stmt.ReturnValue)
+		b.exit(RETURN)
+
+	case *ast.ThrowStatement:
+		b.expression(stmt.Value)
+		b.exit(THROW)
+
+	case *ast.BlockStatement:
+		b.statements(stmt.Statements)
+
+	case *ast.ForInStatement:
+		header := b.next()
+		b.next()
+		b.statements(stmt.Body.Statements)
+		link(b.current, header)
+
+		b.current = header
+		b.next()
+		b.valued = false
+
+	case *ast.TryStatement:
+		b.try(stmt)
+
+	default:
+		b.valued = false
+	}
+}
+
+// expression build the branches of an if-else expression.
+func (b *builder) expression(expr ast.Expression) {
+	ifElse, ok := expr.(*ast.IfElseExpression)
+
+	if !ok {
+		b.valued = true
+		return
+	}
+	condition := b.current
+
+	consequence, consequenceValued := b.branch(condition, ifElse.Consequence)
+	alternative, alternativeValued := condition, false
+
+	if ifElse.Alternative != nil {
+		alternative, alternativeValued = b.branch(condition, ifElse.Alternative)
+	}
+
+	b.join(consequence, alternative)
+	b.valued = consequenceValued && alternativeValued
+}
+
+// branch build a block run from the block from, and return
+// the block it ends in and whether it ends with a value.
+func (b *builder) branch(from *Block, block *ast.BlockStatement) (*Block, bool) {
+	b.current = b.newBlock()
+	b.valued = false
+	link(from, b.current)
+
+	if block != nil {
+		b.statements(block.Statements)
+	}
+
+	return b.current, b.valued
+}
+
+// join start a new block following the blocks ends.
+func (b *builder) join(ends ...*Block) {
+	b.current = b.newBlock()
+
+	for _, end := range ends {
+		link(end, b.current)
+	}
+}
+
+func (b *builder) try(stmt *ast.TryStatement) {
+	start := b.current
+	ends := []*Block{}
+	valued := true
+
+	end, endValued := b.branch(start, stmt.Block)
+	ends = append(ends, end)
+	valued = valued && endValued
+
+	if stmt.Catch != nil {
+		end, endValued = b.branch(start, stmt.Catch)
+		ends = append(ends, end)
+		valued = valued && endValued
+	}
+
+	if stmt.Finally == nil {
+		b.join(ends...)
+		b.valued = valued
+		return
+	}
+
+	// The finally block of an error leaving the statement.
+	b.branch(start, stmt.Finally)
+	b.exit(THROW)
+
+	b.join(ends...)
+	b.statements(stmt.Finally.Statements)
+	b.valued = valued
+}
@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"fmt"
+	"monkey/internal/ast"
+	"monkey/internal/lexer"
+	"monkey/internal/parser"
+	"slices"
+	"strings"
+	"testing"
+)
+
+
+func TestBuild(t *testing.T) {
+
+	t.Run("it should link the blocks of the statements", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	[]string
+		}{
+			{ "let x = 1; x", []string{ "0 [let x = 1;, x] -> 1 value" } },
+			{ "", []string{ "0 [] -> 1 no value" } },
+			{
+				"if (c) { 1 } else { 2 }",
+				[]string{ "0 [if c { 1; } else { 2; }] -> 2 3", "2 [1] -> 4", "3 [2] -> 4", "4 [] -> 1 value" },
+			},
+			{
+				"if (c) { 1 }; 2",
+				[]string{ "0 [if c { 1; }] -> 2 3", "2 [1] -> 3", "3 [2] -> 1 value" },
+			},
+			{
+				"return 1; 2",
+				[]string{ "0 [return 1;] -> 1 return", "2 [2] -> 1 value unreachable" },
+			},
+			{
+				"for (x in xs) { x } 0",
+				[]string{ "0 [for x in xs { x; }] -> 2", "2 [] -> 3 4", "3 [x] -> 2", "4 [0] -> 1 value" },
+			},
+			{
+				"try { f() } catch (e) { throw e } finally { g() }",
+				[]string{ "0 [try { f(); } catch (e) { throw e; } finally { g(); }] -> 2 3 5", "2 [f()] -> 7", "3 [throw e;] -> 1 throw", "4 [] -> 7 unreachable", "5 [g()] -> 1 throw", "7 [g()] -> 1 value" },
+			},
+		}
+
+		for i, tt := range tests {
+			got := describeGraph(Build(parse(t, tt.input).Statements))
+
+			if !slices.Equal(got, tt.expected) {
+				t.Fatalf("[test #%d] %s\nExpecting %q, but got %q\n", i, tt.input, tt.expected, got)
+			}
+		}
+	})
+}
+
+
+// describeGraph describe the blocks of graph with statements or
+// successors as "index [statements] -> successors exit".
+func describeGraph(graph *Graph) []string {
+	exits := map[Exit]string{ RETURN: " return", THROW: " throw", VALUE: " value", NO_VALUE: " no value" }
+	lines := []string{}
+
+	for _, block := range graph.Blocks {
+		if block == graph.Exit || (len(block.Statements) == 0 && len(block.Succs) == 0) {
+			continue
+		}
+		statements := []string{}
+		succs := []string{}
+
+		for _, stmt := range block.Statements {
+			statements = append(statements, stmt.String())
+		}
+
+		for _, succ := range block.Succs {
+			succs = append(succs, fmt.Sprint(succ.Index))
+		}
+		line := fmt.Sprintf("%d [%s] -> %s%s", block.Index, strings.Join(statements, ", "), strings.Join(succs, " "), exits[block.Exit])
+
+		if !block.Reachable {
+			line += " unreachable"
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Expecting no parser errors, but got %v\n", p.Errors())
+	}
+
+	return program
+}
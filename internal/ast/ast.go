@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"monkey/internal/token"
+	"strings"
 )
 
 type Node interface {
@@ -45,6 +46,7 @@ func (p *Program) String() string {
 type DeclarationStatement struct {
 	Token 	token.Token
 	Name  	*Identifier
+	Type	TypeExpression // nil when not annotated
 	Value 	Expression
 }
 
@@ -54,7 +56,12 @@ func (ds *DeclarationStatement) String() string {
 	var output bytes.Buffer
 
 	output.WriteString(ds.TokenLiteral() + " ")
-	output.WriteString(ds.Name.String() + " = ")
+	output.WriteString(ds.Name.String())
+
+	if ds.Type != nil {
+		output.WriteString(": " + ds.Type.String())
+	}
+	output.WriteString(" = ")
 	
 	if ds.Value != nil {
 		output.WriteString(ds.Value.String())
@@ -69,6 +76,7 @@ func (ds *DeclarationStatement) String() string {
 type Identifier struct {
 	Token 	token.Token
 	Value 	string
+	Binding	Binding // where the name is bound, set by the resolver
 }
 
 func (i *Identifier) expressionNode() {}
@@ -133,6 +141,63 @@ func (fl *FloatLiteral) String() string { return fl.TokenLiteral() }
 
 
 
+type StringLiteral struct {
+	Token		token.Token
+	Value		string
+}
+func (sl *StringLiteral) expressionNode() {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string { return "\"" + sl.TokenLiteral() + "\"" }
+
+
+
+type ArrayLiteral struct {
+	Token		token.Token
+	Elements	[]Expression
+}
+func (al *ArrayLiteral) expressionNode() {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) String() string {
+	var output bytes.Buffer
+	var lastIdx = len(al.Elements) - 1
+
+	output.WriteString("[")
+
+	for i, element := range al.Elements {
+		output.WriteString(element.String())
+
+		if i < lastIdx {
+			output.WriteString(", ")
+		}
+	}
+
+	output.WriteString("]")
+
+	return output.String()
+}
+
+
+// HashLiteral is `{ key: value, ... }`. Pairs keep the order they
+// are written in, which is the order they are evaluated in.
+type HashLiteral struct {
+	Token	token.Token
+	Keys	[]Expression
+	Values	[]Expression
+}
+func (hl *HashLiteral) expressionNode() {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) String() string {
+	pairs := []string{}
+
+	for i, key := range hl.Keys {
+		pairs = append(pairs, key.String() + ": " + hl.Values[i].String())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+
+
 type Boolean struct {
 	Token 		token.Token
 	Value		bool
@@ -163,6 +228,28 @@ func (pxe *PrefixExpression) String() string {
 
 
 
+// PostfixExpression is an operator applied after its operand,
+// like `i++` or `i--`.
+type PostfixExpression struct {
+	Token		token.Token
+	Left		Expression
+	Operator	string
+}
+func (pfe *PostfixExpression) expressionNode() {}
+func (pfe *PostfixExpression) TokenLiteral() string { return pfe.Token.Literal }
+func (pfe *PostfixExpression) String() string {
+	var output bytes.Buffer
+
+	output.WriteString("(")
+	output.WriteString(pfe.Left.String())
+	output.WriteString(pfe.Operator)
+	output.WriteString(")")
+
+	return output.String()
+}
+
+
+
 type InfixExpression struct {
 	Token		token.Token
 	Left		Expression
@@ -187,20 +274,59 @@ func (ixf *InfixExpression) String() string {
 type BlockStatement struct {
 	Token			token.Token
 	Statements		[]Statement
+	Scope			*Scope // names bound in the environment of the block, set by the resolver
 }
 func (block *BlockStatement) statementNode() {}
 func (block *BlockStatement) TokenLiteral() string { return block.Token.Literal }
 func (block *BlockStatement) String() string {
 	var output bytes.Buffer
 
+	output.WriteString("{ ")
+
 	for _, stmt := range block.Statements {
 		output.WriteString(stmt.String())
+
+		// Expression statements don't carry their semicolon, so we
+		// add it back to keep statements apart when parsed again.
+		if _, ok := stmt.(*ExpressionStatement); ok {
+			output.WriteString(";")
+		}
+		output.WriteString(" ")
 	}
 
+	output.WriteString("}")
+
 	return output.String()
 }
 
 
+// Scope list the names bound in the environment a block is evaluated
+// in: the declarations of the block, preceded by the parameters of a
+// function, the variable of a loop or the error of a catch block.
+// The slot of a name in the environment is its index.
+type Scope struct {
+	Names		[]string
+}
+
+// BindingKind tell how the value of an identifier is looked up.
+type BindingKind int
+
+const (
+	UNRESOLVED BindingKind = iota // by name, from the current environment
+	LOCAL // in a slot of an enclosing block environment
+	GLOBAL // by name, in the environment of the program
+)
+
+// Binding locate the value of an identifier relative to the environment
+// it's evaluated in: Depth is the number of enclosing environments to go
+// up to reach the one holding it, where a LOCAL value is in Slot.
+type Binding struct {
+	Kind		BindingKind
+	Depth		int
+	Slot		int
+}
+
+
 type IfElseExpression struct {
 	Token			token.Token
 	Condition		Expression
@@ -209,36 +335,107 @@ type IfElseExpression struct {
 }
 func (ieExpr *IfElseExpression) expressionNode() {}
 func (ieExpr *IfElseExpression) TokenLiteral() string { return ieExpr.Token.Literal }
+
+// String return the canonical form of the expression, which can
+// be parsed back into the same tree. An alternative holding only
+// a nested IfElseExpression is printed as an `else if` chain.
 func (ieExpr *IfElseExpression) String() string {
 	var output bytes.Buffer
 
-	output.WriteString("if")
+	output.WriteString("if ")
 	output.WriteString(ieExpr.Condition.String())
 	output.WriteString(" ")
 	output.WriteString(ieExpr.Consequence.String())
 
-	if ieExpr.Alternative != nil {
-		output.WriteString(" else ")
+	if ieExpr.Alternative == nil {
+		return output.String()
+	}
+
+	output.WriteString(" else ")
+
+	if nested := ieExpr.ElseIf(); nested != nil {
+		output.WriteString(nested.String())
+	} else {
 		output.WriteString(ieExpr.Alternative.String())
 	}
 
 	return output.String()
 }
 
+// ElseIf return the nested IfElseExpression when the alternative
+// is an `else if` branch, otherwise it return nil.
+func (ieExpr *IfElseExpression) ElseIf() *IfElseExpression {
+	if ieExpr.Alternative == nil || len(ieExpr.Alternative.Statements) != 1 {
+		return nil
+	}
+
+	stmt, ok := ieExpr.Alternative.Statements[0].(*ExpressionStatement)
+
+	if !ok {
+		return nil
+	}
+
+	nested, _ := stmt.Expression.(*IfElseExpression)
+
+	return nested
+}
+
+
+
+// Parameter is a function parameter. It can have a default value,
+// `fn(x, y = 10)`, or collect the remaining arguments when Rest,
+// `fn(first, ...rest)`.
+type Parameter struct {
+	Token		token.Token
+	Name		*Identifier
+	Type		TypeExpression // nil when not annotated
+	Default		Expression
+	Rest		bool
+}
+func (param *Parameter) TokenLiteral() string { return param.Token.Literal }
+func (param *Parameter) String() string {
+	var output bytes.Buffer
+
+	if param.Rest {
+		output.WriteString("...")
+	}
+	output.WriteString(param.Name.String())
+
+	if param.Type != nil {
+		output.WriteString(": " + param.Type.String())
+	}
+
+	if param.Default != nil {
+		output.WriteString(" = ")
+		output.WriteString(param.Default.String())
+	}
+
+	return output.String()
+}
+
 
 
 type FunctionLiteral struct {
 	Token		token.Token
-	Params		[]*Identifier
+	Name		string // name of a declared function, empty when anonymous
+	Params		[]*Parameter
+	ReturnType	TypeExpression // nil when not annotated
 	Body		*BlockStatement
 }
 func (fn *FunctionLiteral) expressionNode() {}
 func (fn *FunctionLiteral) TokenLiteral() string { return fn.Token.Literal }
 func (fn *FunctionLiteral) String() string {
 	var output bytes.Buffer
-	var lastIdx = len(fn.Params) - 1
 
 	output.WriteString(fn.TokenLiteral())
+	fn.writeParamsAndBody(&output)
+
+	return output.String()
+}
+
+func (fn *FunctionLiteral) writeParamsAndBody(output *bytes.Buffer) {
+	var lastIdx = len(fn.Params) - 1
+
 	output.WriteString("(")
 
 	for i, param := range fn.Params {
@@ -249,18 +446,67 @@ func (fn *FunctionLiteral) String() string {
 		}
 	}
 
-	output.WriteString(") ")
+	output.WriteString(")")
+
+	if fn.ReturnType != nil {
+		output.WriteString(": " + fn.ReturnType.String())
+	}
+	output.WriteString(" ")
 	output.WriteString(fn.Body.String())
+}
+
+
+
+// FunctionDeclaration is `fn name(params) { ... }`. Declared functions
+// are hoisted: they are bound before the other statements of their
+// block are evaluated.
+type FunctionDeclaration struct {
+	Token		token.Token
+	Name		*Identifier
+	Function	*FunctionLiteral
+}
+func (fd *FunctionDeclaration) statementNode() {}
+func (fd *FunctionDeclaration) TokenLiteral() string { return fd.Token.Literal }
+func (fd *FunctionDeclaration) String() string {
+	var output bytes.Buffer
+
+	output.WriteString(fd.TokenLiteral() + " ")
+	output.WriteString(fd.Name.String())
+	fd.Function.writeParamsAndBody(&output)
 
 	return output.String()
 }
 
 
 
+// SpreadExpression is `...value` in the arguments of a function
+// call, passing each element of an array as a separate argument.
+type SpreadExpression struct {
+	Token		token.Token
+	Value		Expression
+}
+func (se *SpreadExpression) expressionNode() {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string { return "..." + se.Value.String() }
+
+
+
+// NamedArgument is `name: value` in the arguments of a function call.
+type NamedArgument struct {
+	Token		token.Token
+	Name		*Identifier
+	Value		Expression
+}
+func (na *NamedArgument) expressionNode() {}
+func (na *NamedArgument) TokenLiteral() string { return na.Token.Literal }
+func (na *NamedArgument) String() string { return na.Name.String() + ": " + na.Value.String() }
+
+
+
 type FunctionCallExpression struct {
 	Token		token.Token
 	Function	Expression // Identifier or FunctionLiteral
-	Arguments	[]Expression
+	Arguments	[]Expression // positional and spread arguments first, then named ones
 }
 func (fnCall *FunctionCallExpression) expressionNode() {}
 func (fnCall *FunctionCallExpression) TokenLiteral() string { return fnCall.Token.Literal }
@@ -283,3 +529,280 @@ func (fnCall *FunctionCallExpression) String() string {
 
 	return output.String()
 }
+
+
+
+type IndexExpression struct {
+	Token		token.Token // the '[' token
+	Left		Expression
+	Index		Expression
+}
+func (ie *IndexExpression) expressionNode() {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	var output bytes.Buffer
+
+	output.WriteString("(")
+	output.WriteString(ie.Left.String())
+	output.WriteString("[")
+	output.WriteString(ie.Index.String())
+	output.WriteString("])")
+
+	return output.String()
+}
+
+
+
+// MemberExpression is `object.member`.
+type MemberExpression struct {
+	Token	token.Token // the '.' token
+	Object	Expression
+	Member	*Identifier
+}
+func (me *MemberExpression) expressionNode() {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Member.String() + ")"
+}
+
+
+// SliceExpression is `left[start:stop:step]`, where each bound
+// is optional and nil when omitted.
+type SliceExpression struct {
+	Token		token.Token // the '[' token
+	Left		Expression
+	Start		Expression
+	Stop		Expression
+	Step		Expression
+}
+func (se *SliceExpression) expressionNode() {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var output bytes.Buffer
+
+	output.WriteString("(")
+	output.WriteString(se.Left.String())
+	output.WriteString("[")
+
+	if se.Start != nil {
+		output.WriteString(se.Start.String())
+	}
+	output.WriteString(":")
+
+	if se.Stop != nil {
+		output.WriteString(se.Stop.String())
+	}
+
+	if se.Step != nil {
+		output.WriteString(":")
+		output.WriteString(se.Step.String())
+	}
+
+	output.WriteString("])")
+
+	return output.String()
+}
+
+
+
+// RangeExpression is `start..end`, or `start..=end` when Inclusive.
+type RangeExpression struct {
+	Token		token.Token
+	Start		Expression
+	End			Expression
+	Inclusive	bool
+}
+func (re *RangeExpression) expressionNode() {}
+func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *RangeExpression) String() string {
+	var output bytes.Buffer
+
+	output.WriteString("(")
+	output.WriteString(re.Start.String())
+	output.WriteString(re.TokenLiteral())
+	output.WriteString(re.End.String())
+	output.WriteString(")")
+
+	return output.String()
+}
+
+
+
+// ImportStatement is `import "path/to/lib.mk" as lib`.
+type ImportStatement struct {
+	Token	token.Token
+	Path	*StringLiteral
+	Alias	*Identifier
+}
+func (is *ImportStatement) statementNode() {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	return is.TokenLiteral() + " " + is.Path.String() + " as " + is.Alias.String() + ";"
+}
+
+
+// ExportStatement is a declaration, `let`, `const` or `fn`, preceded
+// by `export`, which make the declared name a member of the module.
+type ExportStatement struct {
+	Token		token.Token
+	Statement	Statement // *DeclarationStatement or *FunctionDeclaration
+}
+func (es *ExportStatement) statementNode() {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
+
+// Name return the name the exported statement declare.
+func (es *ExportStatement) Name() string {
+	switch stmt := es.Statement.(type) {
+
+	case *DeclarationStatement:
+		return stmt.Name.Value
+
+	case *FunctionDeclaration:
+		return stmt.Name.Value
+	}
+
+	return ""
+}
+
+
+// ThrowStatement is `throw value`.
+type ThrowStatement struct {
+	Token	token.Token
+	Value	Expression
+}
+func (ts *ThrowStatement) statementNode() {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
+
+// TryStatement is `try { ... } catch (e) { ... } finally { ... }`, where
+// either the catch or the finally block can be omitted. CatchParam is nil
+// when the catch block doesn't bind the error.
+type TryStatement struct {
+	Token		token.Token
+	Block		*BlockStatement
+	CatchParam	*Identifier
+	Catch		*BlockStatement
+	Finally		*BlockStatement
+}
+func (ts *TryStatement) statementNode() {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) String() string {
+	var output bytes.Buffer
+
+	output.WriteString("try ")
+	output.WriteString(ts.Block.String())
+
+	if ts.Catch != nil {
+		output.WriteString(" catch ")
+
+		if ts.CatchParam != nil {
+			output.WriteString("(" + ts.CatchParam.String() + ") ")
+		}
+		output.WriteString(ts.Catch.String())
+	}
+
+	if ts.Finally != nil {
+		output.WriteString(" finally ")
+		output.WriteString(ts.Finally.String())
+	}
+
+	return output.String()
+}
+
+
+// ForInStatement is `for item in iterable { ... }`.
+type ForInStatement struct {
+	Token		token.Token
+	Variable	*Identifier
+	Iterable	Expression
+	Body		*BlockStatement
+}
+func (fis *ForInStatement) statementNode() {}
+func (fis *ForInStatement) TokenLiteral() string { return fis.Token.Literal }
+func (fis *ForInStatement) String() string {
+	var output bytes.Buffer
+
+	output.WriteString("for ")
+	output.WriteString(fis.Variable.String())
+	output.WriteString(" in ")
+	output.WriteString(fis.Iterable.String())
+	output.WriteString(" ")
+	output.WriteString(fis.Body.String())
+
+	return output.String()
+}
+
+
+
+// TypeExpression is a type annotation, like the `int` of `let x: int = 5`.
+// Annotations are only read by the type checker, evaluating a program
+// ignore them.
+type TypeExpression interface {
+	Node
+	typeNode()
+}
+
+// NamedType is one of the basic types: `int`, `float`, `bool`,
+// `string`, or `any`, the type of every value.
+type NamedType struct {
+	Token	token.Token
+	Name	string
+}
+func (nt *NamedType) typeNode() {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string { return nt.Name }
+
+// ArrayType is `array<Element>`.
+type ArrayType struct {
+	Token		token.Token
+	Element		TypeExpression
+}
+func (at *ArrayType) typeNode() {}
+func (at *ArrayType) TokenLiteral() string { return at.Token.Literal }
+func (at *ArrayType) String() string { return "array<" + at.Element.String() + ">" }
+
+// MapType is `map<Key, Value>`, the type of hashes.
+type MapType struct {
+	Token	token.Token
+	Key		TypeExpression
+	Value	TypeExpression
+}
+func (mt *MapType) typeNode() {}
+func (mt *MapType) TokenLiteral() string { return mt.Token.Literal }
+func (mt *MapType) String() string { return "map<" + mt.Key.String() + ", " + mt.Value.String() + ">" }
+
+// FunctionType is `fn(Params): Return`. When Rest, the last parameter is
+// the array collecting the remaining arguments, `fn(int, ...array<int>)`.
+// Return is nil when omitted.
+type FunctionType struct {
+	Token		token.Token
+	Params		[]TypeExpression
+	Rest		bool
+	Return		TypeExpression
+}
+func (ft *FunctionType) typeNode() {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) String() string {
+	params := []string{}
+
+	for i, param := range ft.Params {
+		if ft.Rest && i == len(ft.Params) - 1 {
+			params = append(params, "..." + param.String())
+		} else {
+			params = append(params, param.String())
+		}
+	}
+	output := "fn(" + strings.Join(params, ", ") + ")"
+
+	if ft.Return != nil {
+		output += ": " + ft.Return.String()
+	}
+
+	return output
+}
@@ -0,0 +1,730 @@
+package checker
+
+import (
+	"monkey/internal/ast"
+	"monkey/internal/diagnostic"
+	"monkey/internal/token"
+	"slices"
+)
+
+
+// Check verify the types of program, as far as they're known, and return
+// the mismatches in the order of the source. Typing is gradual: the type
+// of a declaration is its annotation, or the type of its value when it
+// has none, while parameters without annotation are of type any, which
+// is compatible with every type. The return type of a function without
+// annotation is the one of the values it return.
+//
+// Only the operations that are sure to fail are reported, like adding a
+// string to an int or passing a string where an int is expected.
+func Check(program *ast.Program) []diagnostic.Diagnostic {
+	c := &checker{ scope: &scope{ types: map[string]Type{} } }
+
+	c.declareFunctions(program.Statements)
+	c.checkStatements(program.Statements)
+
+	diagnostic.Sort(c.diagnostics)
+
+	return c.diagnostics
+}
+
+type checker struct {
+	scope			*scope
+	function		*function // the function being checked, nil at the top level
+	diagnostics		[]diagnostic.Diagnostic
+}
+
+type scope struct {
+	types		map[string]Type
+	outer		*scope
+}
+
+// function collect the types a function return while its body is checked.
+type function struct {
+	name		string
+	result		Type // the annotated return type, nil when inferred
+	returns		[]Type
+}
+
+func (c *checker) declare(name string, t Type) {
+	c.scope.types[name] = t
+}
+
+func (c *checker) lookup(name string) Type {
+	for s := c.scope; s != nil; s = s.outer {
+		if t, ok := s.types[name]; ok {
+			return t
+		}
+	}
+
+	return ANY
+}
+
+func (c *checker) enter() {
+	c.scope = &scope{ types: map[string]Type{}, outer: c.scope }
+}
+
+func (c *checker) leave() {
+	c.scope = c.scope.outer
+}
+
+// declareFunctions declare the functions of a block before its statements
+// are checked, as they are hoisted. Their return type is any until their
+// declaration is checked, unless it's annotated.
+func (c *checker) declareFunctions(statements []ast.Statement) {
+	for _, stmt := range statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+
+		if declaration, ok := stmt.(*ast.FunctionDeclaration); ok {
+			c.declare(declaration.Name.Value, signature(declaration.Function))
+		}
+	}
+}
+
+// signature return the type of a function as it's annotated.
+func signature(fn *ast.FunctionLiteral) *Function {
+	fnType := &Function{ Params: []Param{}, Return: ANY }
+
+	for _, param := range fn.Params {
+		t := Type(ANY)
+
+		if param.Type != nil {
+			t = fromAnnotation(param.Type)
+		}
+
+		if param.Rest {
+			if _, ok := t.(*Array); !ok {
+				t = &Array{ Element: ANY }
+			}
+			fnType.Rest = t
+			fnType.RestName = param.Name.Value
+			continue
+		}
+		fnType.Params = append(fnType.Params, Param{ Name: param.Name.Value, Type: t, Optional: param.Default != nil })
+	}
+
+	if fn.ReturnType != nil {
+		fnType.Return = fromAnnotation(fn.ReturnType)
+	}
+
+	return fnType
+}
+
+// checkStatements check a list of statements and return the type of
+// the value they give, which is the one of the last expression, or nil
+// when they can't complete because they end with a return or a throw.
+func (c *checker) checkStatements(statements []ast.Statement) Type {
+	var result Type = ANY
+
+	for _, stmt := range statements {
+		result = c.checkStatement(stmt)
+	}
+
+	return result
+}
+
+// checkBlock check a block in its own scope.
+func (c *checker) checkBlock(block *ast.BlockStatement, bindings map[string]Type) Type {
+	if block == nil {
+		return ANY
+	}
+	c.enter()
+	defer c.leave()
+
+	for name, t := range bindings {
+		c.declare(name, t)
+	}
+	c.declareFunctions(block.Statements)
+
+	return c.checkStatements(block.Statements)
+}
+
+func (c *checker) checkStatement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+
+	case *ast.ExpressionStatement:
+		return c.typeOf(stmt.Expression)
+
+	case *ast.DeclarationStatement:
+		c.checkDeclaration(stmt)
+
+	case *ast.ReturnStatement:
+		c.checkReturn(stmt.Token, c.typeOf(stmt.ReturnValue))
+		return nil
+
+	case *ast.ThrowStatement:
+		c.typeOf(stmt.Value)
+		return nil
+
+	case *ast.BlockStatement:
+		return c.checkStatements(stmt.Statements)
+
+	case *ast.FunctionDeclaration:
+		c.declare(stmt.Name.Value, c.checkFunction(stmt.Function))
+
+	case *ast.ExportStatement:
+		c.checkStatement(stmt.Statement)
+
+	case *ast.ImportStatement:
+		c.declare(stmt.Alias.Value, ANY)
+
+	case *ast.TryStatement:
+		c.checkBlock(stmt.Block, nil)
+
+		if stmt.CatchParam != nil {
+			c.checkBlock(stmt.Catch, map[string]Type{ stmt.CatchParam.Value: ANY })
+		} else {
+			c.checkBlock(stmt.Catch, nil)
+		}
+		c.checkBlock(stmt.Finally, nil)
+
+	case *ast.ForInStatement:
+		element := c.elementOf(stmt.Iterable)
+		c.checkBlock(stmt.Body, map[string]Type{ stmt.Variable.Value: element })
+	}
+
+	return ANY
+}
+
+func (c *checker) checkDeclaration(stmt *ast.DeclarationStatement) {
+	value := c.typeOf(stmt.Value)
+
+	if stmt.Type == nil {
+		c.declare(stmt.Name.Value, value)
+		return
+	}
+	declared := fromAnnotation(stmt.Type)
+
+	if !assignable(value, declared) {
+		c.error(stmt.Name.Token, "cannot assign %s to %s of type %s", value, stmt.Name.Value, declared)
+	}
+	c.declare(stmt.Name.Value, declared)
+}
+
+// checkReturn check a value returned from the current function.
+func (c *checker) checkReturn(tok token.Token, t Type) {
+	if c.function == nil {
+		return
+	}
+
+	if c.function.result != nil && !assignable(t, c.function.result) {
+		c.error(tok, "cannot return %s from %s, which returns %s", t, c.function.name, c.function.result)
+	}
+	c.function.returns = append(c.function.returns, t)
+}
+
+// checkFunction check the body of a function and return its type,
+// whose return type is inferred when it's not annotated.
+func (c *checker) checkFunction(fn *ast.FunctionLiteral) *Function {
+	fnType := signature(fn)
+
+	name := fn.Name
+
+	if name == "" {
+		name = "function"
+	}
+
+	outer := c.function
+	c.function = &function{ name: name }
+
+	if fn.ReturnType != nil {
+		c.function.result = fnType.Return
+	}
+
+	c.enter()
+
+	for i, param := range fn.Params {
+		if param.Rest {
+			if annotated := fromAnnotation(param.Type); param.Type != nil && annotated != ANY {
+				if _, ok := annotated.(*Array); !ok {
+					c.error(param.Name.Token, "rest parameter %s must be an array, not %s", param.Name.Value, annotated)
+				}
+			}
+			c.declare(param.Name.Value, fnType.Rest)
+			continue
+		}
+
+		if param.Default != nil {
+			value := c.typeOf(param.Default)
+
+			if !assignable(value, fnType.Params[i].Type) {
+				c.error(param.Name.Token, "cannot use %s as the default value of %s of type %s", value, param.Name.Value, fnType.Params[i].Type)
+			}
+		}
+		c.declare(param.Name.Value, fnType.Params[i].Type)
+	}
+
+	c.declareFunctions(fn.Body.Statements)
+	value := c.checkStatements(fn.Body.Statements)
+
+	if value != nil {
+		c.checkReturn(lastToken(fn.Body), value)
+	}
+
+	if fn.ReturnType == nil {
+		fnType.Return = ANY
+
+		for i, t := range c.function.returns {
+			if i == 0 {
+				fnType.Return = t
+			} else {
+				fnType.Return = join(fnType.Return, t)
+			}
+		}
+	}
+
+	c.leave()
+	c.function = outer
+
+	return fnType
+}
+
+
+func (c *checker) typeOf(expr ast.Expression) Type {
+	switch expr := expr.(type) {
+
+	case *ast.IntegerLiteral:
+		return INT
+
+	case *ast.FloatLiteral:
+		return FLOAT
+
+	case *ast.StringLiteral:
+		return STRING
+
+	case *ast.Boolean:
+		return BOOL
+
+	case *ast.Identifier:
+		return c.lookup(expr.Value)
+
+	case *ast.PrefixExpression:
+		return c.prefixType(expr)
+
+	case *ast.PostfixExpression:
+		return c.updateType(expr.Token, expr.Operator, c.typeOf(expr.Left))
+
+	case *ast.InfixExpression:
+		left := c.typeOf(expr.Left)
+		right := c.typeOf(expr.Right)
+		t, ok := infixType(expr.Operator, left, right)
+
+		if !ok {
+			c.error(expr.Token, "unsupported operand types for %s: %s and %s", expr.Operator, left, right)
+		}
+		return t
+
+	case *ast.IfElseExpression:
+		c.typeOf(expr.Condition)
+		consequence := c.checkBlock(expr.Consequence, nil)
+
+		if expr.Alternative == nil {
+			return ANY
+		}
+		alternative := c.checkBlock(expr.Alternative, nil)
+
+		switch {
+		case consequence == nil && alternative == nil:
+			return ANY
+		case consequence == nil:
+			return alternative
+		case alternative == nil:
+			return consequence
+		}
+		return join(consequence, alternative)
+
+	case *ast.ArrayLiteral:
+		return &Array{ Element: c.joinAll(expr.Elements) }
+
+	case *ast.HashLiteral:
+		return &Map{ Key: c.joinAll(expr.Keys), Value: c.joinAll(expr.Values) }
+
+	case *ast.FunctionLiteral:
+		return c.checkFunction(expr)
+
+	case *ast.FunctionCallExpression:
+		return c.callType(expr)
+
+	case *ast.IndexExpression:
+		return c.indexType(expr)
+
+	case *ast.MemberExpression:
+		c.typeOf(expr.Object)
+
+	case *ast.SliceExpression:
+		left := c.typeOf(expr.Left)
+
+		for _, bound := range []ast.Expression{ expr.Start, expr.Stop, expr.Step } {
+			if bound != nil {
+				c.typeOf(bound)
+			}
+		}
+
+		if _, ok := left.(*Array); ok || left == STRING {
+			return left
+		}
+
+	case *ast.RangeExpression:
+		c.typeOf(expr.Start)
+		c.typeOf(expr.End)
+		return RANGE
+
+	case *ast.SpreadExpression:
+		return c.elementOf(expr.Value)
+
+	case *ast.NamedArgument:
+		return c.typeOf(expr.Value)
+	}
+
+	return ANY
+}
+
+// joinAll return the type shared by the elements of an
+// array literal, or the keys or values of a hash literal.
+func (c *checker) joinAll(expressions []ast.Expression) Type {
+	var shared Type = ANY
+
+	for i, expr := range expressions {
+		t := c.typeOf(expr)
+
+		if i == 0 {
+			shared = t
+		} else {
+			shared = join(shared, t)
+		}
+	}
+
+	return shared
+}
+
+func (c *checker) prefixType(expr *ast.PrefixExpression) Type {
+	right := c.typeOf(expr.Right)
+
+	switch expr.Operator {
+
+	case "!":
+		return BOOL
+
+	case "-":
+		if right == FLOAT {
+			return FLOAT
+		}
+
+		if right == INT || right == BOOL {
+			return INT
+		}
+
+	case "~":
+		if right != INT && right != ANY {
+			c.error(expr.Token, "unsupported operand type for ~: %s", right)
+		}
+		return INT
+
+	case "++", "--":
+		return c.updateType(expr.Token, expr.Operator, right)
+	}
+
+	return ANY
+}
+
+func (c *checker) updateType(tok token.Token, operator string, t Type) Type {
+	if t != INT && t != FLOAT && t != ANY {
+		c.error(tok, "unsupported operand type for %s: %s", operator, t)
+		return ANY
+	}
+
+	return t
+}
+
+// elementOf return the type of the elements a for-in loop
+// or a spread argument iterate over.
+func (c *checker) elementOf(iterable ast.Expression) Type {
+	t := c.typeOf(iterable)
+
+	switch t := t.(type) {
+
+	case *Array:
+		return t.Element
+
+	case Basic:
+		switch t {
+
+		case STRING:
+			return STRING
+
+		case RANGE:
+			return INT
+
+		case ANY:
+			return ANY
+		}
+	}
+
+	c.error(firstToken(iterable), "cannot iterate over %s", t)
+
+	return ANY
+}
+
+func (c *checker) indexType(expr *ast.IndexExpression) Type {
+	left := c.typeOf(expr.Left)
+	index := c.typeOf(expr.Index)
+
+	switch left := left.(type) {
+
+	case *Array:
+		if !assignable(index, INT) {
+			c.error(expr.Token, "cannot index %s with %s", left, index)
+		}
+		return left.Element
+
+	case *Map:
+		if !assignable(index, left.Key) {
+			c.error(expr.Token, "cannot index %s with %s", left, index)
+		}
+		return left.Value
+
+	case Basic:
+		if left == STRING {
+			if !assignable(index, INT) {
+				c.error(expr.Token, "cannot index %s with %s", left, index)
+			}
+			return STRING
+		}
+	}
+
+	return ANY
+}
+
+// callType check the arguments of a call against the parameters of the
+// function called, when its type is known, and return its return type.
+func (c *checker) callType(call *ast.FunctionCallExpression) Type {
+	callee := c.typeOf(call.Function)
+	args := make([]Type, len(call.Arguments))
+
+	for i, arg := range call.Arguments {
+		args[i] = c.typeOf(arg)
+	}
+
+	fn, ok := callee.(*Function)
+
+	if !ok {
+		if callee != ANY {
+			c.error(call.Token, "cannot call %s", callee)
+		}
+		return ANY
+	}
+	name := "function"
+
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		name = ident.Value
+	}
+
+	positional := 0
+	spread := false
+	named := map[string]Type{}
+
+	for i, arg := range call.Arguments {
+		switch arg := arg.(type) {
+
+		case *ast.SpreadExpression:
+			spread = true
+
+		case *ast.NamedArgument:
+			named[arg.Name.Value] = args[i]
+
+		default:
+			if spread {
+				continue
+			}
+			positional++
+
+			expected := fn.Rest
+
+			if i < len(fn.Params) {
+				expected = fn.Params[i].Type
+			} else if expected != nil {
+				expected = expected.(*Array).Element
+			}
+
+			if expected != nil && !assignable(args[i], expected) {
+				c.error(firstToken(arg), "cannot use %s as %s in argument %d to %s", args[i], expected, i + 1, name)
+			}
+		}
+	}
+
+	if !spread && fn.Rest == nil && positional > len(fn.Params) {
+		c.error(call.Token, "too many arguments in call to %s: want at most %d, got %d", name, len(fn.Params), positional)
+	}
+
+	for i, param := range fn.Params {
+		// The parameters of a function annotation have no name,
+		// so named arguments can only be checked against literals.
+		if param.Name == "" {
+			continue
+		}
+		t, isNamed := named[param.Name]
+		delete(named, param.Name)
+
+		switch {
+
+		case isNamed && !assignable(t, param.Type):
+			c.error(call.Token, "cannot use %s as %s in argument %s to %s", t, param.Type, param.Name, name)
+
+		case !isNamed && i >= positional && !spread && !param.Optional:
+			c.error(call.Token, "missing argument for parameter %s of %s", param.Name, name)
+		}
+	}
+
+	if len(fn.Params) != 0 && fn.Params[0].Name != "" {
+		for _, unknown := range sortedKeys(named) {
+			c.error(call.Token, "unknown parameter name in call to %s: %s", name, unknown)
+		}
+	}
+
+	return fn.Return
+}
+
+func (c *checker) error(tok token.Token, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, diagnostic.Errorf(tok, format, args...))
+}
+
+
+var comparisonOperators = []string{ "==", "!=", "<", ">", "<=", ">=" }
+var bitwiseOperators = []string{ "&", "|", "^", "<<", ">>" }
+
+// infixType return the type of the result of a binary operator, and
+// false if it's sure to fail on operands of types left and right. The
+// rules are the ones of object.Infix: bitwise operators take integers,
+// strings can only be concatenated and compared for equality, and
+// the other operators take numbers or booleans. Their result is an int
+// whenever it's integral, so only a division of integers can be either.
+func infixType(operator string, left, right Type) (Type, bool) {
+	comparison := slices.Contains(comparisonOperators, operator)
+	bitwise := slices.Contains(bitwiseOperators, operator)
+
+	switch {
+
+	case bitwise:
+		return INT, (left == INT || left == ANY) && (right == INT || right == ANY)
+
+	case comparison && (left == ANY || right == ANY):
+		return BOOL, true
+
+	case left == ANY || right == ANY:
+		return ANY, true
+
+	case left == STRING && right == STRING:
+		if operator == "+" {
+			return STRING, true
+		}
+		return BOOL, operator == "==" || operator == "!="
+
+	case !numeric(left) || !numeric(right):
+		return ANY, false
+
+	case comparison || (left == BOOL && right == BOOL):
+		return BOOL, true
+
+	case left == FLOAT || right == FLOAT:
+		return FLOAT, true
+
+	// Integers give an int when they divide evenly, a float otherwise.
+	case operator == "/":
+		return ANY, true
+	}
+
+	return INT, true
+}
+
+func numeric(t Type) bool {
+	return t == INT || t == FLOAT || t == BOOL
+}
+
+func sortedKeys(m map[string]Type) []string {
+	keys := []string{}
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// firstToken return the token an expression start with.
+func firstToken(expr ast.Expression) token.Token {
+	switch expr := expr.(type) {
+
+	case *ast.InfixExpression:
+		return firstToken(expr.Left)
+
+	case *ast.PostfixExpression:
+		return firstToken(expr.Left)
+
+	case *ast.FunctionCallExpression:
+		return firstToken(expr.Function)
+
+	case *ast.IndexExpression:
+		return firstToken(expr.Left)
+
+	case *ast.SliceExpression:
+		return firstToken(expr.Left)
+
+	case *ast.MemberExpression:
+		return firstToken(expr.Object)
+
+	case *ast.RangeExpression:
+		return firstToken(expr.Start)
+
+	case *ast.Identifier:
+		return expr.Token
+
+	case *ast.IntegerLiteral:
+		return expr.Token
+
+	case *ast.FloatLiteral:
+		return expr.Token
+
+	case *ast.StringLiteral:
+		return expr.Token
+
+	case *ast.Boolean:
+		return expr.Token
+
+	case *ast.PrefixExpression:
+		return expr.Token
+
+	case *ast.ArrayLiteral:
+		return expr.Token
+
+	case *ast.HashLiteral:
+		return expr.Token
+
+	case *ast.FunctionLiteral:
+		return expr.Token
+
+	case *ast.IfElseExpression:
+		return expr.Token
+
+	case *ast.SpreadExpression:
+		return expr.Token
+	}
+
+	return token.Token{}
+}
+
+// lastToken return the first token of the last statement of a block,
+// where the value a function give without `return` is computed.
+func lastToken(block *ast.BlockStatement) token.Token {
+	if len(block.Statements) == 0 {
+		return block.Token
+	}
+
+	if stmt, ok := block.Statements[len(block.Statements) - 1].(*ast.ExpressionStatement); ok && stmt.Expression != nil {
+		return firstToken(stmt.Expression)
+	}
+
+	return block.Token
+}
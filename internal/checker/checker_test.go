@@ -0,0 +1,128 @@
+package checker
+
+import (
+	"monkey/internal/ast"
+	"monkey/internal/lexer"
+	"monkey/internal/parser"
+	"slices"
+	"testing"
+)
+
+
+func TestCheck(t *testing.T) {
+
+	t.Run("it should accept well-typed programs", func(t *testing.T) {
+		tests := []string{
+			"let x: int = 5; let y: float = x; let z: float = 1.5 * 2; y + z",
+			"fn add(a: int, b: int): int { a + b } add(1, 2) + 3",
+			"let xs: array<int> = []; let m: map<string, int> = { \"a\": 1 }; m[\"a\"] + xs[0]",
+			"fn f(n) { n + 1 } f(\"a\")",
+			"fn f(a: int, b: int = 2, ...rest: array<int>) { rest } f(1); f(1, b: 3); f(1, 2, 3, 4); f(...[1, 2])",
+			"let apply: fn(fn(int): int, int): int = fn(f: fn(int): int, x: int): int { f(x) }; apply(fn(x: int): int { x * 2 }, 3)",
+			"for (i in 0..3) { i << 1 } for (c in \"abc\") { c + \"!\" } for (x in [1.5]) { x * 2 }",
+			"fn fib(n: int): int { if (n < 2) { return n } fib(n - 1) + fib(n - 2) }",
+			"fn f(): int { if (true) { 1 } else { return 2 } }",
+			"fn f(): int { throw \"not yet\" }",
+			"let s = \"a\" + \"b\"; s == \"ab\"; s[0] + s[1:]",
+			"true + 1; !\"x\"; -true; 1 < 2.5; true == false",
+			"try { throw \"x\" } catch (e) { e.message + 1 }",
+			"import \"m.mk\" as m; m.f(1) + 1",
+			"let n = 1; n++; let f = 1.5; --f",
+			"fn f(x: any): string { x } f(1)",
+			"let h: map<string, array<float>> = { \"a\": [1, 2.5] }",
+			"fn half(n: int): int { n / 2 } let h: int = half(4); let q: float = 1 / 3",
+		}
+
+		for i, input := range tests {
+			if diagnostics := Check(parse(t, input)); len(diagnostics) != 0 {
+				t.Fatalf("[test #%d] %s\nExpecting no diagnostics, but got %v\n", i, input, diagnostics)
+			}
+		}
+	})
+
+	t.Run("it should report type mismatches", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	[]string
+		}{
+			{ "let x: int = \"a\"", []string{ "<input>:1:5: error: cannot assign string to x of type int" } },
+			{ "let x: int = 1.5", []string{ "<input>:1:5: error: cannot assign float to x of type int" } },
+			{ "let xs: array<int> = [\"a\"]", []string{ "<input>:1:5: error: cannot assign array<string> to xs of type array<int>" } },
+			{ "\"a\" - 1", []string{ "<input>:1:5: error: unsupported operand types for -: string and int" } },
+			{ "let n = 5; n + \"a\"", []string{ "<input>:1:14: error: unsupported operand types for +: int and string" } },
+			{ "1.5 & 1", []string{ "<input>:1:5: error: unsupported operand types for &: float and int" } },
+			{ "true + fn() { }", []string{ "<input>:1:6: error: unsupported operand types for +: bool and fn(): any" } },
+			{ "~1.5", []string{ "<input>:1:1: error: unsupported operand type for ~: float" } },
+			{ "let s = \"a\"; s++", []string{ "<input>:1:15: error: unsupported operand type for ++: string" } },
+			{ "fn add(a: int, b: int): int { a + b } add(1, \"2\")", []string{ "<input>:1:46: error: cannot use string as int in argument 2 to add" } },
+			{ "fn add(a: int, b: int): int { a + b } add(1)", []string{ "<input>:1:42: error: missing argument for parameter b of add" } },
+			{ "fn add(a: int, b: int): int { a + b } add(1, 2, 3)", []string{ "<input>:1:42: error: too many arguments in call to add: want at most 2, got 3" } },
+			{ "fn add(a: int, b: int): int { a + b } add(1, c: 2)", []string{ "<input>:1:42: error: missing argument for parameter b of add", "<input>:1:42: error: unknown parameter name in call to add: c" } },
+			{ "fn add(a: int, b: int): int { a + b } add(b: 1.5, a: 1)", []string{ "<input>:1:42: error: cannot use float as int in argument b to add" } },
+			{ "fn f(...xs: array<int>) { xs } f(1, \"a\")", []string{ "<input>:1:37: error: cannot use string as int in argument 2 to f" } },
+			{ "fn f(): int { \"a\" }", []string{ "<input>:1:15: error: cannot return string from f, which returns int" } },
+			{ "fn f(): int { return 1.5 }", []string{ "<input>:1:15: error: cannot return float from f, which returns int" } },
+			{ "let f = fn(x: int) { x }; f(true)", []string{ "<input>:1:29: error: cannot use bool as int in argument 1 to f" } },
+			{ "5(1)", []string{ "<input>:1:2: error: cannot call int" } },
+			{ "let m: map<string, int> = {}; m[1]", []string{ "<input>:1:32: error: cannot index map<string, int> with int" } },
+			{ "for (x in 5) { x }", []string{ "<input>:1:11: error: cannot iterate over int" } },
+			{ "fn f(...rest: int) { rest }", []string{ "<input>:1:9: error: rest parameter rest must be an array, not int" } },
+			{ "fn f(x: int = \"a\") { x }", []string{ "<input>:1:6: error: cannot use string as the default value of x of type int" } },
+			{ "let g: fn(int): int = fn(x: string): int { 1 }", []string{ "<input>:1:5: error: cannot assign fn(string): int to g of type fn(int): int" } },
+			{ "fn half(n: float) { n / 2 } let h: int = half(4)", []string{ "<input>:1:33: error: cannot assign float to h of type int" } },
+			{ "fn name() { \"monkey\" } name() * 2", []string{ "<input>:1:31: error: unsupported operand types for *: string and int" } },
+			{ "fn f(xs: array<int>) { for (x in xs) { x + \"!\" } }", []string{ "<input>:1:42: error: unsupported operand types for +: int and string" } },
+		}
+
+		for i, tt := range tests {
+			got := []string{}
+
+			for _, diagnostic := range Check(parse(t, tt.input)) {
+				got = append(got, diagnostic.String())
+			}
+
+			if !slices.Equal(got, tt.expected) {
+				t.Fatalf("[test #%d] %s\nExpecting %q, but got %q\n", i, tt.input, tt.expected, got)
+			}
+		}
+	})
+}
+
+func TestAssignable(t *testing.T) {
+	tests := []struct{
+		from		Type
+		to			Type
+		expected	bool
+	}{
+		{ INT, FLOAT, true },
+		{ FLOAT, INT, false },
+		{ ANY, STRING, true },
+		{ STRING, ANY, true },
+		{ BOOL, INT, false },
+		{ &Array{ Element: INT }, &Array{ Element: FLOAT }, true },
+		{ &Array{ Element: ANY }, &Array{ Element: STRING }, true },
+		{ &Map{ Key: STRING, Value: INT }, &Map{ Key: INT, Value: INT }, false },
+		{ &Function{ Params: []Param{ { Type: FLOAT } }, Return: INT }, &Function{ Params: []Param{ { Type: INT } }, Return: FLOAT }, true },
+		{ &Function{ Params: []Param{ { Type: INT } }, Return: INT }, &Function{ Params: []Param{ { Type: FLOAT } }, Return: INT }, false },
+		{ &Function{ Return: INT }, &Function{ Params: []Param{ { Type: INT } }, Return: INT }, false },
+		{ &Function{ Rest: &Array{ Element: INT }, Return: INT }, &Function{ Return: INT }, false },
+	}
+
+	for i, tt := range tests {
+		if got := assignable(tt.from, tt.to); got != tt.expected {
+			t.Fatalf("[test #%d]: Expecting assignable(%s, %s) to be %t, but got %t\n", i, tt.from, tt.to, tt.expected, got)
+		}
+	}
+}
+
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Expecting no parser errors, but got %v\n", p.Errors())
+	}
+
+	return program
+}
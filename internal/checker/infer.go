@@ -0,0 +1,1172 @@
+package checker
+
+import (
+	"monkey/internal/ast"
+	"monkey/internal/diagnostic"
+	"monkey/internal/token"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+
+// Var is a type variable of the inference, standing for a type that
+// isn't known yet. It's bound to a type once uses of the value tell it.
+type Var struct {
+	id			int
+	instance	Type // nil while unbound
+	param		bool // the type of a parameter, which a call can give any value
+}
+
+func (v *Var) String() string {
+	if v.instance != nil {
+		return v.instance.String()
+	}
+
+	return newNamer().format(v)
+}
+
+// scheme is the type of a binding, generic over vars: each use of the
+// binding get its own copy of the type, with fresh variables.
+type scheme struct {
+	vars	[]*Var
+	t		Type
+}
+
+// Signature is the inferred type of a top-level binding.
+type Signature struct {
+	Name		string
+	Kind		string // "let", "const" or "fn"
+	Type		Type
+	Token		token.Token
+}
+
+// String write the signature like an annotated declaration, where
+// type variables are named 'a, 'b, ... in order of appearance:
+//
+//	fn map(xs: array<'a>, f: fn('a): 'b): array<'b>
+//	let limit: int
+func (s Signature) String() string {
+	n := newNamer()
+	fn, ok := prune(s.Type).(*Function)
+
+	if s.Kind != "fn" || !ok {
+		return s.Kind + " " + s.Name + ": " + n.format(s.Type)
+	}
+	params := []string{}
+
+	for _, param := range fn.Params {
+		params = append(params, param.Name + ": " + n.format(param.Type))
+	}
+
+	if fn.Rest != nil {
+		params = append(params, "..." + fn.RestName + ": " + n.format(fn.Rest))
+	}
+
+	return "fn " + s.Name + "(" + strings.Join(params, ", ") + "): " + n.format(fn.Return)
+}
+
+// Infer infer the types of program, annotated or not, in the manner of
+// Hindley-Milner: each unknown type is a variable, bound by unifying
+// the types an expression must have given how it's used, like the
+// operands of an infix expression or the arguments and parameters of
+// a call. Functions bound with `let` or declared with `fn` are generic
+// over the variables their type keep.
+//
+// Monkey being dynamically typed, what the inference can't tell, like
+// a member of a hash, is of type any, and values that may be of several
+// types, like an if-else expression whose branches differ, are too.
+// Only the errors that are sure to happen are reported, like
+// `true + fn() {}` or a call passing a string where an int is used.
+//
+// It return the signature of each top-level binding, in the order of
+// their declaration, and the errors in the order of the source.
+func Infer(program *ast.Program) ([]Signature, []diagnostic.Diagnostic) {
+	in := &inferer{ scope: &inferScope{ schemes: map[string]*scheme{} } }
+	signatures := []Signature{}
+	seen := map[string]int{}
+
+	in.declareFunctions(program.Statements)
+
+	for _, stmt := range program.Statements {
+		in.inferStatement(stmt)
+
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+		var name *ast.Identifier
+		kind := "fn"
+
+		switch stmt := stmt.(type) {
+
+		case *ast.DeclarationStatement:
+			name, kind = stmt.Name, stmt.Token.Literal
+
+		case *ast.FunctionDeclaration:
+			name = stmt.Name
+		}
+
+		if name == nil {
+			continue
+		}
+		signature := Signature{ Name: name.Value, Kind: kind, Token: name.Token }
+
+		if i, ok := seen[name.Value]; ok {
+			signatures[i] = signature
+		} else {
+			seen[name.Value] = len(signatures)
+			signatures = append(signatures, signature)
+		}
+	}
+
+	// Types are read once the whole program is inferred,
+	// as later uses can bind the variables they hold.
+	for i, signature := range signatures {
+		signatures[i].Type = in.scope.schemes[signature.Name].t
+	}
+
+	diagnostic.Sort(in.diagnostics)
+
+	return signatures, in.diagnostics
+}
+
+type inferer struct {
+	scope			*inferScope
+	function		*inferFunction // the function being inferred, nil at the top level
+	vars			int
+	trail			[]binding // bindings of variables, in order, to undo failed unifications
+	diagnostics		[]diagnostic.Diagnostic
+}
+
+// binding is a variable bound by a unification, along with the
+// type it stood for before, nil if it was unbound.
+type binding struct {
+	v			*Var
+	previous	Type
+}
+
+type inferScope struct {
+	schemes		map[string]*scheme
+	outer		*inferScope
+}
+
+type inferFunction struct {
+	name		string
+	result		Type
+	annotated	bool
+	mixed		bool // returning values of several types, so any
+}
+
+func (in *inferer) fresh() *Var {
+	in.vars++
+
+	return &Var{ id: in.vars }
+}
+
+func (in *inferer) enter() {
+	in.scope = &inferScope{ schemes: map[string]*scheme{}, outer: in.scope }
+}
+
+func (in *inferer) leave() {
+	in.scope = in.scope.outer
+}
+
+func (in *inferer) declare(name string, t Type) {
+	in.scope.schemes[name] = &scheme{ t: t }
+}
+
+func (in *inferer) lookup(name string) Type {
+	for s := in.scope; s != nil; s = s.outer {
+		if sc, ok := s.schemes[name]; ok {
+			return in.instantiate(sc)
+		}
+	}
+
+	return ANY
+}
+
+// error report an error, formatting the types of args
+// with the same names for the same variables.
+func (in *inferer) error(tok token.Token, format string, args ...any) {
+	n := newNamer()
+
+	for i, arg := range args {
+		if t, ok := arg.(Type); ok {
+			args[i] = n.format(t)
+		}
+	}
+	in.diagnostics = append(in.diagnostics, diagnostic.Errorf(tok, format, args...))
+}
+
+
+// prune return the type t stand for, following bound variables.
+func prune(t Type) Type {
+	for {
+		v, ok := t.(*Var)
+
+		if !ok || v.instance == nil {
+			return t
+		}
+		t = v.instance
+	}
+}
+
+// unify make a and b the same type by binding their variables, and
+// report whether it's possible. On failure, no variable is bound. Any
+// unify with every type, and int, float and bool with each other, as
+// the operators convert them. A variable bound to int is widened to
+// float when unified with a float, like arithmetic mixing them give a
+// float at runtime.
+func (in *inferer) unify(a, b Type) bool {
+	mark := len(in.trail)
+
+	if in.unifyTypes(a, b) {
+		return true
+	}
+
+	for i := len(in.trail) - 1; i >= mark; i-- {
+		in.trail[i].v.instance = in.trail[i].previous
+	}
+	in.trail = in.trail[:mark]
+
+	return false
+}
+
+func (in *inferer) unifyTypes(a, b Type) bool {
+	if in.widen(a, b) || in.widen(b, a) {
+		return true
+	}
+	a, b = prune(a), prune(b)
+
+	if a == b || a == ANY || b == ANY {
+		return true
+	}
+
+	if v, ok := a.(*Var); ok {
+		// The variable of a parameter is kept over another one,
+		// so an if-else returning it is still widened.
+		if w, ok := b.(*Var); ok && v.param && !w.param {
+			return in.bind(w, v)
+		}
+		return in.bind(v, b)
+	}
+
+	if v, ok := b.(*Var); ok {
+		return in.bind(v, a)
+	}
+
+	switch a := a.(type) {
+
+	case Basic:
+		b, ok := b.(Basic)
+		return ok && (a == b || (numeric(a) && numeric(b)))
+
+	case *Array:
+		b, ok := b.(*Array)
+		return ok && in.unifyTypes(a.Element, b.Element)
+
+	case *Map:
+		b, ok := b.(*Map)
+		return ok && in.unifyTypes(a.Key, b.Key) && in.unifyTypes(a.Value, b.Value)
+
+	case *Function:
+		b, ok := b.(*Function)
+		return ok && in.unifyFunctions(a, b)
+	}
+
+	return false
+}
+
+// unifyFunctions unify the parameters two functions have in common,
+// the extra ones of either being optional, and their results.
+func (in *inferer) unifyFunctions(a, b *Function) bool {
+	for i := 0; i < len(a.Params) || i < len(b.Params); i++ {
+		switch {
+
+		case i >= len(a.Params):
+			if !b.Params[i].Optional && a.Rest == nil {
+				return false
+			}
+
+		case i >= len(b.Params):
+			if !a.Params[i].Optional && b.Rest == nil {
+				return false
+			}
+
+		case !in.unifyTypes(a.Params[i].Type, b.Params[i].Type):
+			return false
+		}
+	}
+
+	if a.Rest != nil && b.Rest != nil && !in.unifyTypes(a.Rest, b.Rest) {
+		return false
+	}
+
+	return in.unifyTypes(a.Return, b.Return)
+}
+
+// bind bind v to t, unless t contain v: such a recursive type, like the
+// one of `x` in `x(x)`, can't be written, so v is left unbound.
+func (in *inferer) bind(v *Var, t Type) bool {
+	if !occurs(v, t) {
+		in.trail = append(in.trail, binding{ v: v, previous: v.instance })
+		v.instance = t
+	}
+
+	return true
+}
+
+// widen bind the variable a stand for to float, if it's bound
+// to int and b is a float, reporting whether it did.
+func (in *inferer) widen(a, b Type) bool {
+	v, ok := a.(*Var)
+
+	for ok && v.instance != nil {
+		if next, isVar := v.instance.(*Var); isVar {
+			v = next
+			continue
+		}
+
+		if v.instance != INT || prune(b) != FLOAT {
+			return false
+		}
+		in.trail = append(in.trail, binding{ v: v, previous: v.instance })
+		v.instance = FLOAT
+
+		return true
+	}
+
+	return false
+}
+
+func occurs(v *Var, t Type) bool {
+	switch t := prune(t).(type) {
+
+	case *Var:
+		return t == v
+
+	case *Array:
+		return occurs(v, t.Element)
+
+	case *Map:
+		return occurs(v, t.Key) || occurs(v, t.Value)
+
+	case *Function:
+		for _, param := range t.Params {
+			if occurs(v, param.Type) {
+				return true
+			}
+		}
+		return (t.Rest != nil && occurs(v, t.Rest)) || occurs(v, t.Return)
+	}
+
+	return false
+}
+
+// generalize return the scheme of t, generic over the variables it
+// holds that aren't also held by the bindings of the enclosing scopes.
+func (in *inferer) generalize(t Type) *scheme {
+	bound := map[*Var]bool{}
+
+	for s := in.scope; s != nil; s = s.outer {
+		for _, sc := range s.schemes {
+			for _, v := range freeVars(sc.t, nil) {
+				bound[v] = true
+			}
+		}
+	}
+	sc := &scheme{ t: t }
+
+	for _, v := range freeVars(t, nil) {
+		if !bound[v] {
+			sc.vars = append(sc.vars, v)
+		}
+	}
+
+	return sc
+}
+
+// freeVars append the unbound variables of t to vars, once each.
+func freeVars(t Type, vars []*Var) []*Var {
+	switch t := prune(t).(type) {
+
+	case *Var:
+		for _, v := range vars {
+			if v == t {
+				return vars
+			}
+		}
+		return append(vars, t)
+
+	case *Array:
+		return freeVars(t.Element, vars)
+
+	case *Map:
+		return freeVars(t.Value, freeVars(t.Key, vars))
+
+	case *Function:
+		for _, param := range t.Params {
+			vars = freeVars(param.Type, vars)
+		}
+
+		if t.Rest != nil {
+			vars = freeVars(t.Rest, vars)
+		}
+		return freeVars(t.Return, vars)
+	}
+
+	return vars
+}
+
+// instantiate return the type of a scheme with fresh variables.
+func (in *inferer) instantiate(sc *scheme) Type {
+	if len(sc.vars) == 0 {
+		return sc.t
+	}
+	fresh := map[*Var]Type{}
+
+	for _, v := range sc.vars {
+		fresh[v] = in.fresh()
+	}
+
+	return substitute(sc.t, fresh)
+}
+
+func substitute(t Type, vars map[*Var]Type) Type {
+	switch t := prune(t).(type) {
+
+	case *Var:
+		if replacement, ok := vars[t]; ok {
+			return replacement
+		}
+		return t
+
+	case *Array:
+		return &Array{ Element: substitute(t.Element, vars) }
+
+	case *Map:
+		return &Map{ Key: substitute(t.Key, vars), Value: substitute(t.Value, vars) }
+
+	case *Function:
+		fn := &Function{ Params: make([]Param, len(t.Params)), Return: substitute(t.Return, vars) }
+
+		for i, param := range t.Params {
+			fn.Params[i] = Param{ Name: param.Name, Type: substitute(param.Type, vars), Optional: param.Optional }
+		}
+
+		if t.Rest != nil {
+			fn.Rest = substitute(t.Rest, vars)
+			fn.RestName = t.RestName
+		}
+		return fn
+	}
+
+	return t
+}
+
+
+// declareFunctions bind the functions declared in a block before its
+// statements are inferred, as they are hoisted. Until its declaration is
+// inferred, a function isn't generic: its uses tell the type it must have.
+func (in *inferer) declareFunctions(statements []ast.Statement) {
+	for _, stmt := range statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+
+		if declaration, ok := stmt.(*ast.FunctionDeclaration); ok {
+			in.declare(declaration.Name.Value, in.fresh())
+		}
+	}
+}
+
+// inferStatements infer a list of statements and return the type of the
+// value they give, nil when they end with a return or a throw.
+func (in *inferer) inferStatements(statements []ast.Statement) Type {
+	var result Type = ANY
+
+	for _, stmt := range statements {
+		result = in.inferStatement(stmt)
+	}
+
+	return result
+}
+
+func (in *inferer) inferBlock(block *ast.BlockStatement, bindings map[string]Type) Type {
+	if block == nil {
+		return ANY
+	}
+	in.enter()
+	defer in.leave()
+
+	for name, t := range bindings {
+		in.declare(name, t)
+	}
+	in.declareFunctions(block.Statements)
+
+	return in.inferStatements(block.Statements)
+}
+
+func (in *inferer) inferStatement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+
+	case *ast.ExpressionStatement:
+		return in.infer(stmt.Expression)
+
+	case *ast.DeclarationStatement:
+		in.inferDeclaration(stmt)
+
+	case *ast.ReturnStatement:
+		in.inferReturn(stmt.Token, in.infer(stmt.ReturnValue))
+		return nil
+
+	case *ast.ThrowStatement:
+		in.infer(stmt.Value)
+		return nil
+
+	case *ast.BlockStatement:
+		return in.inferStatements(stmt.Statements)
+
+	case *ast.FunctionDeclaration:
+		name := stmt.Name.Value
+		hoisted := in.scope.schemes[name]
+
+		t := in.inferFunction(stmt.Function)
+
+		if hoisted != nil {
+			in.unify(hoisted.t, t)
+		}
+
+		// The declaration is generalized over the variables
+		// the function don't share with the other bindings.
+		delete(in.scope.schemes, name)
+		in.scope.schemes[name] = in.generalize(t)
+
+	case *ast.ExportStatement:
+		in.inferStatement(stmt.Statement)
+
+	case *ast.ImportStatement:
+		in.declare(stmt.Alias.Value, ANY)
+
+	case *ast.TryStatement:
+		in.inferBlock(stmt.Block, nil)
+
+		if stmt.CatchParam != nil {
+			in.inferBlock(stmt.Catch, map[string]Type{ stmt.CatchParam.Value: ANY })
+		} else {
+			in.inferBlock(stmt.Catch, nil)
+		}
+		in.inferBlock(stmt.Finally, nil)
+
+	case *ast.ForInStatement:
+		element := in.elementOf(stmt.Iterable)
+		in.inferBlock(stmt.Body, map[string]Type{ stmt.Variable.Value: element })
+	}
+
+	return ANY
+}
+
+func (in *inferer) inferDeclaration(stmt *ast.DeclarationStatement) {
+	value := in.infer(stmt.Value)
+
+	if stmt.Type != nil {
+		declared := fromAnnotation(stmt.Type)
+
+		if !in.unify(declared, value) {
+			in.error(stmt.Name.Token, "cannot assign %s to %s of type %s", value, stmt.Name.Value, declared)
+		}
+		value = declared
+	}
+
+	// Only functions are generalized: the type of other values,
+	// like the elements of an empty array, is told by their uses.
+	if _, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		in.scope.schemes[stmt.Name.Value] = in.generalize(value)
+	} else {
+		in.declare(stmt.Name.Value, value)
+	}
+}
+
+// inferReturn unify a value returned from the current function with its
+// result. Returning values of different types is allowed, unless the
+// result is annotated, but then the function return any.
+func (in *inferer) inferReturn(tok token.Token, t Type) {
+	fn := in.function
+
+	if fn == nil || fn.mixed {
+		return
+	}
+
+	switch {
+
+	case prune(t) == ANY:
+		fn.mixed = !fn.annotated
+
+	case in.unify(fn.result, t):
+		return
+
+	case fn.annotated:
+		in.error(tok, "cannot return %s from %s, which returns %s", t, fn.name, fn.result)
+
+	default:
+		fn.mixed = true
+	}
+}
+
+func (in *inferer) inferFunction(fn *ast.FunctionLiteral) *Function {
+	fnType := &Function{ Params: []Param{} }
+
+	name := fn.Name
+
+	if name == "" {
+		name = "function"
+	}
+
+	outer := in.function
+	in.function = &inferFunction{ name: name, result: in.fresh() }
+
+	if fn.ReturnType != nil {
+		in.function.result = fromAnnotation(fn.ReturnType)
+		in.function.annotated = true
+	}
+	fnType.Return = in.function.result
+
+	in.enter()
+
+	for _, param := range fn.Params {
+		v := in.fresh()
+		v.param = true
+
+		var t Type = v
+
+		if param.Type != nil {
+			t = fromAnnotation(param.Type)
+		}
+
+		if param.Rest {
+			if _, ok := prune(t).(*Array); !ok {
+				t = &Array{ Element: in.fresh() }
+			}
+			fnType.Rest = t
+			fnType.RestName = param.Name.Value
+			in.declare(param.Name.Value, t)
+			continue
+		}
+
+		if param.Default != nil {
+			value := in.infer(param.Default)
+
+			if !in.unify(t, value) {
+				in.error(param.Name.Token, "cannot use %s as the default value of %s of type %s", value, param.Name.Value, t)
+			}
+		}
+		fnType.Params = append(fnType.Params, Param{ Name: param.Name.Value, Type: t, Optional: param.Default != nil })
+		in.declare(param.Name.Value, t)
+	}
+
+	in.declareFunctions(fn.Body.Statements)
+	value := in.inferStatements(fn.Body.Statements)
+
+	if value != nil {
+		in.inferReturn(lastToken(fn.Body), value)
+	}
+
+	if in.function.mixed {
+		fnType.Return = ANY
+	}
+
+	in.leave()
+	in.function = outer
+
+	return fnType
+}
+
+
+func (in *inferer) infer(expr ast.Expression) Type {
+	switch expr := expr.(type) {
+
+	case *ast.IntegerLiteral:
+		return INT
+
+	case *ast.FloatLiteral:
+		return FLOAT
+
+	case *ast.StringLiteral:
+		return STRING
+
+	case *ast.Boolean:
+		return BOOL
+
+	case *ast.Identifier:
+		return in.lookup(expr.Value)
+
+	case *ast.PrefixExpression:
+		return in.inferPrefix(expr)
+
+	case *ast.PostfixExpression:
+		return in.inferUpdate(expr.Token, expr.Operator, in.infer(expr.Left))
+
+	case *ast.InfixExpression:
+		return in.inferInfix(expr)
+
+	case *ast.IfElseExpression:
+		in.infer(expr.Condition)
+		consequence := in.inferBlock(expr.Consequence, nil)
+
+		if expr.Alternative == nil {
+			return ANY
+		}
+		alternative := in.inferBlock(expr.Alternative, nil)
+
+		switch {
+		case consequence == nil && alternative == nil:
+			return ANY
+		case consequence == nil:
+			return alternative
+		case alternative == nil:
+			return consequence
+		}
+		return in.joinBranches(consequence, alternative)
+
+	case *ast.ArrayLiteral:
+		return &Array{ Element: in.inferAll(expr.Elements) }
+
+	case *ast.HashLiteral:
+		return &Map{ Key: in.inferAll(expr.Keys), Value: in.inferAll(expr.Values) }
+
+	case *ast.FunctionLiteral:
+		return in.inferFunction(expr)
+
+	case *ast.FunctionCallExpression:
+		return in.inferCall(expr)
+
+	case *ast.IndexExpression:
+		return in.inferIndex(expr)
+
+	case *ast.MemberExpression:
+		in.infer(expr.Object)
+
+	case *ast.SliceExpression:
+		left := in.infer(expr.Left)
+
+		for _, bound := range []ast.Expression{ expr.Start, expr.Stop, expr.Step } {
+			if bound != nil {
+				in.infer(bound)
+			}
+		}
+
+		if _, ok := prune(left).(*Array); ok || prune(left) == STRING {
+			return left
+		}
+
+	case *ast.RangeExpression:
+		in.unify(in.infer(expr.Start), INT)
+		in.unify(in.infer(expr.End), INT)
+		return RANGE
+
+	case *ast.SpreadExpression:
+		return in.elementOf(expr.Value)
+
+	case *ast.NamedArgument:
+		return in.infer(expr.Value)
+	}
+
+	return ANY
+}
+
+// joinBranches return the type of an if-else expression whose branches
+// give a and b. Branches of different types give any, even when they
+// could be unified: a parameter returned from one of them can be given
+// any value by the calls. Only a variable standing for a result that
+// isn't inferred yet, like the one of a recursive call, is bound to the
+// type of the other branch. An int and a float give a float.
+func (in *inferer) joinBranches(a, b Type) Type {
+	prunedA, prunedB := prune(a), prune(b)
+	varA, isVarA := prunedA.(*Var)
+	varB, isVarB := prunedB.(*Var)
+
+	switch {
+
+	case prunedA == prunedB:
+		return a
+
+	case (isVarA && varA.param) || (isVarB && varB.param):
+		return ANY
+
+	case isVarA || isVarB:
+		if in.unify(a, b) {
+			return a
+		}
+		return ANY
+
+	case len(freeVars(prunedA, nil)) == 0 && len(freeVars(prunedB, nil)) == 0:
+		return join(prunedA, prunedB)
+
+	case in.unify(a, b):
+		return a
+	}
+
+	return ANY
+}
+
+// inferAll return the type shared by the elements of an array literal,
+// or the keys or values of a hash literal: a variable when there are
+// none, any when they have different types.
+func (in *inferer) inferAll(expressions []ast.Expression) Type {
+	var shared Type = in.fresh()
+
+	for _, expr := range expressions {
+		t := in.infer(expr)
+
+		if shared != ANY && !in.unify(shared, t) {
+			shared = ANY
+		}
+	}
+
+	return shared
+}
+
+func (in *inferer) inferPrefix(expr *ast.PrefixExpression) Type {
+	right := in.infer(expr.Right)
+
+	switch expr.Operator {
+
+	case "!":
+		return BOOL
+
+	case "-":
+		switch prune(right) {
+
+		case INT, BOOL:
+			return INT
+
+		case FLOAT:
+			return FLOAT
+		}
+
+		if _, ok := prune(right).(*Var); ok {
+			return right
+		}
+
+	case "~":
+		if !in.unify(right, INT) {
+			in.error(expr.Token, "unsupported operand type for ~: %s", right)
+		}
+		return INT
+
+	case "++", "--":
+		return in.inferUpdate(expr.Token, expr.Operator, right)
+	}
+
+	return ANY
+}
+
+func (in *inferer) inferUpdate(tok token.Token, operator string, t Type) Type {
+	switch prune(t).(type) {
+
+	case *Var:
+		return t
+
+	case Basic:
+		if prune(t) == INT || prune(t) == FLOAT || prune(t) == ANY {
+			return t
+		}
+	}
+	in.error(tok, "unsupported operand type for %s: %s", operator, t)
+
+	return ANY
+}
+
+// inferInfix unify the operands of a binary operator when one of them
+// isn't known yet, as the operators take operands of the same type,
+// except for the conversions between numbers and booleans. The type of
+// the result then follow the rules of infixType.
+func (in *inferer) inferInfix(expr *ast.InfixExpression) Type {
+	left := in.infer(expr.Left)
+	right := in.infer(expr.Right)
+
+	_, leftVar := prune(left).(*Var)
+	_, rightVar := prune(right).(*Var)
+
+	switch {
+
+	case slices.Contains(bitwiseOperators, expr.Operator):
+		if !in.unify(left, INT) || !in.unify(right, INT) {
+			in.error(expr.Token, "unsupported operand types for %s: %s and %s", expr.Operator, left, right)
+		}
+		return INT
+
+	case leftVar && rightVar:
+		in.unify(left, right)
+
+		if slices.Contains(comparisonOperators, expr.Operator) {
+			return BOOL
+		}
+
+		if expr.Operator == "/" {
+			return ANY
+		}
+		return left
+
+	case leftVar && prune(right) != BOOL:
+		in.unify(left, right)
+
+	case rightVar && prune(left) != BOOL:
+		in.unify(right, left)
+	}
+
+	// An operand still unknown is used with a boolean,
+	// which could be converted to its type.
+	_, leftVar = prune(left).(*Var)
+	_, rightVar = prune(right).(*Var)
+
+	if leftVar || rightVar {
+		if slices.Contains(comparisonOperators, expr.Operator) {
+			return BOOL
+		}
+		return ANY
+	}
+	t, ok := infixType(expr.Operator, prune(left), prune(right))
+
+	if !ok {
+		in.error(expr.Token, "unsupported operand types for %s: %s and %s", expr.Operator, left, right)
+	}
+
+	return t
+}
+
+// elementOf return the type of the elements a for-in loop
+// or a spread argument iterate over.
+func (in *inferer) elementOf(iterable ast.Expression) Type {
+	t := in.infer(iterable)
+
+	switch pruned := prune(t).(type) {
+
+	case *Array:
+		return pruned.Element
+
+	case *Var:
+		return ANY
+
+	case Basic:
+		switch pruned {
+
+		case STRING:
+			return STRING
+
+		case RANGE:
+			return INT
+
+		case ANY:
+			return ANY
+		}
+	}
+	in.error(firstToken(iterable), "cannot iterate over %s", t)
+
+	return ANY
+}
+
+func (in *inferer) inferIndex(expr *ast.IndexExpression) Type {
+	left := in.infer(expr.Left)
+	index := in.infer(expr.Index)
+
+	switch pruned := prune(left).(type) {
+
+	case *Array:
+		if !in.unify(index, INT) {
+			in.error(expr.Token, "cannot index %s with %s", left, index)
+		}
+		return pruned.Element
+
+	case *Map:
+		// A missing key give null rather than an error.
+		in.unify(index, pruned.Key)
+		return pruned.Value
+
+	case *Function:
+		in.error(expr.Token, "cannot index %s", left)
+
+	case Basic:
+		switch pruned {
+
+		case STRING:
+			if !in.unify(index, INT) {
+				in.error(expr.Token, "cannot index %s with %s", left, index)
+			}
+			return STRING
+
+		case INT, FLOAT, BOOL, RANGE:
+			in.error(expr.Token, "cannot index %s", left)
+		}
+	}
+
+	return ANY
+}
+
+// inferCall unify the arguments of a call with the parameters of the
+// function called, and return the type of its result. A function of
+// unknown type is bound to the type of a function taking the arguments.
+func (in *inferer) inferCall(call *ast.FunctionCallExpression) Type {
+	callee := in.infer(call.Function)
+	args := make([]Type, len(call.Arguments))
+
+	for i, arg := range call.Arguments {
+		args[i] = in.infer(arg)
+	}
+	name := "function"
+
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		name = ident.Value
+	}
+
+	switch pruned := prune(callee).(type) {
+
+	case *Var:
+		fn := &Function{ Params: []Param{}, Return: in.fresh() }
+
+		for i, arg := range call.Arguments {
+			switch arg.(type) {
+
+			case *ast.SpreadExpression, *ast.NamedArgument:
+				return ANY
+			}
+			fn.Params = append(fn.Params, Param{ Name: "", Type: args[i] })
+		}
+		in.unify(pruned, fn)
+
+		return fn.Return
+
+	case *Function:
+		in.unifyArguments(call, name, pruned, args)
+		return pruned.Return
+
+	case Basic:
+		if pruned == ANY {
+			return ANY
+		}
+	}
+	in.error(call.Token, "cannot call %s", callee)
+
+	return ANY
+}
+
+func (in *inferer) unifyArguments(call *ast.FunctionCallExpression, name string, fn *Function, args []Type) {
+	positional := 0
+	spread := false
+	named := map[string]Type{}
+
+	for i, arg := range call.Arguments {
+		switch arg := arg.(type) {
+
+		case *ast.SpreadExpression:
+			spread = true
+
+		case *ast.NamedArgument:
+			named[arg.Name.Value] = args[i]
+
+		default:
+			if spread {
+				continue
+			}
+			positional++
+
+			var expected Type
+
+			if i < len(fn.Params) {
+				expected = fn.Params[i].Type
+			} else if rest, ok := prune(fn.Rest).(*Array); ok {
+				expected = rest.Element
+			}
+
+			if expected != nil && !in.unify(expected, args[i]) {
+				in.error(firstToken(arg), "cannot use %s as %s in argument %d to %s", args[i], expected, i + 1, name)
+			}
+		}
+	}
+
+	if !spread && fn.Rest == nil && positional > len(fn.Params) {
+		in.error(call.Token, "too many arguments in call to %s: want at most %d, got %d", name, len(fn.Params), positional)
+	}
+
+	for i, param := range fn.Params {
+		if param.Name == "" {
+			continue
+		}
+		t, isNamed := named[param.Name]
+		delete(named, param.Name)
+
+		switch {
+
+		case isNamed && !in.unify(param.Type, t):
+			in.error(call.Token, "cannot use %s as %s in argument %s to %s", t, param.Type, param.Name, name)
+
+		case !isNamed && i >= positional && !spread && !param.Optional:
+			in.error(call.Token, "missing argument for parameter %s of %s", param.Name, name)
+		}
+	}
+
+	if len(fn.Params) != 0 && fn.Params[0].Name != "" {
+		for _, unknown := range sortedKeys(named) {
+			in.error(call.Token, "unknown parameter name in call to %s: %s", name, unknown)
+		}
+	}
+}
+
+
+// namer give the variables of the types it format the names 'a, 'b, ...
+// in the order it meet them.
+type namer struct {
+	names		map[*Var]string
+}
+
+func newNamer() *namer {
+	return &namer{ names: map[*Var]string{} }
+}
+
+func (n *namer) format(t Type) string {
+	switch t := prune(t).(type) {
+
+	case *Var:
+		name, ok := n.names[t]
+
+		if !ok {
+			name = varName(len(n.names))
+			n.names[t] = name
+		}
+		return name
+
+	case *Array:
+		return "array<" + n.format(t.Element) + ">"
+
+	case *Map:
+		return "map<" + n.format(t.Key) + ", " + n.format(t.Value) + ">"
+
+	case *Function:
+		params := []string{}
+
+		for _, param := range t.Params {
+			params = append(params, n.format(param.Type))
+		}
+
+		if t.Rest != nil {
+			params = append(params, "..." + n.format(t.Rest))
+		}
+		return "fn(" + strings.Join(params, ", ") + "): " + n.format(t.Return)
+
+	case nil:
+		return "any"
+	}
+
+	return t.String()
+}
+
+// varName return 'a to 'z, then 'a1, 'b1, ...
+func varName(i int) string {
+	name := "'" + string(rune('a' + i % 26))
+
+	if i >= 26 {
+		name += strconv.Itoa(i / 26)
+	}
+
+	return name
+}
@@ -0,0 +1,110 @@
+package checker
+
+import (
+	"slices"
+	"testing"
+)
+
+
+func TestInfer(t *testing.T) {
+
+	t.Run("it should infer the signatures of the top-level bindings", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	[]string
+		}{
+			{ "let x = 5; const s = \"a\"; let r = 0..3", []string{ "let x: int", "const s: string", "let r: range" } },
+			{ "fn add(a, b) { a + b } let total = add(1, 2)", []string{ "fn add(a: 'a, b: 'a): 'a", "let total: int" } },
+			{ "fn half(n) { n / 2 }", []string{ "fn half(n: int): any" } },
+			{ "let id = fn(x) { x }; let n = id(1); let s = id(\"a\")", []string{ "let id: fn('a): 'a", "let n: int", "let s: string" } },
+			{ "fn fib(n) { if (n < 2) { return n } fib(n - 1) + fib(n - 2) }", []string{ "fn fib(n: int): int" } },
+			{ "fn greet(name) { \"Hello, \" + name }", []string{ "fn greet(name: string): string" } },
+			{ "fn compose(f, g) { fn(x) { g(f(x)) } }", []string{ "fn compose(f: fn('a): 'b, g: fn('b): 'c): fn('a): 'c" } },
+			{ "fn first(xs) { xs[0] } let n = first([1, 2]) + 1", []string{ "fn first(xs: 'a): any", "let n: any" } },
+			{ "let xs = []; xs[0] + 1", []string{ "let xs: array<int>" } },
+			{ "let h = { \"a\": 1 }; let mixed = [1, \"a\"]", []string{ "let h: map<string, int>", "let mixed: array<any>" } },
+			{ "fn even(n) { if (n == 0) { true } else { odd(n - 1) } } fn odd(n) { if (n == 0) { false } else { even(n - 1) } }", []string{ "fn even(n: int): bool", "fn odd(n: int): bool" } },
+			{ "fn pick(c) { if (c) { 1 } else { \"one\" } } fn log(x) { }", []string{ "fn pick(c: 'a): any", "fn log(x: 'a): any" } },
+			{ "fn scale(x: float, by = 2, ...rest) { x * by }", []string{ "fn scale(x: float, by: int, ...rest: array<'a>): float" } },
+			{ "fn sum(...xs) { xs }", []string{ "fn sum(...xs: array<'a>): array<'a>" } },
+			{ "fn pick(c, a, b) { if c { a } else { b } } let p = pick(true, 1, \"x\")", []string{ "fn pick(c: 'a, a: 'b, b: 'c): any", "let p: any" } },
+			{ "fn add(a, b) { a + b } let total = add(1, 2.5); let n = add(1, 2)", []string{ "fn add(a: 'a, b: 'a): 'a", "let total: float", "let n: int" } },
+			{ "let xs = [1, 2.5]; fn sign(n) { if (n > 0) { 1 } else { 0.5 } }", []string{ "let xs: array<float>", "fn sign(n: int): float" } },
+			{ "let x = 1; let x = \"a\"; export fn f() { x }", []string{ "let x: string", "fn f(): string" } },
+		}
+
+		for i, tt := range tests {
+			signatures, diagnostics := Infer(parse(t, tt.input))
+
+			if len(diagnostics) != 0 {
+				t.Fatalf("[test #%d] %s\nExpecting no diagnostics, but got %v\n", i, tt.input, diagnostics)
+			}
+			got := []string{}
+
+			for _, signature := range signatures {
+				got = append(got, signature.String())
+			}
+
+			if !slices.Equal(got, tt.expected) {
+				t.Fatalf("[test #%d] %s\nExpecting %q, but got %q\n", i, tt.input, tt.expected, got)
+			}
+		}
+	})
+
+	t.Run("it should accept what could succeed at runtime", func(t *testing.T) {
+		tests := []string{
+			"fn add(a, b) { a + b } add(1, 2.5); add(true, 1); add(\"a\", \"b\")",
+			"fn f(x) { if (x) { 1 } else { \"a\" } } f(1) + 1; f(true) + \"b\"",
+			"fn f(x) { x(x) }",
+			"let f = fn(x, y = 1) { x + y }; f(1); f(1, 2); f(x: 1)",
+			"fn sum(...xs) { xs } sum(1, 2, 3); sum(...[1, 2])",
+			"fn len(xs) { let n = 0; for (x in xs) { n++ } n } len([1]); len(\"abc\"); len(0..3)",
+			"try { throw \"x\" } catch (e) { e.message + 1 }",
+			"import \"m.mk\" as m; m.f(1) + 1",
+			"let h = { \"a\": 1 }; h[2]",
+			"fn f(n) { n + true } f(1); f(false)",
+			"fn half(n: int): int { n / 2 } half(4) + 1",
+			"fn pick(c, a, b) { if c { a } else { b } } pick(true, 1, \"x\"); pick(false, [1], 2)",
+		}
+
+		for i, input := range tests {
+			if _, diagnostics := Infer(parse(t, input)); len(diagnostics) != 0 {
+				t.Fatalf("[test #%d] %s\nExpecting no diagnostics, but got %v\n", i, input, diagnostics)
+			}
+		}
+	})
+
+	t.Run("it should report the errors sure to happen", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	[]string
+		}{
+			{ "true + fn() { }", []string{ "<input>:1:6: error: unsupported operand types for +: bool and fn(): any" } },
+			{ "fn f(n) { n - 1 } f(\"a\")", []string{ "<input>:1:21: error: cannot use string as int in argument 1 to f" } },
+			{ "fn f(s) { s + \"!\" } f(1)", []string{ "<input>:1:23: error: cannot use int as string in argument 1 to f" } },
+			{ "fn f(a, b) { a + b } f(\"a\", [1])", []string{ "<input>:1:29: error: cannot use array<int> as string in argument 2 to f" } },
+			{ "fn f(n) { n < 2 } f([])", []string{ "<input>:1:21: error: cannot use array<'a> as int in argument 1 to f" } },
+			{ "fn apply(f, x) { f(x) } apply(fn(a, b) { a }, 1)", []string{ "<input>:1:31: error: cannot use fn('a, 'b): 'a as fn('c): 'd in argument 1 to apply" } },
+			{ "let id = fn(x) { x }; id(1) + id(\"a\")", []string{ "<input>:1:29: error: unsupported operand types for +: int and string" } },
+			{ "fn f(a, b) { a } f(1); f(1, 2, 3)", []string{ "<input>:1:19: error: missing argument for parameter b of f", "<input>:1:25: error: too many arguments in call to f: want at most 2, got 3" } },
+			{ "let s = \"a\"; s - 1", []string{ "<input>:1:16: error: unsupported operand types for -: string and int" } },
+			{ "fn f(x) { x & 1 } f(\"a\")", []string{ "<input>:1:21: error: cannot use string as int in argument 1 to f" } },
+			{ "let n = 5; n(1); n[0]; for (x in n) { }", []string{ "<input>:1:13: error: cannot call int", "<input>:1:19: error: cannot index int", "<input>:1:34: error: cannot iterate over int" } },
+			{ "let x: int = \"a\"", []string{ "<input>:1:5: error: cannot assign string to x of type int" } },
+			{ "fn f(): string { return 1 }", []string{ "<input>:1:18: error: cannot return int from f, which returns string" } },
+		}
+
+		for i, tt := range tests {
+			_, diagnostics := Infer(parse(t, tt.input))
+			got := []string{}
+
+			for _, diagnostic := range diagnostics {
+				got = append(got, diagnostic.String())
+			}
+
+			if !slices.Equal(got, tt.expected) {
+				t.Fatalf("[test #%d] %s\nExpecting %q, but got %q\n", i, tt.input, tt.expected, got)
+			}
+		}
+	})
+}
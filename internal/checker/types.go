@@ -0,0 +1,185 @@
+package checker
+
+import (
+	"monkey/internal/ast"
+	"strings"
+)
+
+
+// Type is the static type of a value.
+type Type interface {
+	String() string
+}
+
+// Basic is a type without parameters.
+type Basic string
+
+const (
+	INT		Basic = "int"
+	FLOAT	Basic = "float"
+	BOOL	Basic = "bool"
+	STRING	Basic = "string"
+	RANGE	Basic = "range"
+	ANY		Basic = "any" // unknown, compatible with every type
+)
+
+func (b Basic) String() string { return string(b) }
+
+// Array is the type of arrays whose elements are of type Element.
+type Array struct {
+	Element		Type
+}
+
+func (a *Array) String() string { return "array<" + a.Element.String() + ">" }
+
+// Map is the type of hashes from Key to Value.
+type Map struct {
+	Key		Type
+	Value	Type
+}
+
+func (m *Map) String() string { return "map<" + m.Key.String() + ", " + m.Value.String() + ">" }
+
+// Function is the type of functions. The parameters of a function
+// literal are named, the ones of an annotation are not. Rest is the
+// type of the array collecting the remaining arguments, nil when
+// there is no rest parameter, and RestName the name of that parameter.
+type Function struct {
+	Params		[]Param
+	Rest		Type
+	RestName	string
+	Return		Type
+}
+
+// Param is a parameter of a function type, Optional when it has a default value.
+type Param struct {
+	Name		string
+	Type		Type
+	Optional	bool
+}
+
+func (f *Function) String() string {
+	params := []string{}
+
+	for _, param := range f.Params {
+		params = append(params, param.Type.String())
+	}
+
+	if f.Rest != nil {
+		params = append(params, "..." + f.Rest.String())
+	}
+
+	return "fn(" + strings.Join(params, ", ") + "): " + f.Return.String()
+}
+
+
+// fromAnnotation return the type an annotation stand for.
+func fromAnnotation(annotation ast.TypeExpression) Type {
+	switch annotation := annotation.(type) {
+
+	case *ast.NamedType:
+		return Basic(annotation.Name)
+
+	case *ast.ArrayType:
+		return &Array{ Element: fromAnnotation(annotation.Element) }
+
+	case *ast.MapType:
+		return &Map{ Key: fromAnnotation(annotation.Key), Value: fromAnnotation(annotation.Value) }
+
+	case *ast.FunctionType:
+		fn := &Function{ Return: ANY }
+
+		for i, param := range annotation.Params {
+			if annotation.Rest && i == len(annotation.Params) - 1 {
+				fn.Rest = fromAnnotation(param)
+			} else {
+				fn.Params = append(fn.Params, Param{ Type: fromAnnotation(param) })
+			}
+		}
+
+		if annotation.Return != nil {
+			fn.Return = fromAnnotation(annotation.Return)
+		}
+		return fn
+	}
+
+	return ANY
+}
+
+// assignable report whether a value of type from can be used where
+// a value of type to is expected. Any is assignable to and from every
+// type, and an int to a float, as arithmetic give an int whenever the
+// result is integral. Arrays and maps are covariant, and functions
+// are assignable when their parameters and results are.
+func assignable(from, to Type) bool {
+	if from == ANY || to == ANY || from.String() == to.String() {
+		return true
+	}
+
+	switch to := to.(type) {
+
+	case Basic:
+		return to == FLOAT && from == INT
+
+	case *Array:
+		if from, ok := from.(*Array); ok {
+			return assignable(from.Element, to.Element)
+		}
+
+	case *Map:
+		if from, ok := from.(*Map); ok {
+			return assignable(from.Key, to.Key) && assignable(from.Value, to.Value)
+		}
+
+	case *Function:
+		from, ok := from.(*Function)
+
+		if !ok || len(from.Params) != len(to.Params) || (from.Rest == nil) != (to.Rest == nil) {
+			return false
+		}
+
+		for i, param := range to.Params {
+			if !assignable(param.Type, from.Params[i].Type) {
+				return false
+			}
+		}
+
+		if to.Rest != nil && !assignable(to.Rest, from.Rest) {
+			return false
+		}
+		return assignable(from.Return, to.Return)
+	}
+
+	return false
+}
+
+// join return the type of a value that is either of type a or b,
+// like the value of an if-else expression.
+func join(a, b Type) Type {
+	if a.String() == b.String() {
+		return a
+	}
+
+	if assignable(a, b) && a != ANY && b != ANY {
+		return b
+	}
+
+	if assignable(b, a) && a != ANY && b != ANY {
+		return a
+	}
+
+	switch a := a.(type) {
+
+	case *Array:
+		if b, ok := b.(*Array); ok {
+			return &Array{ Element: join(a.Element, b.Element) }
+		}
+
+	case *Map:
+		if b, ok := b.(*Map); ok {
+			return &Map{ Key: join(a.Key, b.Key), Value: join(a.Value, b.Value) }
+		}
+	}
+
+	return ANY
+}
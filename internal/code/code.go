@@ -0,0 +1,279 @@
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+
+// Instructions is a sequence of encoded instructions: an opcode
+// followed by its operands, each stored in big endian.
+type Instructions []byte
+
+type Opcode byte
+
+
+const (
+	OpConstant Opcode = iota // push a constant of the pool
+	OpNil // push the lack of value of statements like `let`
+	OpNull
+	OpTrue
+	OpFalse
+	OpPop
+
+	// Operators, applied to the values on top of the stack
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpPow
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpGreater
+	OpLessOrEqual
+	OpGreaterOrEqual
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+	OpMinus
+	OpBang
+	OpBitNot
+	OpUpdate // replace a value by the result of `++`/`--` and the updated value
+
+	// Names, given as a constant index followed by the binding the
+	// resolver gave them: its kind, depth and slot
+	OpGetName
+	OpDefine // `let`
+	OpDefineConst // `const`
+	OpBind // bind without checking for constants, like hoisted functions
+	OpCheckMutable // fail if the name is a constant
+	OpAssign // update an existing binding
+	OpPushScope // enter a block, given the index of its scope in the function
+	OpPopScope
+
+	// Values
+	OpArray
+	OpHash
+	OpIndex
+	OpIndexForUpdate // replace an array and index by the array, resolved index and element
+	OpSetIndex
+	OpSlice
+	OpRange
+	OpMember
+
+	// Control flow, jumps are given as absolute offsets
+	OpJump
+	OpJumpNotTruthy
+	OpClosure
+	OpNamedArgument
+	OpSpread
+	OpCall
+	OpTailCall
+	OpReturn
+	OpIter
+	OpIterNext // push the next item, or pop the iterator and jump when done
+	OpThrow
+	OpRaise // raise the error whose message is a constant
+	OpTry
+	OpEndBlock // end a try, catch or finally block with the value on top
+	OpImport // push the module at the path given as a constant index
+)
+
+
+// Definition describe an opcode: its name and the width,
+// in bytes, of each of its operands.
+type Definition struct {
+	Name			string
+	OperandWidths	[]int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: { "OpConstant", []int{ 2 } },
+	OpNil: { "OpNil", []int{} },
+	OpNull: { "OpNull", []int{} },
+	OpTrue: { "OpTrue", []int{} },
+	OpFalse: { "OpFalse", []int{} },
+	OpPop: { "OpPop", []int{} },
+
+	OpAdd: { "OpAdd", []int{} },
+	OpSub: { "OpSub", []int{} },
+	OpMul: { "OpMul", []int{} },
+	OpDiv: { "OpDiv", []int{} },
+	OpMod: { "OpMod", []int{} },
+	OpPow: { "OpPow", []int{} },
+	OpEqual: { "OpEqual", []int{} },
+	OpNotEqual: { "OpNotEqual", []int{} },
+	OpLess: { "OpLess", []int{} },
+	OpGreater: { "OpGreater", []int{} },
+	OpLessOrEqual: { "OpLessOrEqual", []int{} },
+	OpGreaterOrEqual: { "OpGreaterOrEqual", []int{} },
+	OpBitAnd: { "OpBitAnd", []int{} },
+	OpBitOr: { "OpBitOr", []int{} },
+	OpBitXor: { "OpBitXor", []int{} },
+	OpShiftLeft: { "OpShiftLeft", []int{} },
+	OpShiftRight: { "OpShiftRight", []int{} },
+	OpMinus: { "OpMinus", []int{} },
+	OpBang: { "OpBang", []int{} },
+	OpBitNot: { "OpBitNot", []int{} },
+	OpUpdate: { "OpUpdate", []int{ 1, 1 } }, // decrement, prefix
+
+	OpGetName: { "OpGetName", []int{ 2, 1, 2, 2 } }, // name, binding kind, depth and slot
+	OpDefine: { "OpDefine", []int{ 2, 1, 2, 2 } },
+	OpDefineConst: { "OpDefineConst", []int{ 2, 1, 2, 2 } },
+	OpBind: { "OpBind", []int{ 2, 1, 2, 2 } },
+	OpCheckMutable: { "OpCheckMutable", []int{ 2, 1, 2, 2 } },
+	OpAssign: { "OpAssign", []int{ 2, 1, 2, 2 } },
+	OpPushScope: { "OpPushScope", []int{ 2 } },
+	OpPopScope: { "OpPopScope", []int{} },
+
+	OpArray: { "OpArray", []int{ 2 } },
+	OpHash: { "OpHash", []int{ 2 } }, // number of pairs
+	OpIndex: { "OpIndex", []int{} },
+	OpIndexForUpdate: { "OpIndexForUpdate", []int{} },
+	OpSetIndex: { "OpSetIndex", []int{} },
+	OpSlice: { "OpSlice", []int{ 1 } }, // bit set of the given bounds: 1 start, 2 stop, 4 step
+	OpRange: { "OpRange", []int{ 1 } }, // inclusive
+	OpMember: { "OpMember", []int{ 2 } },
+
+	OpJump: { "OpJump", []int{ 2 } },
+	OpJumpNotTruthy: { "OpJumpNotTruthy", []int{ 2 } },
+	OpClosure: { "OpClosure", []int{ 2 } },
+	OpNamedArgument: { "OpNamedArgument", []int{ 2 } },
+	OpSpread: { "OpSpread", []int{} },
+	OpCall: { "OpCall", []int{ 1 } }, // number of arguments
+	OpTailCall: { "OpTailCall", []int{ 1 } },
+	OpReturn: { "OpReturn", []int{} },
+	OpIter: { "OpIter", []int{} },
+	OpIterNext: { "OpIterNext", []int{ 2 } },
+	OpThrow: { "OpThrow", []int{} },
+	OpRaise: { "OpRaise", []int{ 2 } },
+	OpTry: { "OpTry", []int{ 2, 2, 2 } }, // catch, finally and end offsets, 0 when there is no block
+	OpEndBlock: { "OpEndBlock", []int{} },
+	OpImport: { "OpImport", []int{ 2 } },
+}
+
+func (op Opcode) String() string {
+	if def, ok := definitions[op]; ok {
+		return def.Name
+	}
+
+	return fmt.Sprintf("Opcode(%d)", byte(op))
+}
+
+// Lookup return the definition of op.
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+// Make encode an instruction. Missing operands are encoded as 0.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+
+	for _, width := range def.OperandWidths {
+		length += width
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+	offset := 1
+
+	for i, width := range def.OperandWidths {
+		operand := 0
+
+		if i < len(operands) {
+			operand = operands[i]
+		}
+
+		switch width {
+
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(operand))
+
+		case 1:
+			instruction[offset] = byte(operand)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+// ReadOperands decode the operands of an instruction whose opcode is
+// def, returning them along with the number of bytes they take.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+
+		switch width {
+
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
+
+// String list the instructions, one per line, prefixed by their offset.
+func (ins Instructions) String() string {
+	var output bytes.Buffer
+
+	for i := 0; i < len(ins); {
+		def, err := Lookup(ins[i])
+
+		if err != nil {
+			fmt.Fprintf(&output, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&output, "%04d %s\n", i, formatInstruction(def, operands))
+
+		i += 1 + read
+	}
+
+	return output.String()
+}
+
+func formatInstruction(def *Definition, operands []int) string {
+	var output bytes.Buffer
+
+	output.WriteString(def.Name)
+
+	for _, operand := range operands {
+		fmt.Fprintf(&output, " %d", operand)
+	}
+
+	return output.String()
+}
@@ -0,0 +1,81 @@
+package code
+
+import "testing"
+
+
+func TestMake(t *testing.T) {
+
+	t.Run("it should encode the opcode followed by its operands", func(t *testing.T) {
+		tests := []struct{
+			op			Opcode
+			operands	[]int
+			expected	[]byte
+		}{
+			{ OpConstant, []int{ 65534 }, []byte{ byte(OpConstant), 255, 254 } },
+			{ OpAdd, []int{}, []byte{ byte(OpAdd) } },
+			{ OpCall, []int{ 3 }, []byte{ byte(OpCall), 3 } },
+			{ OpUpdate, []int{ 1, 0 }, []byte{ byte(OpUpdate), 1, 0 } },
+			{ OpTry, []int{ 1, 2, 258 }, []byte{ byte(OpTry), 0, 1, 0, 2, 1, 2 } },
+		}
+
+		for i, tt := range tests {
+			instruction := Make(tt.op, tt.operands...)
+
+			if string(instruction) != string(tt.expected) {
+				t.Fatalf("[test #%d]: Expecting %v, but got %v\n", i, tt.expected, instruction)
+			}
+		}
+	})
+}
+
+func TestReadOperands(t *testing.T) {
+
+	t.Run("it should decode the operands Make encoded", func(t *testing.T) {
+		tests := []struct{
+			op			Opcode
+			operands	[]int
+			bytesRead	int
+		}{
+			{ OpConstant, []int{ 65535 }, 2 },
+			{ OpSlice, []int{ 5 }, 1 },
+			{ OpGetName, []int{ 3, 1, 2, 4 }, 7 },
+			{ OpTry, []int{ 10, 0, 30 }, 6 },
+		}
+
+		for i, tt := range tests {
+			instruction := Make(tt.op, tt.operands...)
+			def, err := Lookup(byte(tt.op))
+
+			if err != nil {
+				t.Fatalf("[test #%d]: Expecting a definition, but got %s\n", i, err)
+			}
+			operands, read := ReadOperands(def, instruction[1:])
+
+			if read != tt.bytesRead {
+				t.Fatalf("[test #%d]: Expecting %d bytes read, but got %d\n", i, tt.bytesRead, read)
+			}
+
+			for j, expected := range tt.operands {
+				if operands[j] != expected {
+					t.Fatalf("[test #%d]: Expecting operand %d to be %d, but got %d\n", i, j, expected, operands[j])
+				}
+			}
+		}
+	})
+}
+
+func TestInstructionsString(t *testing.T) {
+
+	t.Run("it should list the instructions with their offset", func(t *testing.T) {
+		instructions := Instructions{}
+
+		for _, ins := range [][]byte{ Make(OpConstant, 1), Make(OpGetName, 2, 1, 0, 3), Make(OpAdd), Make(OpJump, 0) } {
+			instructions = append(instructions, ins...)
+		}
+		expected := "0000 OpConstant 1\n0003 OpGetName 2 1 0 3\n0011 OpAdd\n0012 OpJump 0\n"
+
+		if instructions.String() != expected {
+			t.Fatalf("Expecting %q, but got %q\n", expected, instructions.String())
+		}
+	})
+}
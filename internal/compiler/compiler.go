@@ -0,0 +1,686 @@
+package compiler
+
+import (
+	"fmt"
+	"math"
+	"monkey/internal/ast"
+	"monkey/internal/code"
+	"monkey/internal/object"
+	"monkey/internal/token"
+)
+
+
+// Bytecode is a compiled program: its main function, the constant pool
+// shared by all the functions it contain, and the names it export when
+// it's imported as a module.
+type Bytecode struct {
+	Main		*object.CompiledFunction
+	Constants	[]object.Object
+	Exports		[]string
+}
+
+// MAX_OPERAND is the largest constant index, jump offset or count that
+// fit in the two bytes of an operand.
+const MAX_OPERAND = math.MaxUint16
+
+// MAX_ARGUMENTS is the largest number of arguments a call can pass,
+// spread arrays counting for one.
+const MAX_ARGUMENTS = math.MaxUint8
+
+
+// Compiler lower a program to bytecode. The bytecode keep the semantics
+// of the evaluator: blocks, calls and loops get their own environment at
+// the same places, where names are found with the binding the resolver
+// gave them, in a slot for the local ones.
+type Compiler struct {
+	constants		[]object.Object
+	constantIndexes	map[constantKey]int
+	functions		[]*object.CompiledFunction // sharing the constant pool
+	scope			*compilationScope
+	err				error
+}
+
+// compilationScope hold the function being compiled.
+type compilationScope struct {
+	fn				*object.CompiledFunction
+	instructions	code.Instructions
+	positions		[]object.Position
+	tailReturns		bool // whether a call returned with `return` is a tail call
+	outer			*compilationScope
+}
+
+// constantKey identify a literal in the constant pool,
+// so each literal value is stored once.
+type constantKey struct {
+	Type	object.ObjectType
+	Value	any
+}
+
+
+// Compile lower program to bytecode. The program should be resolved
+// first, so its local names are compiled to slots: without bindings,
+// names are looked up in the environments, which is slower.
+func Compile(program *ast.Program) (*Bytecode, error) {
+	c := &Compiler{ constantIndexes: map[constantKey]int{} }
+	main := c.enterScope(&object.CompiledFunction{ Scope: scopeOf(nil) }, false)
+
+	c.compileStatements(program.Statements, false)
+	c.emit(code.OpReturn)
+	c.leaveScope()
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	for _, fn := range c.functions {
+		fn.Constants = c.constants
+	}
+
+	bytecode := &Bytecode{ Main: main, Constants: c.constants }
+
+	for _, stmt := range program.Statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			bytecode.Exports = append(bytecode.Exports, export.Name())
+		}
+	}
+
+	return bytecode, nil
+}
+
+
+func (c *Compiler) enterScope(fn *object.CompiledFunction, inFunction bool) *object.CompiledFunction {
+	c.scope = &compilationScope{ fn: fn, tailReturns: inFunction, outer: c.scope }
+	c.functions = append(c.functions, fn)
+
+	return fn
+}
+
+func (c *Compiler) leaveScope() {
+	if len(c.scope.instructions) > MAX_OPERAND {
+		c.fail("function %q too large: %d bytes of bytecode", c.scope.fn.Name, len(c.scope.instructions))
+	}
+	c.scope.fn.Instructions = c.scope.instructions
+	c.scope.fn.Positions = c.scope.positions
+	c.scope = c.scope.outer
+}
+
+// fail record the first error met while compiling.
+func (c *Compiler) fail(format string, args ...any) {
+	if c.err == nil {
+		c.err = fmt.Errorf(format, args...)
+	}
+}
+
+// emit append an instruction to the current function,
+// returning its offset.
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	for _, operand := range operands {
+		if operand > MAX_OPERAND {
+			c.fail("operand of %s too large: %d", op, operand)
+		}
+	}
+	offset := len(c.scope.instructions)
+	c.scope.instructions = append(c.scope.instructions, code.Make(op, operands...)...)
+
+	return offset
+}
+
+// emitAt emit an instruction that can fail, recording the position
+// of tok so its errors are located like the evaluator locate them.
+func (c *Compiler) emitAt(tok token.Token, op code.Opcode, operands ...int) int {
+	offset := c.emit(op, operands...)
+	c.position(offset, tok)
+
+	return offset
+}
+
+// mark record the position of stmt at the next instruction, the first of
+// the statement, so each instruction is listed with the line of the last
+// position recorded before it.
+func (c *Compiler) mark(stmt ast.Statement) {
+	if tok := statementToken(stmt); tok.Line != 0 {
+		c.position(len(c.scope.instructions), tok)
+	}
+}
+
+// position record the position of tok at offset, replacing the one of
+// a statement starting there, as the one of an instruction is precise.
+func (c *Compiler) position(offset int, tok token.Token) {
+	position := object.Position{ Offset: offset, Line: tok.Line, Column: tok.Column }
+	positions := c.scope.positions
+
+	c.scope.fn.File = tok.File
+
+	if len(positions) != 0 && positions[len(positions) - 1].Offset == offset {
+		positions[len(positions) - 1] = position
+		return
+	}
+	c.scope.positions = append(positions, position)
+}
+
+// patch replace the operands of the instruction at offset,
+// like the target of a jump once it's known.
+func (c *Compiler) patch(offset int, operands ...int) {
+	op := code.Opcode(c.scope.instructions[offset])
+
+	for _, operand := range operands {
+		if operand > MAX_OPERAND {
+			c.fail("operand of %s too large: %d", op, operand)
+		}
+	}
+	copy(c.scope.instructions[offset:], code.Make(op, operands...))
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+
+	return len(c.constants) - 1
+}
+
+// constant return the index of a literal value in the pool,
+// adding it the first time.
+func (c *Compiler) constant(obj object.Object) int {
+	var key constantKey
+
+	switch obj := obj.(type) {
+
+	case *object.Integer:
+		key = constantKey{ obj.Type(), obj.Value }
+
+	case *object.Float:
+		key = constantKey{ obj.Type(), math.Float64bits(obj.Value) }
+
+	case *object.String:
+		key = constantKey{ obj.Type(), obj.Value }
+
+	default:
+		return c.addConstant(obj)
+	}
+
+	if index, ok := c.constantIndexes[key]; ok {
+		return index
+	}
+	index := c.addConstant(obj)
+	c.constantIndexes[key] = index
+
+	return index
+}
+
+func (c *Compiler) name(name string) int {
+	return c.constant(&object.String{ Value: name })
+}
+
+// binding return the operands of an instruction on the name of ident:
+// the name, then the kind, depth and slot of the binding the resolver
+// gave it, so the VM find its value like the evaluator do.
+func (c *Compiler) binding(ident *ast.Identifier) []int {
+	return []int{ c.name(ident.Value), int(ident.Binding.Kind), ident.Binding.Depth, ident.Binding.Slot }
+}
+
+// pushScope emit OpPushScope for a block of the current function,
+// whose environment must have the slots of scope.
+func (c *Compiler) pushScope(scope *ast.Scope) {
+	fn := c.scope.fn
+	fn.Blocks = append(fn.Blocks, scopeOf(scope))
+
+	c.emit(code.OpPushScope, len(fn.Blocks) - 1)
+}
+
+// scopeOf return the scope the resolver gave a block, or an empty one when
+// the program isn't resolved, all its names being looked up by name.
+func scopeOf(scope *ast.Scope) *ast.Scope {
+	if scope == nil {
+		return &ast.Scope{ Names: []string{} }
+	}
+
+	return scope
+}
+
+
+// compileStatements compile a list of statements leaving the value
+// of the last one on the stack, or nil when there is none. Declared
+// functions are bound first, like the evaluator hoist them.
+func (c *Compiler) compileStatements(statements []ast.Statement, tail bool) {
+	for _, stmt := range statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+
+		if declaration, ok := stmt.(*ast.FunctionDeclaration); ok {
+			c.mark(declaration)
+			c.emit(code.OpClosure, c.compileFunction(declaration.Function))
+			c.emit(code.OpBind, c.binding(declaration.Name)...)
+		}
+	}
+
+	if len(statements) == 0 {
+		c.emit(code.OpNil)
+		return
+	}
+
+	for i, stmt := range statements {
+		last := i == len(statements) - 1
+
+		c.mark(stmt)
+		c.compileStatement(stmt, tail && last)
+
+		if !last {
+			c.emit(code.OpPop)
+		}
+	}
+}
+
+// compileStatement compile stmt, leaving its value on the stack. When
+// tail is set, stmt is in tail position in a function body.
+func (c *Compiler) compileStatement(stmt ast.Statement, tail bool) {
+
+	switch node := stmt.(type) {
+
+	case *ast.ExpressionStatement:
+		c.compileExpression(node.Expression, tail)
+
+	case *ast.DeclarationStatement:
+		c.compileExpression(node.Value, false)
+
+		if node.Token.Type == token.CONST {
+			c.emitAt(node.Name.Token, code.OpDefineConst, c.binding(node.Name)...)
+		} else {
+			c.emitAt(node.Name.Token, code.OpDefine, c.binding(node.Name)...)
+		}
+		c.emit(code.OpNil)
+
+	case *ast.ReturnStatement:
+		c.compileExpression(node.ReturnValue, c.scope.tailReturns)
+		c.emit(code.OpReturn)
+
+	case *ast.FunctionDeclaration:
+		// Already bound when its block was hoisted.
+		c.emit(code.OpNil)
+
+	case *ast.ExportStatement:
+		c.compileStatement(node.Statement, tail)
+
+	case *ast.ImportStatement:
+		c.emitAt(node.Token, code.OpImport, c.name(node.Path.Value))
+		c.emit(code.OpBind, c.binding(node.Alias)...)
+		c.emit(code.OpNil)
+
+	case *ast.ThrowStatement:
+		c.compileExpression(node.Value, false)
+		c.emitAt(node.Token, code.OpThrow)
+
+	case *ast.TryStatement:
+		c.compileTryStatement(node)
+
+	case *ast.ForInStatement:
+		c.compileForInStatement(node)
+
+	case *ast.BlockStatement:
+		c.compileStatements(node.Statements, false)
+
+	default:
+		c.emit(code.OpNil)
+	}
+}
+
+// compileTryStatement compile the try, catch and finally blocks one after
+// the other, each in its own scope and ended by OpEndBlock, the VM running
+// them like the evaluator do. A call returned from the try block, or from the catch
+// block when a finally block follow, isn't a tail call: it must be made
+// before leaving the statement.
+func (c *Compiler) compileTryStatement(node *ast.TryStatement) {
+	tailReturns := c.scope.tailReturns
+	defer func() { c.scope.tailReturns = tailReturns }()
+
+	try := c.emitAt(node.Token, code.OpTry, 0, 0, 0)
+
+	c.scope.tailReturns = false
+	c.pushScope(node.Block.Scope)
+	c.compileStatements(node.Block.Statements, false)
+	c.emit(code.OpEndBlock)
+
+	catch, finally := 0, 0
+
+	if node.Catch != nil {
+		catch = len(c.scope.instructions)
+		c.pushScope(node.Catch.Scope)
+
+		// The VM push the caught error before running the block.
+		if node.CatchParam != nil {
+			c.emit(code.OpBind, c.binding(node.CatchParam)...)
+		} else {
+			c.emit(code.OpPop)
+		}
+		c.scope.tailReturns = tailReturns && node.Finally == nil
+		c.compileStatements(node.Catch.Statements, false)
+		c.emit(code.OpEndBlock)
+	}
+
+	if node.Finally != nil {
+		finally = len(c.scope.instructions)
+
+		c.scope.tailReturns = tailReturns
+		c.pushScope(node.Finally.Scope)
+		c.compileStatements(node.Finally.Statements, false)
+		c.emit(code.OpEndBlock)
+	}
+
+	c.patch(try, catch, finally, len(c.scope.instructions))
+}
+
+// compileForInStatement compile the loop as an iterator, kept on the
+// stack while the loop run, and a body run in a new scope for each item.
+func (c *Compiler) compileForInStatement(node *ast.ForInStatement) {
+	c.compileExpression(node.Iterable, false)
+	c.emitAt(node.Token, code.OpIter)
+
+	loop := c.emitAt(node.Token, code.OpIterNext, 0)
+	c.pushScope(node.Body.Scope)
+	c.emit(code.OpBind, c.binding(node.Variable)...)
+	c.compileStatements(node.Body.Statements, false)
+	c.emit(code.OpPop)
+	c.emit(code.OpPopScope)
+	c.emit(code.OpJump, loop)
+
+	c.patch(loop, len(c.scope.instructions))
+	c.emit(code.OpNil)
+}
+
+
+var infixOperators = map[string]code.Opcode{
+	"+": code.OpAdd,
+	"-": code.OpSub,
+	"*": code.OpMul,
+	"/": code.OpDiv,
+	"%": code.OpMod,
+	"**": code.OpPow,
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+	"<": code.OpLess,
+	">": code.OpGreater,
+	"<=": code.OpLessOrEqual,
+	">=": code.OpGreaterOrEqual,
+	"&": code.OpBitAnd,
+	"|": code.OpBitOr,
+	"^": code.OpBitXor,
+	"<<": code.OpShiftLeft,
+	">>": code.OpShiftRight,
+}
+
+var prefixOperators = map[string]code.Opcode{
+	"-": code.OpMinus,
+	"!": code.OpBang,
+	"~": code.OpBitNot,
+}
+
+// compileExpression compile expr, leaving its value on the stack. When
+// tail is set, expr is in tail position in a function body, so a call
+// there is compiled as a tail call.
+func (c *Compiler) compileExpression(expr ast.Expression, tail bool) {
+
+	switch node := expr.(type) {
+
+	case nil:
+		c.emit(code.OpNil)
+
+	case *ast.Identifier:
+		c.emitAt(node.Token, code.OpGetName, c.binding(node)...)
+
+	case *ast.IntegerLiteral:
+		c.emit(code.OpConstant, c.constant(object.NewInteger(node.Value)))
+
+	case *ast.FloatLiteral:
+		c.emit(code.OpConstant, c.constant(&object.Float{ Value: node.Value }))
+
+	case *ast.StringLiteral:
+		c.emit(code.OpConstant, c.constant(&object.String{ Value: node.Value }))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			c.compileExpression(element, false)
+		}
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		for i, key := range node.Keys {
+			c.compileExpression(key, false)
+			c.compileExpression(node.Values[i], false)
+		}
+		c.emitAt(node.Token, code.OpHash, len(node.Keys))
+
+	case *ast.IndexExpression:
+		c.compileExpression(node.Left, false)
+		c.compileExpression(node.Index, false)
+		c.emitAt(node.Token, code.OpIndex)
+
+	case *ast.SliceExpression:
+		c.compileExpression(node.Left, false)
+		bounds := 0
+
+		for i, bound := range []ast.Expression{ node.Start, node.Stop, node.Step } {
+			if bound != nil {
+				c.compileExpression(bound, false)
+				bounds |= 1 << i
+			}
+		}
+		c.emitAt(node.Token, code.OpSlice, bounds)
+
+	case *ast.RangeExpression:
+		c.compileExpression(node.Start, false)
+		c.compileExpression(node.End, false)
+
+		if node.Inclusive {
+			c.emitAt(node.Token, code.OpRange, 1)
+		} else {
+			c.emitAt(node.Token, code.OpRange, 0)
+		}
+
+	case *ast.MemberExpression:
+		c.compileExpression(node.Object, false)
+		c.emitAt(node.Member.Token, code.OpMember, c.name(node.Member.Value))
+
+	case *ast.FunctionLiteral:
+		c.emit(code.OpClosure, c.compileFunction(node))
+
+	case *ast.IfElseExpression:
+		c.compileIfElseExpression(node, tail)
+
+	case *ast.FunctionCallExpression:
+		c.compileFunctionCall(node, tail)
+
+	case *ast.PrefixExpression:
+		if node.Operator == "++" || node.Operator == "--" {
+			c.compileUpdateExpression(node.Token, node.Operator, node.Right, true)
+			return
+		}
+		c.compileExpression(node.Right, false)
+
+		if op, ok := prefixOperators[node.Operator]; ok {
+			c.emitAt(node.Token, op)
+		} else {
+			c.emit(code.OpPop)
+			c.emit(code.OpNull)
+		}
+
+	case *ast.PostfixExpression:
+		c.compileUpdateExpression(node.Token, node.Operator, node.Left, false)
+
+	case *ast.InfixExpression:
+		c.compileExpression(node.Left, false)
+		c.compileExpression(node.Right, false)
+
+		if op, ok := infixOperators[node.Operator]; ok {
+			c.emitAt(node.Token, op)
+		} else {
+			c.fail("unknown operator: %s", node.Operator)
+		}
+
+	default:
+		c.emit(code.OpNil)
+	}
+}
+
+// compileIfElseExpression compile the branches, each run in
+// its own scope. Without alternative, the value is NULL.
+func (c *Compiler) compileIfElseExpression(node *ast.IfElseExpression, tail bool) {
+	c.compileExpression(node.Condition, false)
+	jumpNotTruthy := c.emit(code.OpJumpNotTruthy, 0)
+
+	c.pushScope(node.Consequence.Scope)
+	c.compileStatements(node.Consequence.Statements, tail)
+	c.emit(code.OpPopScope)
+	jump := c.emit(code.OpJump, 0)
+
+	c.patch(jumpNotTruthy, len(c.scope.instructions))
+
+	if node.Alternative != nil {
+		c.pushScope(node.Alternative.Scope)
+		c.compileStatements(node.Alternative.Statements, tail)
+		c.emit(code.OpPopScope)
+	} else {
+		c.emit(code.OpNull)
+	}
+
+	c.patch(jump, len(c.scope.instructions))
+}
+
+// compileFunctionCall compile the callee, then the arguments from left
+// to right. Named and spread arguments are wrapped, so the VM can tell
+// them apart from the positional ones.
+func (c *Compiler) compileFunctionCall(node *ast.FunctionCallExpression, tail bool) {
+	c.compileExpression(node.Function, false)
+
+	for _, arg := range node.Arguments {
+
+		switch arg := arg.(type) {
+
+		case *ast.SpreadExpression:
+			c.compileExpression(arg.Value, false)
+			c.emitAt(arg.Token, code.OpSpread)
+
+		case *ast.NamedArgument:
+			c.compileExpression(arg.Value, false)
+			c.emit(code.OpNamedArgument, c.name(arg.Name.Value))
+
+		default:
+			c.compileExpression(arg, false)
+		}
+	}
+
+	if len(node.Arguments) > MAX_ARGUMENTS {
+		c.fail("too many arguments in call: %d", len(node.Arguments))
+	}
+
+	if tail {
+		c.emitAt(node.Token, code.OpTailCall, len(node.Arguments))
+	} else {
+		c.emitAt(node.Token, code.OpCall, len(node.Arguments))
+	}
+}
+
+// compileUpdateExpression compile `++` and `--` applied to target, which
+// must be a name or an array element. OpUpdate leave the value of the
+// expression under the updated value, which is then stored.
+func (c *Compiler) compileUpdateExpression(tok token.Token, operator string, target ast.Expression, prefix bool) {
+	decrement, isPrefix := 0, 0
+
+	if operator == "--" {
+		decrement = 1
+	}
+
+	if prefix {
+		isPrefix = 1
+	}
+
+	switch target := target.(type) {
+
+	case *ast.Identifier:
+		binding := c.binding(target)
+
+		c.emitAt(tok, code.OpCheckMutable, binding...)
+		c.emitAt(target.Token, code.OpGetName, binding...)
+		c.emitAt(tok, code.OpUpdate, decrement, isPrefix)
+		c.emit(code.OpAssign, binding...)
+
+	case *ast.IndexExpression:
+		c.compileExpression(target.Left, false)
+		c.compileExpression(target.Index, false)
+		c.emitAt(target.Token, code.OpIndexForUpdate)
+		c.emitAt(tok, code.OpUpdate, decrement, isPrefix)
+		c.emit(code.OpSetIndex)
+
+	default:
+		message := fmt.Sprintf("invalid operand for %s: %s", operator, target.String())
+		c.emitAt(tok, code.OpRaise, c.constant(&object.String{ Value: message }))
+	}
+}
+
+// compileFunction compile a function literal in its own scope,
+// returning the index of the compiled function in the pool.
+func (c *Compiler) compileFunction(node *ast.FunctionLiteral) int {
+	fn := &object.CompiledFunction{ Name: node.Name, Scope: scopeOf(node.Body.Scope) }
+
+	for _, param := range node.Params {
+		compiled := object.CompiledParameter{ Name: param.Name.Value, Rest: param.Rest }
+
+		if param.Default != nil {
+			compiled.Default = c.enterScope(&object.CompiledFunction{ Name: param.Name.Value, Scope: scopeOf(nil) }, false)
+			c.compileExpression(param.Default, false)
+			c.emit(code.OpReturn)
+			c.leaveScope()
+		}
+		fn.Parameters = append(fn.Parameters, compiled)
+	}
+
+	c.enterScope(fn, true)
+	c.compileStatements(node.Body.Statements, true)
+	c.emit(code.OpReturn)
+	c.leaveScope()
+
+	return c.addConstant(fn)
+}
+
+// statementToken return the token a statement start with.
+func statementToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+
+	case *ast.ExpressionStatement:
+		return stmt.Token
+
+	case *ast.DeclarationStatement:
+		return stmt.Token
+
+	case *ast.ReturnStatement:
+		return stmt.Token
+
+	case *ast.ThrowStatement:
+		return stmt.Token
+
+	case *ast.BlockStatement:
+		return stmt.Token
+
+	case *ast.FunctionDeclaration:
+		return stmt.Token
+
+	case *ast.ForInStatement:
+		return stmt.Token
+
+	case *ast.TryStatement:
+		return stmt.Token
+
+	case *ast.ImportStatement:
+		return stmt.Token
+
+	case *ast.ExportStatement:
+		return stmt.Token
+	}
+
+	return token.Token{}
+}
@@ -0,0 +1,176 @@
+package compiler
+
+import (
+	"monkey/internal/code"
+	"monkey/internal/lexer"
+	"monkey/internal/object"
+	"monkey/internal/parser"
+	"testing"
+)
+
+
+func TestCompile(t *testing.T) {
+
+	t.Run("it should lower statements to instructions", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	[][]byte
+		}{
+			{
+				"1 + 2",
+				[][]byte{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturn),
+				},
+			},
+			{
+				"let x = 1; x",
+				[][]byte{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpDefine, 1),
+					code.Make(code.OpNil),
+					code.Make(code.OpPop),
+					code.Make(code.OpGetName, 1),
+					code.Make(code.OpReturn),
+				},
+			},
+			{
+				"if (true) { 10 }",
+				[][]byte{
+					code.Make(code.OpTrue),
+					code.Make(code.OpJumpNotTruthy, 14),
+					code.Make(code.OpPushScope, 0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpPopScope),
+					code.Make(code.OpJump, 15),
+					code.Make(code.OpNull),
+					code.Make(code.OpReturn),
+				},
+			},
+			{
+				"x++",
+				[][]byte{
+					code.Make(code.OpCheckMutable, 0),
+					code.Make(code.OpGetName, 0),
+					code.Make(code.OpUpdate, 0, 0),
+					code.Make(code.OpAssign, 0),
+					code.Make(code.OpReturn),
+				},
+			},
+			{
+				"",
+				[][]byte{
+					code.Make(code.OpNil),
+					code.Make(code.OpReturn),
+				},
+			},
+		}
+
+		for i, tt := range tests {
+			bytecode := testCompile(t, tt.input)
+			expected := code.Instructions{}
+
+			for _, ins := range tt.expected {
+				expected = append(expected, ins...)
+			}
+
+			if bytecode.Main.Instructions.String() != expected.String() {
+				t.Fatalf(
+					"[test #%d]: Expecting instructions\n%s\nbut got\n%s\n",
+					i, expected, bytecode.Main.Instructions,
+				)
+			}
+		}
+	})
+
+	t.Run("it should store each literal once in the constant pool", func(t *testing.T) {
+		bytecode := testCompile(t, `1 + 1; "a" + "a"; 1.5`)
+
+		if len(bytecode.Constants) != 3 {
+			t.Fatalf("Expecting 3 constants, but got %d\n", len(bytecode.Constants))
+		}
+	})
+
+	t.Run("it should compile calls in tail position as tail calls", func(t *testing.T) {
+		bytecode := testCompile(t, "fn f(n) { if n { f(n - 1) } else { g(n) } }")
+		fn := findFunction(t, bytecode, "f")
+
+		tailCalls := 0
+
+		for i := 0; i < len(fn.Instructions); {
+			def, _ := code.Lookup(fn.Instructions[i])
+			_, read := code.ReadOperands(def, fn.Instructions[i+1:])
+
+			if code.Opcode(fn.Instructions[i]) == code.OpTailCall {
+				tailCalls++
+			}
+			i += 1 + read
+		}
+
+		if tailCalls != 2 {
+			t.Fatalf("Expecting 2 tail calls, but got %d\n%s\n", tailCalls, fn.Instructions)
+		}
+	})
+
+	t.Run("it should not compile calls returned from a try block as tail calls", func(t *testing.T) {
+		bytecode := testCompile(t, "fn f() { try { return g() } finally { 1 } }")
+		fn := findFunction(t, bytecode, "f")
+
+		for i := 0; i < len(fn.Instructions); {
+			def, _ := code.Lookup(fn.Instructions[i])
+			_, read := code.ReadOperands(def, fn.Instructions[i+1:])
+
+			if code.Opcode(fn.Instructions[i]) == code.OpTailCall {
+				t.Fatalf("Expecting no tail call, but got\n%s\n", fn.Instructions)
+			}
+			i += 1 + read
+		}
+	})
+
+	t.Run("it should record the position of the instructions that can fail", func(t *testing.T) {
+		bytecode := testCompile(t, "let a = 1;\n  a + b")
+		position, ok := bytecode.Main.PositionAt(21)
+
+		if !ok || position.Line != 2 || position.Column != 7 {
+			t.Fatalf("Expecting the position 2:7, but got %+v\n%s\n", position, bytecode.Main.Instructions)
+		}
+	})
+
+	t.Run("it should list the exported names", func(t *testing.T) {
+		bytecode := testCompile(t, "export let a = 1; let b = 2; export fn c() { 3 }")
+
+		if len(bytecode.Exports) != 2 || bytecode.Exports[0] != "a" || bytecode.Exports[1] != "c" {
+			t.Fatalf("Expecting the exports [a c], but got %v\n", bytecode.Exports)
+		}
+	})
+}
+
+
+func findFunction(t *testing.T, bytecode *Bytecode, name string) *object.CompiledFunction {
+	for _, constant := range bytecode.Constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok && fn.Name == name {
+			return fn
+		}
+	}
+	t.Fatalf("Expecting the function %s in the constant pool\n", name)
+
+	return nil
+}
+
+func testCompile(t *testing.T, input string) *Bytecode {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Expecting no parser errors, but got %v\n", p.Errors())
+	}
+	bytecode, err := Compile(program)
+
+	if err != nil {
+		t.Fatalf("Expecting no compilation error, but got %s\n", err)
+	}
+
+	return bytecode
+}
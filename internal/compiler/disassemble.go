@@ -0,0 +1,190 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"monkey/internal/code"
+	"monkey/internal/object"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+
+// Disassemble write a readable listing of bytecode to w. Each function is
+// listed with the offset, opcode and operands of its instructions, the
+// constants they refer to and, when source is given, the lines they were
+// compiled from. Functions are listed after the function creating them,
+// indented one more level, and default values after their function.
+func Disassemble(w io.Writer, bytecode *Bytecode, source string) {
+	d := &disassembler{
+		w: w,
+		lines: strings.Split(source, "\n"),
+		listed: map[*object.CompiledFunction]bool{},
+	}
+
+	if source == "" {
+		d.lines = nil
+	}
+
+	d.function(bytecode.Main, "<main>", 0)
+}
+
+type disassembler struct {
+	w			io.Writer
+	lines		[]string
+	listed		map[*object.CompiledFunction]bool
+}
+
+func (d *disassembler) function(fn *object.CompiledFunction, title string, depth int) {
+	if d.listed[fn] {
+		return
+	}
+	d.listed[fn] = true
+
+	indent := strings.Repeat("    ", depth)
+	nested := []*object.CompiledFunction{}
+
+	if fn.File != "" {
+		title += " " + fn.File
+	}
+	fmt.Fprintf(d.w, "%s%s\n", indent, title)
+
+	line := 0
+
+	for offset := 0; offset < len(fn.Instructions); {
+		def, err := code.Lookup(fn.Instructions[offset])
+
+		if err != nil {
+			fmt.Fprintf(d.w, "%s    %04d ERROR: %s\n", indent, offset, err)
+			offset++
+			continue
+		}
+		operands, read := code.ReadOperands(def, fn.Instructions[offset+1:])
+
+		if current := lineAt(fn, offset); current != 0 && current != line {
+			line = current
+			header := fmt.Sprintf("%s  %4d| %s", indent, line, d.sourceLine(line))
+			fmt.Fprintln(d.w, strings.TrimRight(header, " "))
+		}
+
+		instruction := def.Name
+
+		for _, operand := range operands {
+			instruction += " " + strconv.Itoa(operand)
+		}
+		comment := d.comment(fn, code.Opcode(fn.Instructions[offset]), operands)
+
+		if comment == "" {
+			fmt.Fprintf(d.w, "%s    %04d %s\n", indent, offset, instruction)
+		} else {
+			fmt.Fprintf(d.w, "%s    %04d %-24s %s\n", indent, offset, instruction, comment)
+		}
+
+		if code.Opcode(fn.Instructions[offset]) == code.OpClosure {
+			if closure, ok := constantAt(fn, operands[0]).(*object.CompiledFunction); ok {
+				nested = append(nested, closure)
+			}
+		}
+		offset += 1 + read
+	}
+
+	for _, param := range fn.Parameters {
+		if param.Default != nil {
+			fmt.Fprintln(d.w)
+			d.function(param.Default, "default " + param.Name, depth + 1)
+		}
+	}
+
+	for _, closure := range nested {
+		fmt.Fprintln(d.w)
+		d.function(closure, closure.Signature(), depth + 1)
+	}
+}
+
+// comment describe the constants an instruction refer to.
+func (d *disassembler) comment(fn *object.CompiledFunction, op code.Opcode, operands []int) string {
+	switch op {
+
+	case code.OpConstant, code.OpRaise:
+		return formatConstant(constantAt(fn, operands[0]))
+
+	case code.OpGetName, code.OpDefine, code.OpDefineConst, code.OpBind, code.OpCheckMutable,
+		code.OpAssign, code.OpMember, code.OpNamedArgument:
+		return constantName(fn, operands[0])
+
+	case code.OpClosure:
+		if closure, ok := constantAt(fn, operands[0]).(*object.CompiledFunction); ok {
+			return closure.Signature()
+		}
+
+	case code.OpImport:
+		return formatConstant(constantAt(fn, operands[0]))
+
+	case code.OpPushScope:
+		if operands[0] < len(fn.Blocks) {
+			return "[" + strings.Join(fn.Blocks[operands[0]].Names, ", ") + "]"
+		}
+	}
+
+	return ""
+}
+
+func (d *disassembler) sourceLine(line int) string {
+	if line > len(d.lines) {
+		return ""
+	}
+
+	return strings.TrimSpace(d.lines[line - 1])
+}
+
+// lineAt return the line an instruction was compiled from: the line of
+// the last position recorded at or before it, either the one of the
+// statement it's part of or of an instruction that can fail.
+func lineAt(fn *object.CompiledFunction, offset int) int {
+	i := sort.Search(len(fn.Positions), func(i int) bool { return fn.Positions[i].Offset > offset })
+
+	if i == 0 {
+		return 0
+	}
+
+	return fn.Positions[i - 1].Line
+}
+
+func constantAt(fn *object.CompiledFunction, index int) object.Object {
+	if index < len(fn.Constants) {
+		return fn.Constants[index]
+	}
+
+	return nil
+}
+
+func constantName(fn *object.CompiledFunction, index int) string {
+	if name, ok := constantAt(fn, index).(*object.String); ok {
+		return name.Value
+	}
+
+	return "?"
+}
+
+func formatConstant(obj object.Object) string {
+	switch obj := obj.(type) {
+
+	case nil:
+		return "?"
+
+	case *object.String:
+		return strconv.Quote(obj.Value)
+
+	case *object.Float:
+		formatted := strconv.FormatFloat(obj.Value, 'g', -1, 64)
+
+		// Keep floats holding an integer apart from integers.
+		if !strings.ContainsAny(formatted, ".eIN") {
+			formatted += ".0"
+		}
+		return formatted
+	}
+
+	return obj.Inspect()
+}
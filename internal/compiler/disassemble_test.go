@@ -0,0 +1,91 @@
+package compiler
+
+import (
+	"bytes"
+	"monkey/internal/lexer"
+	"monkey/internal/parser"
+	"monkey/internal/resolver"
+	"strings"
+	"testing"
+)
+
+
+func TestDisassemble(t *testing.T) {
+
+	t.Run("it should list each function with its constants and source lines", func(t *testing.T) {
+		source := strings.Join([]string{
+			`fn greet(name, greeting = "Hi") {`,
+			`	greeting + name`,
+			`}`,
+			`greet(1.0)`,
+		}, "\n")
+		expected := strings.Join([]string{
+			`<main> test.mk`,
+			`     1| fn greet(name, greeting = "Hi") {`,
+			`    0000 OpClosure 3              fn greet(name, greeting = ...)`,
+			`    0003 OpBind 4 2 0 0           greet`,
+			`    0011 OpNil`,
+			`    0012 OpPop`,
+			`     4| greet(1.0)`,
+			`    0013 OpGetName 4 2 0 0        greet`,
+			`    0021 OpConstant 5             1.0`,
+			`    0024 OpCall 1`,
+			`    0026 OpReturn`,
+			``,
+			`    fn greet(name, greeting = ...) test.mk`,
+			`         2| greeting + name`,
+			`        0000 OpGetName 1 1 0 1        greeting`,
+			`        0008 OpGetName 2 1 0 0        name`,
+			`        0016 OpAdd`,
+			`        0017 OpReturn`,
+			``,
+			`        default greeting`,
+			`            0000 OpConstant 0             "Hi"`,
+			`            0003 OpReturn`,
+			``,
+		}, "\n")
+
+		parser := parser.New(lexer.NewFile("test.mk", source))
+		program := parser.ParseProgram()
+		resolver.Resolve(program)
+
+		bytecode, err := Compile(program)
+
+		if err != nil {
+			t.Fatalf("Expecting no compilation error, but got %s\n", err)
+		}
+
+		var output bytes.Buffer
+		Disassemble(&output, bytecode, source)
+
+		if output.String() != expected {
+			t.Fatalf("Expecting\n%s\nbut got\n%s\n", expected, output.String())
+		}
+	})
+
+	t.Run("it should give the line numbers only without source", func(t *testing.T) {
+		bytecode := testCompile(t, "let a = 1;\na.b")
+
+		var output bytes.Buffer
+		Disassemble(&output, bytecode, "")
+
+		if !strings.Contains(output.String(), "     2|\n    0013 OpGetName") {
+			t.Fatalf("Expecting the line 2 without source, but got\n%s\n", output.String())
+		}
+	})
+	t.Run("it should list hoisted functions under the line declaring them", func(t *testing.T) {
+		source := "let one = 1\nfn add(a, b) { a + b }\nadd(one, 2)"
+		bytecode := testCompile(t, source)
+
+		var output bytes.Buffer
+		Disassemble(&output, bytecode, source)
+
+		if !strings.HasPrefix(output.String(), "<main>\n     2| fn add(a, b) { a + b }\n    0000 OpClosure") {
+			t.Fatalf("Expecting the closure of add under the line 2, but got\n%s\n", output.String())
+		}
+
+		if !strings.Contains(output.String(), "     1| let one = 1\n    0011 OpConstant") {
+			t.Fatalf("Expecting the declaration of one under the line 1, but got\n%s\n", output.String())
+		}
+	})
+}
@@ -0,0 +1,726 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"math/bits"
+	"monkey/internal/ast"
+	"monkey/internal/code"
+	"monkey/internal/object"
+)
+
+
+// A compiled program is stored in a `.mkc` file laid out as follows,
+// integers being unsigned varints unless stated otherwise:
+//
+//	magic       4 bytes, FORMAT_MAGIC
+//	version     2 bytes, big endian
+//	constants   count, then a tag byte and a value per constant
+//	main        function
+//	exports     count, then strings
+//	checksum    4 bytes, big endian CRC-32 (IEEE) of all the previous bytes
+//
+// Strings are their length followed by their bytes. Integers are signed
+// varints, floats the 8 big endian bytes of their IEEE 754 bits. A function
+// is its name, file, parameters, scopes, instructions and line table:
+//
+//	name, file          strings
+//	parameters          count, then per parameter its name, a flag byte
+//	                    (1 rest, 2 default) and its default function
+//	scope               count, then the names of the body scope
+//	blocks              count, then per block scope the count and names
+//	instructions        length, then the bytes
+//	positions           count, then per position the offset from the
+//	                    previous one, the line and the column
+const (
+	FORMAT_MAGIC = "MKC\x00"
+	FORMAT_VERSION = 2
+)
+
+const (
+	INTEGER_CONSTANT byte = iota + 1
+	FLOAT_CONSTANT
+	STRING_CONSTANT
+	FUNCTION_CONSTANT
+)
+
+const (
+	REST_PARAMETER byte = 1 << iota
+	DEFAULT_PARAMETER
+)
+
+// FormatError is returned when reading a file that isn't a valid
+// compiled program, Offset being where the problem was found.
+type FormatError struct {
+	Offset		int
+	Message		string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("invalid bytecode file at byte %d: %s", e.Offset, e.Message)
+}
+
+
+// Write encode bytecode to w in the `.mkc` format.
+func Write(w io.Writer, bytecode *Bytecode) error {
+	data, err := Marshal(bytecode)
+
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+
+	return err
+}
+
+// Marshal encode bytecode in the `.mkc` format.
+func Marshal(bytecode *Bytecode) ([]byte, error) {
+	enc := &encoder{ data: []byte(FORMAT_MAGIC) }
+	enc.data = binary.BigEndian.AppendUint16(enc.data, FORMAT_VERSION)
+
+	enc.uint(len(bytecode.Constants))
+
+	for _, constant := range bytecode.Constants {
+		switch constant := constant.(type) {
+
+		case *object.Integer:
+			enc.data = append(enc.data, INTEGER_CONSTANT)
+			enc.data = binary.AppendVarint(enc.data, constant.Value)
+
+		case *object.Float:
+			enc.data = append(enc.data, FLOAT_CONSTANT)
+			enc.data = binary.BigEndian.AppendUint64(enc.data, math.Float64bits(constant.Value))
+
+		case *object.String:
+			enc.data = append(enc.data, STRING_CONSTANT)
+			enc.string(constant.Value)
+
+		case *object.CompiledFunction:
+			enc.data = append(enc.data, FUNCTION_CONSTANT)
+			enc.function(constant)
+
+		default:
+			return nil, fmt.Errorf("cannot encode constant of type %s", constant.Type())
+		}
+	}
+
+	enc.function(bytecode.Main)
+	enc.uint(len(bytecode.Exports))
+
+	for _, name := range bytecode.Exports {
+		enc.string(name)
+	}
+
+	return binary.BigEndian.AppendUint32(enc.data, crc32.ChecksumIEEE(enc.data)), nil
+}
+
+type encoder struct {
+	data	[]byte
+}
+
+func (enc *encoder) uint(value int) {
+	enc.data = binary.AppendUvarint(enc.data, uint64(value))
+}
+
+func (enc *encoder) string(value string) {
+	enc.uint(len(value))
+	enc.data = append(enc.data, value...)
+}
+
+// scope write the names of a scope, a missing one having no name.
+func (enc *encoder) scope(scope *ast.Scope) {
+	if scope == nil {
+		enc.uint(0)
+		return
+	}
+	enc.uint(len(scope.Names))
+
+	for _, name := range scope.Names {
+		enc.string(name)
+	}
+}
+
+func (enc *encoder) function(fn *object.CompiledFunction) {
+	enc.string(fn.Name)
+	enc.string(fn.File)
+	enc.uint(len(fn.Parameters))
+
+	for _, param := range fn.Parameters {
+		var flags byte
+
+		if param.Rest {
+			flags |= REST_PARAMETER
+		}
+
+		if param.Default != nil {
+			flags |= DEFAULT_PARAMETER
+		}
+		enc.string(param.Name)
+		enc.data = append(enc.data, flags)
+
+		if param.Default != nil {
+			enc.function(param.Default)
+		}
+	}
+
+	enc.scope(fn.Scope)
+	enc.uint(len(fn.Blocks))
+
+	for _, block := range fn.Blocks {
+		enc.scope(block)
+	}
+
+	enc.uint(len(fn.Instructions))
+	enc.data = append(enc.data, fn.Instructions...)
+	enc.uint(len(fn.Positions))
+
+	previous := 0
+
+	for _, position := range fn.Positions {
+		enc.uint(position.Offset - previous)
+		enc.uint(position.Line)
+		enc.uint(position.Column)
+		previous = position.Offset
+	}
+}
+
+
+// Read decode a program in the `.mkc` format from r.
+func Read(r io.Reader) (*Bytecode, error) {
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Unmarshal(data)
+}
+
+// Unmarshal decode a program in the `.mkc` format. Truncated or corrupted
+// data, and instructions that would make the VM read out of the function
+// or the constant pool, are reported with a FormatError.
+func Unmarshal(data []byte) (*Bytecode, error) {
+	headerLength := len(FORMAT_MAGIC) + 2
+
+	if len(data) < headerLength || string(data[:len(FORMAT_MAGIC)]) != FORMAT_MAGIC {
+		return nil, &FormatError{ Offset: 0, Message: "not a compiled Monkey program" }
+	}
+
+	if version := binary.BigEndian.Uint16(data[len(FORMAT_MAGIC):]); version != FORMAT_VERSION {
+		return nil, &FormatError{
+			Offset: len(FORMAT_MAGIC),
+			Message: fmt.Sprintf("unsupported version %d, want %d", version, FORMAT_VERSION),
+		}
+	}
+
+	if len(data) < headerLength + 4 {
+		return nil, &FormatError{ Offset: len(data), Message: "unexpected end of file" }
+	}
+	body := data[:len(data) - 4]
+
+	if binary.BigEndian.Uint32(data[len(body):]) != crc32.ChecksumIEEE(body) {
+		return nil, &FormatError{ Offset: len(body), Message: "checksum mismatch" }
+	}
+
+	dec := &decoder{ data: body, offset: headerLength }
+	bytecode := &Bytecode{}
+
+	for range dec.count() {
+		switch tag := dec.byte(); tag {
+
+		case INTEGER_CONSTANT:
+			bytecode.Constants = append(bytecode.Constants, object.NewInteger(dec.int()))
+
+		case FLOAT_CONSTANT:
+			bits := dec.bytes(8)
+
+			if dec.err == nil {
+				value := math.Float64frombits(binary.BigEndian.Uint64(bits))
+				bytecode.Constants = append(bytecode.Constants, &object.Float{ Value: value })
+			}
+
+		case STRING_CONSTANT:
+			bytecode.Constants = append(bytecode.Constants, &object.String{ Value: dec.string() })
+
+		case FUNCTION_CONSTANT:
+			bytecode.Constants = append(bytecode.Constants, dec.function(false))
+
+		default:
+			dec.fail("unknown constant tag %d", tag)
+		}
+
+		if dec.err != nil {
+			return nil, dec.err
+		}
+	}
+
+	bytecode.Main = dec.function(false)
+
+	for range dec.count() {
+		bytecode.Exports = append(bytecode.Exports, dec.string())
+	}
+
+	if dec.err == nil && dec.offset != len(body) {
+		dec.fail("%d unexpected bytes after the program", len(body) - dec.offset)
+	}
+
+	for _, fn := range dec.functions {
+		fn.Constants = bytecode.Constants
+		dec.validate(fn, bytecode.Constants)
+	}
+
+	if dec.err != nil {
+		return nil, dec.err
+	}
+
+	return bytecode, nil
+}
+
+// decoder read the body of a file, recording the first error met.
+// Once it failed, its methods return zero values.
+type decoder struct {
+	data		[]byte
+	offset		int
+	functions	[]*object.CompiledFunction
+	err			*FormatError
+}
+
+func (dec *decoder) fail(format string, args ...any) {
+	if dec.err == nil {
+		dec.err = &FormatError{ Offset: dec.offset, Message: fmt.Sprintf(format, args...) }
+	}
+}
+
+func (dec *decoder) bytes(n int) []byte {
+	if dec.err != nil {
+		return nil
+	}
+
+	if n > len(dec.data) - dec.offset {
+		dec.fail("unexpected end of file")
+		return nil
+	}
+	value := dec.data[dec.offset:dec.offset + n]
+	dec.offset += n
+
+	return value
+}
+
+func (dec *decoder) byte() byte {
+	if value := dec.bytes(1); value != nil {
+		return value[0]
+	}
+
+	return 0
+}
+
+func (dec *decoder) uint() int {
+	if dec.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(dec.data[dec.offset:])
+
+	if n <= 0 || value > math.MaxInt32 {
+		dec.fail("invalid integer")
+		return 0
+	}
+	dec.offset += n
+
+	return int(value)
+}
+
+func (dec *decoder) int() int64 {
+	if dec.err != nil {
+		return 0
+	}
+	value, n := binary.Varint(dec.data[dec.offset:])
+
+	if n <= 0 {
+		dec.fail("invalid integer")
+		return 0
+	}
+	dec.offset += n
+
+	return value
+}
+
+// count read the length of a list. Each item taking at least one byte,
+// a length larger than the rest of the file is rejected before anything
+// is allocated for it.
+func (dec *decoder) count() int {
+	count := dec.uint()
+
+	if count > len(dec.data) - dec.offset {
+		dec.fail("length %d larger than the rest of the file", count)
+		return 0
+	}
+
+	return count
+}
+
+func (dec *decoder) string() string {
+	return string(dec.bytes(dec.count()))
+}
+
+func (dec *decoder) scope() *ast.Scope {
+	scope := &ast.Scope{ Names: []string{} }
+
+	for range dec.count() {
+		scope.Names = append(scope.Names, dec.string())
+	}
+
+	return scope
+}
+
+// function read a function. Default values are compiled as functions
+// without parameters, so they're the only nested functions.
+func (dec *decoder) function(isDefault bool) *object.CompiledFunction {
+	fn := &object.CompiledFunction{ Name: dec.string(), File: dec.string() }
+	dec.functions = append(dec.functions, fn)
+
+	parameters := dec.count()
+
+	if isDefault && parameters != 0 {
+		dec.fail("default value of %q with parameters", fn.Name)
+	}
+
+	for range parameters {
+		param := object.CompiledParameter{ Name: dec.string() }
+		flags := dec.byte()
+
+		if flags &^ (REST_PARAMETER | DEFAULT_PARAMETER) != 0 {
+			dec.fail("unknown parameter flags %d", flags)
+		}
+		param.Rest = flags & REST_PARAMETER != 0
+
+		if flags & DEFAULT_PARAMETER != 0 {
+			param.Default = dec.function(true)
+		}
+		fn.Parameters = append(fn.Parameters, param)
+	}
+
+	fn.Scope = dec.scope()
+
+	for range dec.count() {
+		fn.Blocks = append(fn.Blocks, dec.scope())
+	}
+
+	fn.Instructions = code.Instructions(dec.bytes(dec.count()))
+
+	offset := 0
+
+	for i := range dec.count() {
+		offset += dec.uint()
+		position := object.Position{ Offset: offset, Line: dec.uint(), Column: dec.uint() }
+
+		if (i > 0 && position.Offset == fn.Positions[i - 1].Offset) || position.Offset >= len(fn.Instructions) {
+			dec.fail("invalid position offset %d in %q", position.Offset, fn.Name)
+		}
+		fn.Positions = append(fn.Positions, position)
+	}
+
+	return fn
+}
+
+// validate check that the instructions of fn can be run without reading
+// out of them, of the constant pool or of the scopes of fn: each opcode is
+// known with all its operands, constants have the expected type, bindings
+// have a known kind, jumps land on an instruction and the instructions end
+// with OpReturn. Jumps going back must land on an OpIterNext, which check
+// the budgets, so a loop can always be stopped.
+//
+// The values the instructions are given are only known at run time, so
+// the VM still check them, returning an error for the ones it can't use.
+func (dec *decoder) validate(fn *object.CompiledFunction, constants []object.Object) {
+	if dec.err != nil {
+		return
+	}
+	ins := fn.Instructions
+
+	starts := map[int]bool{}
+	jumps := [][2]int{} // offsets of the jumping instruction and of its target
+	last := code.Opcode(0)
+
+	for i := 0; i < len(ins); {
+		def, err := code.Lookup(ins[i])
+
+		if err != nil {
+			dec.fail("%s at instruction %d", err, i)
+			return
+		}
+		width := 0
+
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+
+		if i + 1 + width > len(ins) {
+			dec.fail("truncated %s at instruction %d", def.Name, i)
+			return
+		}
+		op := code.Opcode(ins[i])
+		operands, _ := code.ReadOperands(def, ins[i+1:])
+
+		for _, expected := range constantOperands(op, operands) {
+			if expected.index >= len(constants) {
+				dec.fail("%s at instruction %d refers to constant %d of %d", def.Name, i, expected.index, len(constants))
+				return
+			}
+
+			if !expected.valid(constants[expected.index]) {
+				dec.fail("%s at instruction %d refers to a %s constant", def.Name, i, constants[expected.index].Type())
+				return
+			}
+		}
+
+		switch op {
+
+		case code.OpGetName, code.OpDefine, code.OpDefineConst, code.OpBind, code.OpCheckMutable, code.OpAssign:
+			if ast.BindingKind(operands[1]) > ast.GLOBAL {
+				dec.fail("%s at instruction %d has unknown binding kind %d", def.Name, i, operands[1])
+				return
+			}
+
+		case code.OpPushScope:
+			if operands[0] >= len(fn.Blocks) {
+				dec.fail("%s at instruction %d refers to block %d of %d", def.Name, i, operands[0], len(fn.Blocks))
+				return
+			}
+
+		case code.OpJump, code.OpJumpNotTruthy, code.OpIterNext:
+			jumps = append(jumps, [2]int{ i, operands[0] })
+
+		case code.OpTry:
+			for _, target := range operands {
+				if target != 0 {
+					jumps = append(jumps, [2]int{ i, target })
+				}
+			}
+		}
+
+		starts[i] = true
+		last = op
+		i += 1 + width
+	}
+
+	if last != code.OpReturn {
+		dec.fail("instructions not ended by OpReturn")
+		return
+	}
+
+	for _, jump := range jumps {
+		from, target := jump[0], jump[1]
+
+		if !starts[target] {
+			dec.fail("jump to %d, which isn't an instruction", target)
+			return
+		}
+
+		if target <= from && code.Opcode(ins[target]) != code.OpIterNext {
+			dec.fail("jump back to %d at instruction %d, which isn't a loop", target, from)
+			return
+		}
+	}
+
+	dec.checkStack(fn)
+}
+
+// state is what checkStack know before an instruction: the number of
+// values on the stack of the running frame, and of the block scopes
+// entered since the start of the function.
+type state struct {
+	stack	int
+	scopes	int
+}
+
+// checkStack follow every path through the instructions of fn, which
+// validate checked, to ensure that no instruction pop more values than
+// its frame pushed, nor leave more scopes than were entered. Each
+// instruction must be reached with the same state whatever the path,
+// so a loop can't grow the stack. The blocks of a try statement are
+// run in frames of their own, starting with an empty stack, except the
+// catch block which is given the error.
+func (dec *decoder) checkStack(fn *object.CompiledFunction) {
+	ins := fn.Instructions
+	states := map[int]state{}
+	pending := []int{}
+
+	reach := func(from, offset int, reached state) bool {
+		if known, ok := states[offset]; ok {
+			if known != reached {
+				dec.fail("instruction %d reached with different stacks from instruction %d", offset, from)
+				return false
+			}
+			return true
+		}
+		states[offset] = reached
+		pending = append(pending, offset)
+
+		return true
+	}
+	reach(0, 0, state{})
+
+	for len(pending) > 0 {
+		i := pending[len(pending) - 1]
+		pending = pending[:len(pending) - 1]
+
+		op := code.Opcode(ins[i])
+		def, _ := code.Lookup(ins[i])
+		operands, width := code.ReadOperands(def, ins[i+1:])
+		next := i + 1 + width
+
+		current := states[i]
+		pops, pushes := stackEffect(op, operands)
+
+		if pops > current.stack {
+			dec.fail("%s at instruction %d pops more values than the stack holds", def.Name, i)
+			return
+		}
+		after := state{ stack: current.stack - pops + pushes, scopes: current.scopes }
+		ok := true
+
+		switch op {
+
+		case code.OpPushScope:
+			after.scopes++
+			ok = reach(i, next, after)
+
+		case code.OpPopScope:
+			if current.scopes == 0 {
+				dec.fail("%s at instruction %d without a scope to leave", def.Name, i)
+				return
+			}
+			after.scopes--
+			ok = reach(i, next, after)
+
+		case code.OpJump:
+			ok = reach(i, operands[0], after)
+
+		case code.OpJumpNotTruthy:
+			ok = reach(i, next, after) && reach(i, operands[0], after)
+
+		case code.OpIterNext:
+			// Once done, the iterator is popped instead of pushing an item.
+			ok = reach(i, next, after) && reach(i, operands[0], state{ current.stack - 1, current.scopes })
+
+		case code.OpTry:
+			catch, finally, end := operands[0], operands[1], operands[2]
+			ok = reach(i, next, state{ 0, current.scopes })
+
+			if ok && catch != 0 {
+				ok = reach(i, catch, state{ 1, current.scopes })
+			}
+
+			if ok && finally != 0 {
+				ok = reach(i, finally, state{ 0, current.scopes })
+			}
+
+			if ok {
+				ok = reach(i, end, state{ current.stack + 1, current.scopes })
+			}
+
+		case code.OpReturn, code.OpEndBlock, code.OpThrow, code.OpRaise:
+
+		default:
+			ok = reach(i, next, after)
+		}
+
+		if !ok {
+			return
+		}
+	}
+}
+
+// stackEffect return the number of values an instruction pop from the
+// stack, and the number it push when it continue with the next one.
+func stackEffect(op code.Opcode, operands []int) (int, int) {
+	switch op {
+
+	case code.OpConstant, code.OpNil, code.OpNull, code.OpTrue, code.OpFalse,
+		code.OpGetName, code.OpClosure, code.OpImport:
+		return 0, 1
+
+	case code.OpPop, code.OpDefine, code.OpDefineConst, code.OpBind, code.OpAssign,
+		code.OpJumpNotTruthy, code.OpReturn, code.OpEndBlock, code.OpThrow:
+		return 1, 0
+
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+		code.OpEqual, code.OpNotEqual, code.OpLess, code.OpGreater,
+		code.OpLessOrEqual, code.OpGreaterOrEqual,
+		code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight,
+		code.OpIndex, code.OpRange:
+		return 2, 1
+
+	case code.OpMinus, code.OpBang, code.OpBitNot, code.OpMember,
+		code.OpNamedArgument, code.OpSpread, code.OpIter:
+		return 1, 1
+
+	case code.OpUpdate:
+		return 1, 2
+
+	case code.OpIndexForUpdate:
+		return 2, 3
+
+	case code.OpSetIndex:
+		return 4, 1
+
+	case code.OpArray:
+		return operands[0], 1
+
+	case code.OpHash:
+		return 2 * operands[0], 1
+
+	case code.OpSlice:
+		return 1 + bits.OnesCount(uint(operands[0] & 7)), 1
+
+	case code.OpCall, code.OpTailCall:
+		return operands[0] + 1, 1
+
+	case code.OpIterNext:
+		return 1, 2
+	}
+
+	// Jumps, scopes, OpCheckMutable, OpRaise and OpTry.
+	return 0, 0
+}
+
+type constantOperand struct {
+	index	int
+	valid	func(object.Object) bool
+}
+
+// constantOperands return the operands of an instruction that are indexes
+// in the constant pool, along with the type the constant must have.
+func constantOperands(op code.Opcode, operands []int) []constantOperand {
+	isName := func(obj object.Object) bool {
+		_, ok := obj.(*object.String)
+		return ok
+	}
+
+	switch op {
+
+	case code.OpConstant:
+		return []constantOperand{{ operands[0], func(obj object.Object) bool {
+			_, ok := obj.(*object.CompiledFunction)
+			return !ok
+		} }}
+
+	case code.OpClosure:
+		return []constantOperand{{ operands[0], func(obj object.Object) bool {
+			_, ok := obj.(*object.CompiledFunction)
+			return ok
+		} }}
+
+	case code.OpGetName, code.OpDefine, code.OpDefineConst, code.OpBind, code.OpCheckMutable,
+		code.OpAssign, code.OpMember, code.OpNamedArgument, code.OpRaise:
+		return []constantOperand{{ operands[0], isName }}
+
+	case code.OpImport:
+		return []constantOperand{{ operands[0], isName }}
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"monkey/internal/code"
+	"monkey/internal/object"
+	"reflect"
+	"testing"
+)
+
+
+const FORMAT_TEST_INPUT = `
+export let answer = 42;
+const ratio = 1.5;
+
+export fn greet(name, greeting = "Hello", ...rest) {
+	greeting + ", " + name + "!"
+}
+
+let twice = fn(x) { x * 2 };
+
+try {
+	for (i in 0..3) { twice(i) }
+} catch (e) {
+	e.message
+}
+greet("you", greeting: "Hi")
+`
+
+func TestMarshal(t *testing.T) {
+
+	t.Run("it should read back the bytecode it wrote", func(t *testing.T) {
+		bytecode := testCompile(t, FORMAT_TEST_INPUT)
+
+		var buffer bytes.Buffer
+
+		if err := Write(&buffer, bytecode); err != nil {
+			t.Fatalf("Expecting no error, but got %s\n", err)
+		}
+		decoded, err := Read(&buffer)
+
+		if err != nil {
+			t.Fatalf("Expecting no error, but got %s\n", err)
+		}
+
+		if !reflect.DeepEqual(decoded.Exports, bytecode.Exports) {
+			t.Fatalf("Expecting the exports %v, but got %v\n", bytecode.Exports, decoded.Exports)
+		}
+
+		if len(decoded.Constants) != len(bytecode.Constants) {
+			t.Fatalf("Expecting %d constants, but got %d\n", len(bytecode.Constants), len(decoded.Constants))
+		}
+
+		for i, constant := range bytecode.Constants {
+			if fn, ok := constant.(*object.CompiledFunction); ok {
+				testFunctionsEqual(t, fn, decoded.Constants[i])
+				continue
+			}
+
+			if decoded.Constants[i].Type() != constant.Type() || decoded.Constants[i].Inspect() != constant.Inspect() {
+				t.Fatalf("Expecting the constant %s, but got %s\n", constant.Inspect(), decoded.Constants[i].Inspect())
+			}
+		}
+
+		testFunctionsEqual(t, bytecode.Main, decoded.Main)
+	})
+
+	t.Run("it should reject truncated files", func(t *testing.T) {
+		data, _ := Marshal(testCompile(t, FORMAT_TEST_INPUT))
+
+		for length := range len(data) {
+			if _, err := Unmarshal(data[:length]); err == nil {
+				t.Fatalf("Expecting an error for a file of %d bytes out of %d\n", length, len(data))
+			}
+		}
+	})
+
+	t.Run("it should reject corrupted files", func(t *testing.T) {
+		data, _ := Marshal(testCompile(t, FORMAT_TEST_INPUT))
+
+		for i := range data {
+			corrupted := bytes.Clone(data)
+			corrupted[i] ^= 0x5a
+
+			if _, err := Unmarshal(corrupted); err == nil {
+				t.Fatalf("Expecting an error for a file corrupted at byte %d\n", i)
+			}
+		}
+	})
+
+	t.Run("it should reject invalid contents even with a valid checksum", func(t *testing.T) {
+		bytecode := testCompile(t, "1 + 2")
+		data, _ := Marshal(bytecode)
+		body := data[:len(data) - 4]
+		main := bytes.LastIndex(body, bytecode.Main.Instructions)
+
+		tests := []struct{
+			name		string
+			corrupt		func([]byte) []byte
+			expected	string
+		}{
+			{
+				"version",
+				func(body []byte) []byte { body[5] = 9; return body },
+				"unsupported version 9, want 2",
+			},
+			{
+				"constant tag",
+				func(body []byte) []byte { body[7] = 42; return body },
+				"unknown constant tag 42",
+			},
+			{
+				"constant index",
+				func(body []byte) []byte {
+					copy(body[main + 3:], code.Make(code.OpConstant, 7))
+					return body
+				},
+				"OpConstant at instruction 3 refers to constant 7 of 2",
+			},
+			{
+				"backward jump",
+				func(body []byte) []byte {
+					copy(body[main:], code.Make(code.OpJump, 0))
+					return body
+				},
+				"jump back to 0 at instruction 0, which isn't a loop",
+			},
+			{
+				"stack underflow",
+				func(body []byte) []byte {
+					copy(body[main:], []byte{ byte(code.OpPop), byte(code.OpNull), byte(code.OpNull) })
+					return body
+				},
+				"OpPop at instruction 0 pops more values than the stack holds",
+			},
+			{
+				"stack depth",
+				func(body []byte) []byte {
+					ins := append(code.Make(code.OpTrue), code.Make(code.OpJumpNotTruthy, 6)...)
+					copy(body[main:], append(ins, byte(code.OpNull), byte(code.OpNull), byte(code.OpNull)))
+					return body
+				},
+				"instruction 6 reached with different stacks from instruction 5",
+			},
+			{
+				"scope",
+				func(body []byte) []byte {
+					body[main + 6] = byte(code.OpPopScope)
+					return body
+				},
+				"OpPopScope at instruction 6 without a scope to leave",
+			},
+			{
+				"opcode",
+				func(body []byte) []byte {
+					body[main + 6] = 250
+					return body
+				},
+				"opcode 250 undefined at instruction 6",
+			},
+			{
+				"trailing bytes",
+				func(body []byte) []byte { return append(body, 0) },
+				"1 unexpected bytes after the program",
+			},
+			{
+				"list length",
+				func(body []byte) []byte { return binary.AppendUvarint(body[:6], 1 << 30) },
+				"length 1073741824 larger than the rest of the file",
+			},
+		}
+
+		for _, tt := range tests {
+			corrupted := tt.corrupt(bytes.Clone(body))
+			corrupted = binary.BigEndian.AppendUint32(corrupted, crc32.ChecksumIEEE(corrupted))
+
+			_, err := Unmarshal(corrupted)
+			var formatErr *FormatError
+
+			if !errors.As(err, &formatErr) {
+				t.Fatalf("[%s]: Expecting a FormatError, but got %v\n", tt.name, err)
+			}
+
+			if formatErr.Message != tt.expected {
+				t.Fatalf("[%s]: Expecting the error %q, but got %q\n", tt.name, tt.expected, formatErr.Message)
+			}
+		}
+	})
+}
+
+
+func testFunctionsEqual(t *testing.T, expected *object.CompiledFunction, obj object.Object) {
+	actual, ok := obj.(*object.CompiledFunction)
+
+	if !ok {
+		t.Fatalf("Expecting a compiled function, but got %T\n", obj)
+	}
+
+	if actual.Name != expected.Name || actual.File != expected.File {
+		t.Fatalf("Expecting the function %q of %q, but got %q of %q\n", expected.Name, expected.File, actual.Name, actual.File)
+	}
+
+	if actual.Instructions.String() != expected.Instructions.String() {
+		t.Fatalf("Expecting the instructions\n%s\nbut got\n%s\n", expected.Instructions, actual.Instructions)
+	}
+
+	if !reflect.DeepEqual(actual.Positions, expected.Positions) {
+		t.Fatalf("Expecting the positions %v, but got %v\n", expected.Positions, actual.Positions)
+	}
+
+	if len(actual.Parameters) != len(expected.Parameters) {
+		t.Fatalf("Expecting %d parameters, but got %d\n", len(expected.Parameters), len(actual.Parameters))
+	}
+
+	for i, param := range expected.Parameters {
+		if actual.Parameters[i].String() != param.String() {
+			t.Fatalf("Expecting the parameter %s, but got %s\n", param, actual.Parameters[i])
+		}
+
+		if param.Default != nil {
+			testFunctionsEqual(t, param.Default, actual.Parameters[i].Default)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package corpus
+
+import (
+	"embed"
+	"path"
+	"strings"
+)
+
+
+// The corpus is a set of Monkey programs exercising the language the way
+// real scripts do. Every engine must give the same results for them, and
+// the benchmarks of each package run them, so performance regressions
+// show up across the lexer, parser, evaluator and virtual machine alike.
+//
+// Each program is stored in programs/NAME.mk, along with the Inspect form
+// of the value of its last statement in programs/NAME.out. The errors the
+// checks are expected to report, for the programs exercising runtime
+// errors, are listed one per line in programs/NAME.diag. Warnings are not
+// listed: the programs are written like scripts, which often get some.
+
+
+//go:embed programs
+var files embed.FS
+
+// Program is a program of the corpus.
+type Program struct {
+	Name		string
+	File		string // path of the program in the corpus, like "programs/fibonacci.mk"
+	Source		string
+	Expected	string // Inspect form of the value of the last statement
+	Diagnostics	[]string // errors reported before running the program
+}
+
+// Programs return the programs of the corpus, sorted by name.
+func Programs() []Program {
+	entries, err := files.ReadDir("programs")
+
+	// The files are embedded in the binary, reading them can't fail.
+	if err != nil {
+		panic(err)
+	}
+
+	programs := []Program{}
+
+	for _, entry := range entries {
+		if path.Ext(entry.Name()) != ".mk" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".mk")
+		file := path.Join("programs", entry.Name())
+
+		source, err := files.ReadFile(file)
+
+		if err != nil {
+			panic(err)
+		}
+		expected, err := files.ReadFile(path.Join("programs", name + ".out"))
+
+		if err != nil {
+			panic(err)
+		}
+
+		diagnostics := []string{}
+
+		if listed, err := files.ReadFile(path.Join("programs", name + ".diag")); err == nil {
+			diagnostics = strings.Split(strings.TrimSuffix(string(listed), "\n"), "\n")
+		}
+
+		programs = append(programs, Program{
+			Name: name,
+			File: file,
+			Source: string(source),
+			Expected: strings.TrimSuffix(string(expected), "\n"),
+			Diagnostics: diagnostics,
+		})
+	}
+
+	return programs
+}
+
+// Source return the sources of all the programs of the corpus,
+// one after the other, repeated until they're at least size bytes.
+func Source(size int) string {
+	var output strings.Builder
+
+	for output.Len() < size {
+		for _, program := range Programs() {
+			output.WriteString(program.Source)
+			output.WriteString("\n")
+		}
+	}
+
+	return output.String()
+}
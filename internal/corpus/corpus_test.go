@@ -0,0 +1,138 @@
+package corpus
+
+import (
+	"monkey/internal/analysis"
+	"monkey/internal/ast"
+	"monkey/internal/checker"
+	"monkey/internal/compiler"
+	"monkey/internal/diagnostic"
+	"monkey/internal/evaluator"
+	"monkey/internal/lexer"
+	"monkey/internal/object"
+	"monkey/internal/optimizer"
+	"monkey/internal/parser"
+	"monkey/internal/resolver"
+	"monkey/internal/vm"
+	"slices"
+	"testing"
+)
+
+
+func TestPrograms(t *testing.T) {
+	engines := []struct{
+		name	string
+		run		func(t *testing.T, program *ast.Program) object.Object
+	}{
+		{ "evaluator", func(t *testing.T, program *ast.Program) object.Object {
+			return evaluator.Eval(program, object.NewEnvironment())
+		} },
+		{ "resolved evaluator", func(t *testing.T, program *ast.Program) object.Object {
+			resolver.Resolve(optimizer.Optimize(program))
+
+			return evaluator.Eval(program, object.NewEnvironment())
+		} },
+		{ "vm", func(t *testing.T, program *ast.Program) object.Object {
+			return vm.Run(testCompile(t, program), object.NewEnvironment())
+		} },
+		{ "optimized vm", func(t *testing.T, program *ast.Program) object.Object {
+			return vm.Run(testCompile(t, optimizer.Optimize(program)), object.NewEnvironment())
+		} },
+		{ "resolved vm", func(t *testing.T, program *ast.Program) object.Object {
+			resolver.Resolve(optimizer.Optimize(program))
+
+			return vm.Run(testCompile(t, program), object.NewEnvironment())
+		} },
+		{ "compiled file", func(t *testing.T, program *ast.Program) object.Object {
+			resolver.Resolve(program)
+
+			data, err := compiler.Marshal(testCompile(t, program))
+
+			if err != nil {
+				t.Fatalf("Expecting no error, but got %s\n", err)
+			}
+			bytecode, err := compiler.Unmarshal(data)
+
+			if err != nil {
+				t.Fatalf("Expecting no error, but got %s\n", err)
+			}
+			return vm.Run(bytecode, object.NewEnvironment())
+		} },
+	}
+
+	if len(Programs()) == 0 {
+		t.Fatalf("Expecting programs in the corpus\n")
+	}
+
+	for _, engine := range engines {
+		t.Run("it should give the expected results with the " + engine.name, func(t *testing.T) {
+			for _, program := range Programs() {
+				result := engine.run(t, testParse(t, program))
+
+				if result == nil {
+					t.Fatalf("[%s]: Expecting %s, but got nothing\n", program.Name, program.Expected)
+				}
+
+				if result.Inspect() != program.Expected {
+					t.Fatalf("[%s]: Expecting %s, but got %s\n", program.Name, program.Expected, result.Inspect())
+				}
+			}
+		})
+	}
+}
+
+func TestProgramDiagnostics(t *testing.T) {
+
+	t.Run("it should report the expected errors, and warnings only otherwise", func(t *testing.T) {
+		for _, program := range Programs() {
+			parsed := testParse(t, program)
+
+			diagnostics := resolver.Resolve(optimizer.Optimize(parsed))
+			diagnostics = append(diagnostics, checker.Check(parsed)...)
+
+			_, inferred := checker.Infer(parsed)
+			diagnostics = append(diagnostics, inferred...)
+			diagnostics = append(diagnostics, analysis.Analyze(parsed)...)
+
+			reported := map[string]bool{}
+
+			for _, d := range diagnostics {
+				if d.Severity != diagnostic.ERROR {
+					continue
+				}
+
+				if !slices.Contains(program.Diagnostics, d.String()) {
+					t.Fatalf("[%s]: Expecting no error %q, but got it\n", program.Name, d)
+				}
+				reported[d.String()] = true
+			}
+
+			for _, expected := range program.Diagnostics {
+				if !reported[expected] {
+					t.Fatalf("[%s]: Expecting the error %q, but got %v\n", program.Name, expected, diagnostics)
+				}
+			}
+		}
+	})
+}
+
+
+func testParse(t *testing.T, program Program) *ast.Program {
+	p := parser.New(lexer.NewFile(program.File, program.Source))
+	parsed := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("[%s]: Expecting no parser errors, but got %v\n", program.Name, p.Errors())
+	}
+
+	return parsed
+}
+
+func testCompile(t *testing.T, program *ast.Program) *compiler.Bytecode {
+	bytecode, err := compiler.Compile(program)
+
+	if err != nil {
+		t.Fatalf("Expecting no compilation error, but got %s\n", err)
+	}
+
+	return bytecode
+}
@@ -0,0 +1,23 @@
+fn counter() {
+	let count = 0;
+	fn() { ++count }
+}
+
+fn compose(f, g) {
+	fn(x) { f(g(x)) }
+}
+
+fn adder(n) {
+	fn(x) { x + n }
+}
+
+let next = counter();
+let double = fn(x) { x * 2 };
+let pipeline = compose(adder(1), compose(double, adder(-3)));
+
+let results = [0, 0, 0, 0, 0].map(fn(x) { pipeline(next()) });
+for (i in 0..5000) {
+	pipeline(next())
+}
+
+[results, pipeline(next())]
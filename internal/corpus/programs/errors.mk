@@ -0,0 +1,39 @@
+fn parseDigit(c) {
+	const DIGITS = { "0": 0, "1": 1, "2": 2, "3": 3, "4": 4, "5": 5, "6": 6, "7": 7, "8": 8, "9": 9 };
+
+	if (!DIGITS.has(c)) {
+		throw "not a digit: " + c
+	}
+	DIGITS[c]
+}
+
+fn parseNumber(s) {
+	s.split("").reduce(fn(acc, c) { acc * 10 + parseDigit(c) }, 0)
+}
+
+let inputs = "12,7x,300,abc,42,9,1e3,65535".split(",");
+let parsed = 0;
+let failed = 0;
+
+for (round in 0..200) {
+	for (input in inputs) {
+		try {
+			parseNumber(input);
+			parsed++
+		} catch (e) {
+			failed++
+		} finally {
+			round
+		}
+	}
+}
+
+fn describe(f) {
+	try {
+		return f()
+	} catch (e) {
+		return e.message
+	}
+}
+
+[parsed, failed, describe(fn() { parseNumber("4a2") }), describe(fn() { missing }), describe(fn() { "a" - 1 }), parseNumber("65535")]
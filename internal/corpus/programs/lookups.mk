@@ -0,0 +1,62 @@
+const NUMERALS = [
+	{ "value": 1000, "symbol": "M" },
+	{ "value": 900, "symbol": "CM" },
+	{ "value": 500, "symbol": "D" },
+	{ "value": 400, "symbol": "CD" },
+	{ "value": 100, "symbol": "C" },
+	{ "value": 90, "symbol": "XC" },
+	{ "value": 50, "symbol": "L" },
+	{ "value": 40, "symbol": "XL" },
+	{ "value": 10, "symbol": "X" },
+	{ "value": 9, "symbol": "IX" },
+	{ "value": 5, "symbol": "V" },
+	{ "value": 4, "symbol": "IV" },
+	{ "value": 1, "symbol": "I" }
+];
+
+const DIGITS = { "I": 1, "V": 5, "X": 10, "L": 50, "C": 100, "D": 500, "M": 1000 };
+
+fn toRoman(n) {
+	fn convert(n, i, acc) {
+		if (n == 0) {
+			return acc
+		}
+		let numeral = NUMERALS[i];
+
+		if (n >= numeral.value) {
+			convert(n - numeral.value, i, acc + numeral.symbol)
+		} else {
+			convert(n, i + 1, acc)
+		}
+	}
+	convert(n, 0, "")
+}
+
+fn fromRoman(roman) {
+	let values = (roman + " ").split("").map(fn(digit) {
+		if (DIGITS.has(digit)) { DIGITS[digit] } else { 0 }
+	});
+	let index = 0;
+
+	values.map(fn(value) {
+		index++
+
+		if (value == 0) {
+			0
+		} else if (value < values[index]) {
+			-value
+		} else {
+			value
+		}
+	}).reduce(fn(acc, value) { acc + value }, 0)
+}
+
+let checked = 0;
+
+for (n in 1..=1500) {
+	if (fromRoman(toRoman(n)) == n) {
+		checked++
+	}
+}
+
+[toRoman(1994), toRoman(2024), DIGITS.keys().join(""), DIGITS.has("Z"), checked]
@@ -0,0 +1,16 @@
+let counts = [0, 0, 0];
+let multiples = 0;
+
+for (i in 0..100) {
+	for (j in 0..100) {
+		counts[(i * j) % 3]++
+	}
+}
+
+for (i in 0..=10000) {
+	if (i % 3 == 0) {
+		multiples++
+	}
+}
+
+[counts, multiples]
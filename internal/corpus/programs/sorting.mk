@@ -0,0 +1,18 @@
+fn generate(count) {
+	fn build(acc, n) {
+		if (n == 0) { acc } else { build(acc + "x", n - 1) }
+	}
+
+	let index = 0;
+
+	build("", count).split("").map(fn(x) { (index++ * 7919) % 1000 })
+}
+
+let values = generate(2000);
+let sorted = values.sort();
+let descending = values.sort(fn(a, b) { b - a });
+let evens = values.filter(fn(x) { x % 2 == 0 });
+let total = values.reduce(fn(acc, x) { acc + x }, 0);
+let largest = values.reduce(fn(acc, x) { if (x > acc) { x } else { acc } });
+
+[sorted[:5], descending[:5], evens[:3], total, largest, sorted[1000]]
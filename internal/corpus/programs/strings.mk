@@ -0,0 +1,20 @@
+fn repeat(s, n) {
+	fn build(acc, n) {
+		if (n == 0) { acc } else { build(acc + s, n - 1) }
+	}
+	build("", n)
+}
+
+fn label(i) {
+	"item-" + "0123456789"[i % 10]
+}
+
+let line = repeat("monkey ", 500).trim();
+let words = line.split(" ");
+let labels = words.map(fn(word) { word.upper() }).filter(fn(word) { word.startsWith("MON") });
+
+let index = 0;
+let text = words.map(fn(word) { label(index++) }).join(",");
+let reversed = text[::-1];
+
+[labels[0], labels.join("").replace("MONKEY", "ape")[:9], text[:20], reversed[:6], words.indexOf("monkey")]
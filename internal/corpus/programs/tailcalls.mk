@@ -0,0 +1,31 @@
+fn sum(n, acc = 0) {
+	if (n == 0) {
+		acc
+	} else {
+		sum(n - 1, acc: acc + n)
+	}
+}
+
+fn isEven(n) { if (n == 0) { true } else { isOdd(n - 1) } }
+fn isOdd(n) { if (n == 0) { false } else { isEven(n - 1) } }
+
+fn collatz(n, steps) {
+	if (n == 1) {
+		return steps
+	}
+
+	if (n % 2 == 0) {
+		return collatz(n / 2, steps + 1)
+	}
+	collatz(3 * n + 1, steps + 1)
+}
+
+let longest = 0;
+
+for (n in 1..300) {
+	if (collatz(n, 0) > 100) {
+		longest++
+	}
+}
+
+[sum(50000), isEven(20001), collatz(27, 0), longest]
@@ -0,0 +1,381 @@
+package corpus
+
+
+// Snippet is a short program of the tests of the engines, along with
+// the Inspect form of the value of its last statement.
+type Snippet struct {
+	Input		string
+	Expected	string
+}
+
+// SNIPPETS are the programs of the tests of the evaluator, which every
+// engine must run the same way, with the same results and errors.
+var SNIPPETS = []Snippet{
+	{ "5", "5" },
+	{ "10", "10" },
+	{ "187", "187" },
+	{ "-20", "-20" },
+	{ "-3", "-3" },
+	{ "5 + 5 + 5 + 5 - 10", "10" },
+	{ "2 * 2 * 2 * 2 * 2", "32" },
+	{ "-50 + 100 + -50", "0" },
+	{ "5 * 2 + 10", "20" },
+	{ "5 + 2 * 10", "25" },
+	{ "20 + 2 * -10", "0" },
+	{ "12 % 10", "2" },
+	{ "50 / 2 * 2 + 10", "60" },
+	{ "2 * (5 + 10)", "30" },
+	{ "3 * 3 * 3 + 10", "37" },
+	{ "3 * (3 * 3) + 10", "37" },
+	{ "1000 * 1000", "1000000" },
+	{ "123456789 + 1", "123456790" },
+	{ "3.14", "3.14" },
+	{ ".25", "0.25" },
+	{ "-23.1", "-23.1" },
+	{ "-18.5", "-18.5" },
+	{ "5.25 + 5.25 + 5.25 + 5.50 - 10", "11.25" },
+	{ "2.5 * 2.5 * 2.5 * 2", "31.25" },
+	{ "-50 + 100 + -50.50", "-0.5" },
+	{ "5 * 2.5 + 10", "22.5" },
+	{ "5 + 2 * 10.25", "25.5" },
+	{ "50 / 2 * 2 + 10.5", "60.5" },
+	{ "10.5 % 10", "0.5" },
+	{ "2.5 * (5.5 + 10)", "38.75" },
+	{ "3 * 3 * 3 + 10.75", "37.75" },
+	{ "true", "true" },
+	{ "false", "false" },
+	{ "1 < 2", "true" },
+	{ "1 > 2", "false" },
+	{ "1 < 1", "false" },
+	{ "1 > 1", "false" },
+	{ "1 == 1", "true" },
+	{ "1 != 1", "false" },
+	{ "1 == 2", "false" },
+	{ "1 != 2", "true" },
+	{ "1 >= 0", "true" },
+	{ "2 <= 1", "false" },
+	{ "true == true", "true" },
+	{ "false == false", "true" },
+	{ "true == false", "false" },
+	{ "true != false", "true" },
+	{ "false != true", "true" },
+	{ "(1 < 2) == true", "true" },
+	{ "(1 < 2) == false", "false" },
+	{ "(1 > 2) == true", "false" },
+	{ "(1 > 2) == false", "true" },
+	{ "!true", "false" },
+	{ "!false", "true" },
+	{ "!5", "false" },
+	{ "!0", "true" },
+	{ "!!true", "true" },
+	{ "!!false", "false" },
+	{ "!!5", "true" },
+	{ "2 ** 10", "1024" },
+	{ "2 ** 3 ** 2", "512" },
+	{ "(2 ** 3) ** 2", "64" },
+	{ "5 ** 0", "1" },
+	{ "0 ** 0", "1" },
+	{ "-2 ** 2", "-4" },
+	{ "(-2) ** 3", "-8" },
+	{ "2 * 3 ** 2", "18" },
+	{ "3 ** 39", "4052555153018976267" },
+	{ "2 ** 62", "4611686018427387904" },
+	{ "(-2) ** 63", "-9223372036854775808" },
+	{ "2 ** -1", "0.5" },
+	{ "2 ** -2", "0.25" },
+	{ "2.5 ** 2", "6.25" },
+	{ "6.25 ** 0.5", "2.5" },
+	{ "2 ** 63", "ERROR: integer overflow: 2 ** 63 (line 1, column 3)" },
+	{ "10 ** 19", "ERROR: integer overflow: 10 ** 19 (line 1, column 4)" },
+	{ "3 ** 40 + 1", "ERROR: integer overflow: 3 ** 40 (line 1, column 3)" },
+	{ "-(7 ** 100)", "ERROR: integer overflow: 7 ** 100 (line 1, column 5)" },
+	{ "12 & 10", "8" },
+	{ "12 | 10", "14" },
+	{ "12 ^ 10", "6" },
+	{ "~0", "-1" },
+	{ "~5", "-6" },
+	{ "1 << 4", "16" },
+	{ "256 >> 4", "16" },
+	{ "-16 >> 2", "-4" },
+	{ "1 << 2 + 1", "8" },
+	{ "1 | 2 ^ 3 & 4", "3" },
+	{ "1 << 64", "0" },
+	{ "1.5 & 1", "ERROR: unsupported operand types for &: FLOAT and INTEGER (line 1, column 5)" },
+	{ "1 | true", "ERROR: unsupported operand types for |: INTEGER and BOOLEAN (line 1, column 3)" },
+	{ "true ^ false", "ERROR: unsupported operand types for ^: BOOLEAN and BOOLEAN (line 1, column 6)" },
+	{ "2.0 << 1", "ERROR: unsupported operand types for <<: FLOAT and INTEGER (line 1, column 5)" },
+	{ "~1.5", "ERROR: unsupported operand type for ~: FLOAT (line 1, column 1)" },
+	{ "~true", "ERROR: unsupported operand type for ~: BOOLEAN (line 1, column 1)" },
+	{ "1 >> -1", "ERROR: negative shift count: -1 (line 1, column 3)" },
+	{ "6 & 3 == 3", "ERROR: unsupported operand types for &: INTEGER and BOOLEAN (line 1, column 3)" },
+	{ "let a = 5; a;", "5" },
+	{ "let a = 5 * 5; a;", "25" },
+	{ "let a = 5; let b = a; b;", "5" },
+	{ "let a = 5; let b = a; let c = a + b + 5; c;", "15" },
+	{ "const A = 2; A ** 3;", "8" },
+	{ "foobar", "ERROR: identifier not found: foobar (line 1, column 1)" },
+	{ "let a = b;", "ERROR: identifier not found: b (line 1, column 9)" },
+	{ "const A = 1; let A = 2;", "ERROR: cannot redeclare constant: A (line 1, column 18)" },
+	{ "let i = 0; i++;", "0" },
+	{ "let i = 0; i++; i;", "1" },
+	{ "let i = 0; ++i;", "1" },
+	{ "let i = 0; ++i; i;", "1" },
+	{ "let i = 5; i--;", "5" },
+	{ "let i = 5; i--; i;", "4" },
+	{ "let i = 5; --i;", "4" },
+	{ "let i = 1; i++ + i++;", "3" },
+	{ "let i = 1; ++i * ++i;", "6" },
+	{ "let i = 1; -i++;", "-1" },
+	{ "i++", "ERROR: identifier not found: i (line 1, column 1)" },
+	{ "const I = 1; I++;", "ERROR: cannot assign to constant: I (line 1, column 15)" },
+	{ "let b = true; --b;", "ERROR: unsupported operand type for --: BOOLEAN (line 1, column 15)" },
+	{ `"Hello World!"`, "Hello World!" },
+	{ `"Hello" + " " + "World!"`, "Hello World!" },
+	{ `"a" == "a"`, "true" },
+	{ `"a" != "a"`, "false" },
+	{ `"a" - "b"`, "ERROR: unsupported operand types for -: STRING and STRING (line 1, column 5)" },
+	{ `"a" + 1`, "ERROR: unsupported operand types for +: STRING and INTEGER (line 1, column 5)" },
+	{ `[1] + [2]`, "ERROR: unsupported operand types for +: ARRAY and ARRAY (line 1, column 5)" },
+	{ "[1, 2, 3][0]", "1" },
+	{ "[1, 2, 3][2]", "3" },
+	{ "let i = 0; [1][i];", "1" },
+	{ "[1, 2, 3][1 + 1];", "3" },
+	{ "let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", "6" },
+	{ "[1, 2, 3][-1]", "3" },
+	{ "let a = [1, 2, 3]; a[1]++; a[1];", "3" },
+	{ "let a = [1, 2, 3]; --a[-1];", "2" },
+	{ `"abc"[1]`, "b" },
+	{ `"abc"[-3]`, "a" },
+	{ "[1, 2, 3][3]", "ERROR: index out of range: 3 with length 3 (line 1, column 10)" },
+	{ "let a = [1];\na[-2]", "ERROR: index out of range: -2 with length 1 (line 2, column 2)" },
+	{ `"abc"[true]`, "ERROR: index must be an integer, got BOOLEAN (line 1, column 6)" },
+	{ "5[0]", "ERROR: index operator not supported: INTEGER (line 1, column 2)" },
+	{ `let s = "abc"; s[0]++;`, "ERROR: cannot assign to an index of STRING (line 1, column 17)" },
+	{ "[1, 2, 3, 4, 5][1:3]", "[2, 3]" },
+	{ "[1, 2, 3, 4, 5][:2]", "[1, 2]" },
+	{ "[1, 2, 3, 4, 5][3:]", "[4, 5]" },
+	{ "[1, 2, 3, 4, 5][:]", "[1, 2, 3, 4, 5]" },
+	{ "[1, 2, 3, 4, 5][::-1]", "[5, 4, 3, 2, 1]" },
+	{ "[1, 2, 3, 4, 5][::2]", "[1, 3, 5]" },
+	{ "[1, 2, 3, 4, 5][-2:]", "[4, 5]" },
+	{ "[1, 2, 3, 4, 5][3:1]", "[]" },
+	{ "[1, 2, 3, 4, 5][3:1:-1]", "[4, 3]" },
+	{ "[1, 2, 3, 4, 5][5::-2]", "[5, 3, 1]" },
+	{ "[][::-1]", "[]" },
+	{ `"Hello World"[:5]`, "Hello" },
+	{ `"Hello World"[6:]`, "World" },
+	{ `"abc"[::-1]`, "cba" },
+	{ `"abcdef"[1::2]`, "bdf" },
+	{ `"abc"[2:1]`, "" },
+	{ `"héllo"[1:3]`, "él" },
+	{ `"héllo"[::-1]`, "olléh" },
+	{ `"héllo"[-4]`, "é" },
+	{ "[1, 2][0:3]", "ERROR: slice bounds out of range: 3 with length 2 (line 1, column 7)" },
+	{ "[1, 2][-3:]", "ERROR: slice bounds out of range: -3 with length 2 (line 1, column 7)" },
+	{ `"ab"[::0]`, "ERROR: slice step cannot be zero (line 1, column 5)" },
+	{ `"ab"[1.5:]`, "ERROR: slice bounds must be integers, got FLOAT (line 1, column 5)" },
+	{ "5[1:]", "ERROR: slice operator not supported: INTEGER (line 1, column 2)" },
+	{ "1..10", "1..10" },
+	{ "1..=10", "1..=10" },
+	{ "let n = 3; 0..n * 2", "0..6" },
+	{ "5..1", "5..1" },
+	{ "1..=1", "1..=1" },
+	{ "let n = 0; for i in 0..10 { n++; } n;", "10" },
+	{ "let n = 0; for i in 1..=10 { let n = n; } n;", "0" },
+	{ "let n = 0; for (x in [1, 2, 3]) { for y in 0..x { n++; } } n;", "6" },
+	{ "let n = 0; for c in \"abc\" { n++; } n;", "3" },
+	{ "fn f() {} let y = f(); y + 1", "1" },
+	{ "fn f() { let x = 1; } [f()]", "[null]" },
+	{ "fn f() { for i in 0..2 { i } } [f(), f()]", "[null, null]" },
+	{ "fn f() {} [f()].map(fn(v) { v })", "[null]" },
+	{ "fn find(s) { for c in s { if c == \"é\" { return c } } } find(\"héllo\")", "é" },
+	{ "let a = [1, 2]; for i in 0..2 { a[i]++; } a[0] + a[1];", "5" },
+	{ "for i in 5 { }", "ERROR: cannot iterate over INTEGER (line 1, column 1)" },
+	{ "for i in 0..3 { i[0]; }", "ERROR: index operator not supported: INTEGER (line 1, column 18)" },
+	{ "for i in 0..3 { } i;", "ERROR: identifier not found: i (line 1, column 19)" },
+	{ "if (true) { 10 }", "10" },
+	{ "if (false) { 10 }", "null" },
+	{ "if (1) { 10 }", "10" },
+	{ "if (0) { 10 } else { 20 }", "20" },
+	{ "if (1 < 2) { 10 }", "10" },
+	{ "if 1 > 2 { 10 } else { 20 }", "20" },
+	{ "let x = 5; if x > 10 { 1 } else if x > 3 { 2 } else { 3 }", "2" },
+	{ "let x = 0; if x > 10 { 1 } else if x > 3 { 2 } else { 3 }", "3" },
+	{ `if "" { 1 } else { 2 }`, "1" },
+	{ "if (if (false) { 1 }) { 1 } else { 2 }", "2" },
+	{ "let x = 1; if true { let x = 2; } x", "1" },
+	{ "fn add(x, y) { x + y }; add(1, 2);", "3" },
+	{ "let r = double(4); fn double(x) { x * 2 } r;", "8" },
+	{ "fn fact(n) { if n == 0 { 1 } else { n * fact(n - 1) } } fact(10);", "3628800" },
+	{ `
+let r = isEven(10);
+fn isEven(n) { if n == 0 { true } else { isOdd(n - 1) } }
+fn isOdd(n) { if n == 0 { false } else { isEven(n - 1) } }
+r
+`, "true" },
+	{ `
+fn outer() {
+	let r = inner(2);
+	fn inner(x) { x + base }
+	let base = 40;
+	r
+}
+outer();
+`, "ERROR: identifier not found: base (line 4, column 20)" },
+	{ `
+fn outer() {
+	fn inner(x) { x + base }
+	let base = 40;
+	inner(2)
+}
+outer();
+`, "42" },
+	{ "fn outer() { fn inner() { 1 } inner } outer(); inner;", "ERROR: identifier not found: inner (line 1, column 48)" },
+	{ "let x = f; fn f() { 1 } x();", "1" },
+	{ "let identity = fn(x) { x; }; identity(5);", "5" },
+	{ "let identity = fn(x) { return x; }; identity(5);", "5" },
+	{ "let double = fn(x) { x * 2; }; double(5);", "10" },
+	{ "let add = fn(x, y) { x + y; }; add(5, 5);", "10" },
+	{ "let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", "20" },
+	{ "fn(x) { x; }(5)", "5" },
+	{ "let f = fn() { return 1; 2; }; f();", "1" },
+	{ "let f = fn() { for i in 0..10 { return i + 7; } }; f();", "7" },
+	{ "let newAdder = fn(x) { fn(y) { x + y } }; let addTwo = newAdder(2); addTwo(3);", "5" },
+	{ "let x = 10; let f = fn() { x++; }; f(); f(); x;", "12" },
+	{ "let f = fn(x, y = 10) { x + y }; f(1);", "11" },
+	{ "let f = fn(x, y = 10) { x + y }; f(1, 2);", "3" },
+	{ "let f = fn(x, y = x * 2) { y }; f(4);", "8" },
+	{ "let f = fn(first, ...rest) { rest }; f(1, 2, 3);", "[2, 3]" },
+	{ "let f = fn(first, ...rest) { rest }; f(1);", "[]" },
+	{ "let f = fn(x, y, z) { [x, y, z] }; let args = [1, 2]; f(...args, 3);", "[1, 2, 3]" },
+	{ "let f = fn(...all) { all }; f(0, ...[1, 2], ...[], 3);", "[0, 1, 2, 3]" },
+	{ "let f = fn(x, y = 2, z = 3) { [x, y, z] }; f(1, z: 30);", "[1, 2, 30]" },
+	{ "let f = fn(x, y) { x - y }; f(y: 1, x: 5);", "4" },
+	{ "let f = fn(x, y = 2, ...r) { [x, y, r] }; f(1, 2, 3, 4);", "[1, 2, [3, 4]]" },
+	{ "let f = fn(x) { x }; f();", "ERROR: missing argument for parameter x (line 1, column 23)" },
+	{ "let f = fn(x) { x }; f(1, 2);", "ERROR: too many arguments: want at most 1, got 2 (line 1, column 23)" },
+	{ "let f = fn(x) { x }; f(1, x: 2);", "ERROR: got multiple values for parameter x (line 1, column 23)" },
+	{ "let f = fn(x) { x }; f(y: 2, x: 1);", "ERROR: unknown parameter name: y (line 1, column 23)" },
+	{ "let f = fn(...r) { r }; f(r: 1);", "ERROR: cannot pass rest parameter r by name (line 1, column 26)" },
+	{ "let f = fn(x) { x }; f(...1);", "ERROR: cannot spread INTEGER, expected an ARRAY (line 1, column 24)" },
+	{ "let f = fn(x = y) { x }; f();", "ERROR: identifier not found: y (line 1, column 16)" },
+	{ "5(1)", "ERROR: not a function: INTEGER (line 1, column 2)" },
+	{ "let f = fn() { g() }; f();", "ERROR: identifier not found: g (line 1, column 16)" },
+	{ "fn fact(n) { if n == 0 { 1 } else { n * fact(n - 1) } } fact(20);", "2432902008176640000" },
+	{ "fn f(n) { if n == 0 { g() } else { f(n - 1) } } f(10);", "ERROR: identifier not found: g (line 1, column 23)" },
+	{ "fn f(n) { return f(n - 1, 2) } f(1);", "ERROR: too many arguments: want at most 1, got 2 (line 1, column 19)" },
+	{ "let x = 1; fn f() { x() } f();", "ERROR: not a function: INTEGER (line 1, column 22)" },
+	{ "return (fn(x) { x })(7, 8);", "ERROR: too many arguments: want at most 1, got 2 (line 1, column 21)" },
+	{ "let a = 1;\n  \"a\" - 1", "ERROR: unsupported operand types for -: STRING and INTEGER (line 2, column 7)" },
+	{ "let s = \"a\"; s++", "ERROR: unsupported operand type for ++: STRING (line 1, column 15)" },
+	{ "undefined", "ERROR: identifier not found: undefined (line 1, column 1)" },
+	{ "fn f(n) { if n == 0 { 0 } else { 1 + f(n - 1) } } f(49);", "49" },
+	{ "fn f(n) { if n == 0 { 0 } else { 1 + f(n - 1) } } f(50);", "50" },
+	{ "fn countdown(n) { if n == 0 { 0 } else { countdown(n - 1) } } countdown(1000);", "0" },
+	{ `try { throw "boom" } catch (e) { e["message"] }`, "boom" },
+	{ `try { throw 42 } catch (e) { e["message"] }`, "42" },
+	{ `try { 1 + missing } catch (e) { e["message"] }`, "identifier not found: missing" },
+	{ `try { 1 + missing } catch (e) { e["kind"] }`, "Error" },
+	{ `try { [1][5] } catch { "caught" }`, "caught" },
+	{ `try { 1 } catch (e) { 2 }`, "1" },
+	{ `let n = 0; fn f() { try { return 1 } finally { n++ } } f() + n * 10`, "11" },
+	{ `fn f() { try { return 1 } finally { return 2 } } f()`, "2" },
+	{ `fn f() { try { throw "a" } catch (e) { return e["message"] } finally { "ignored" } } f()`, "a" },
+	{ `fn f() { try { throw "a" } finally { return "b" } } f()`, "b" },
+	{ `
+let n = 0;
+fn f() { try { missing } finally { n++ } }
+fn g() { try { f() } catch { n } }
+g()
+`, "1" },
+	{ `
+try {
+	try { throw "inner" } catch (e) { throw e }
+} catch (e) {
+	e["message"]
+}
+`, "inner" },
+	{ `fn bad() { missing } fn f() { try { return bad() } catch (e) { "caught" } } f()`, "caught" },
+	{ `fn f() { try { 1 } catch { 2 } } f()`, "1" },
+	{ `throw "boom"`, "ERROR: boom (line 1, column 1)" },
+	{ `fn f() { throw "boom" } try { f() } finally { 1 }`, "ERROR: boom (line 1, column 10)" },
+	{ `try { 1 } finally { throw "from finally" }`, "ERROR: from finally (line 1, column 21)" },
+	{ `try { throw "a" } catch (e) { throw "b" }`, "ERROR: b (line 1, column 31)" },
+	{ `try { throw "a" } catch (e) { e[0] }`, "ERROR: error field must be a string, got INTEGER (line 1, column 32)" },
+	{ `try { throw "a" } catch (e) { e["foo"] }`, "ERROR: unknown error field: foo (line 1, column 32)" },
+	{ `let x = 1; x.y`, "ERROR: INTEGER has no member y (line 1, column 14)" },
+	{ `let h = { "a": 1, "b": { "c": 2 } }; h.a + h.b.c`, "3" },
+	{ `let h = { "a": 1 }; h["a"]`, "1" },
+	{ `let h = { "a": 1 }; h.missing`, "null" },
+	{ `{ 1: "one" }[1]`, "one" },
+	{ `{ "f": fn(x) { x * 2 } }.f(21)`, "42" },
+	{ `"abc".upper()`, "ABC" },
+	{ `let up = "abc".upper; up()`, "ABC" },
+	{ `[1, 2, 3].map(fn(x) { x * 10 })[2]`, "30" },
+	{ `let k = 3; [1, 2].map(fn(x) { x + k }).map(fn(x) { x * 2 })[1]`, "10" },
+	{ `["a", "b"].map(fn(s) { s.upper() })[1]`, "B" },
+	{ `try { throw "boom" } catch (e) { e.message }`, "boom" },
+	{ `try { [1].map(fn(x) { throw "inside" }) } catch (e) { e.message }`, "inside" },
+	{ `let x = 1; x.upper()`, "ERROR: INTEGER has no member upper (line 1, column 14)" },
+	{ `"abc".nope`, "ERROR: STRING has no member nope (line 1, column 7)" },
+	{ `"abc".upper(1)`, "ERROR: wrong number of arguments for upper: want 0, got 1 (line 1, column 12)" },
+	{ `[1].map(1)`, "ERROR: not a function: INTEGER (line 1, column 8)" },
+	{ `[1].map(f: 1)`, "ERROR: method map does not accept named arguments (line 1, column 8)" },
+	{ `[1].map(fn(x, y) { x + y })`, "ERROR: missing argument for parameter y (line 1, column 8)" },
+	{ `{ [1]: 2 }`, "ERROR: unusable as hash key: ARRAY (line 1, column 1)" },
+	{ `{ "a": 1 }[[1]]`, "ERROR: unusable as hash key: ARRAY (line 1, column 11)" },
+	{ `try { throw "a" } catch (e) { e.nope }`, "ERROR: unknown error field: nope (line 1, column 33)" },
+	{ `"a,b,c".split(",").join("-")`, "a-b-c" },
+	{ `"abc".split("").join(" ")`, "a b c" },
+	{ `"  hi \t".trim()`, "hi" },
+	{ `"AbC".lower()`, "abc" },
+	{ `"monkey".contains("key")`, "true" },
+	{ `"monkey".contains("ape")`, "false" },
+	{ `"a-b-a".replace("a", "o")`, "o-b-o" },
+	{ `"monkey".startsWith("mon")`, "true" },
+	{ `"monkey".startsWith("key")`, "false" },
+	{ `[1, 2, 3, 4].filter(fn(x) { x % 2 == 0 }).join(",")`, "2,4" },
+	{ `[1, 2, 3, 4].reduce(fn(acc, x) { acc + x })`, "10" },
+	{ `[1, 2, 3].reduce(fn(acc, x) { acc + x }, 10)`, "16" },
+	{ `[].reduce(fn(acc, x) { acc + x }, 0)`, "0" },
+	{ `[3, 1.5, 2].sort().join(" ")`, "1.5 2 3" },
+	{ `["b", "c", "a"].sort().join("")`, "abc" },
+	{ `[1, 3, 2].sort(fn(a, b) { b - a }).join("")`, "321" },
+	{ `let a = [2, 1]; a.sort(); a.join("")`, "21" },
+	{ `[1, "a", true].join("|")`, "1|a|true" },
+	{ `[1, 2, 3].indexOf(2)`, "1" },
+	{ `[1, 2, 3].indexOf(2.0)`, "1" },
+	{ `["a"].indexOf("b")`, "-1" },
+	{ `{ "b": 1, "a": 2 }.keys().join(",")`, "b,a" },
+	{ `{ "b": 1, "a": 2 }.values().join(",")`, "1,2" },
+	{ `{ 1: "one" }.has(1)`, "true" },
+	{ `{ 1: "one" }.has("1")`, "false" },
+	{ `let h = { "a": 1, "b": 2 }; h.delete("a"); h.keys().join(",")`, "b" },
+	{ `{ "a": 1 }.delete("b")`, "false" },
+	{ `let n = 2; [1, 2, 3].map(fn(x) { x * n }).filter(fn(x) { x > 2 }).reduce(fn(a, b) { a + b })`, "10" },
+	{ `"abc".split(1)`, "ERROR: argument 1 of split must be a STRING, got INTEGER (line 1, column 12)" },
+	{ `"abc".replace("a")`, "ERROR: wrong number of arguments for replace: want 2, got 1 (line 1, column 14)" },
+	{ `[].reduce(fn(a, b) { a + b })`, "ERROR: reduce of an empty array without initial value (line 1, column 10)" },
+	{ `[1, "a"].sort()`, "ERROR: cannot compare STRING and INTEGER (line 1, column 14)" },
+	{ `[1, 2].sort(fn(a, b) { "a" })`, "ERROR: sort comparison must return a number, got STRING (line 1, column 12)" },
+	{ `[1, 2].sort(fn(a, b) { x })`, "ERROR: identifier not found: x (line 1, column 24)" },
+	{ `[1, 2].filter(fn(x) { throw "bad element" })`, "ERROR: bad element (line 1, column 23)" },
+	{ `{}.has([1])`, "ERROR: unusable as hash key: ARRAY (line 1, column 7)" },
+}
+
+// DEEP_SNIPPETS are the snippets of the tests of the evaluator going
+// through long recursions, huge ranges or up to the call depth limit,
+// too slow to be run in every configuration of an engine.
+var DEEP_SNIPPETS = []Snippet{
+	{ "let n = 0; for i in 1000000000..0 { n++; } n;", "0" },
+	{ "fn countdown(n) { if n == 0 { 0 } else { countdown(n - 1) } } countdown(1000000);", "0" },
+	{ "fn countdown(n) { if n == 0 { return 0 } return countdown(n - 1) } countdown(100000);", "0" },
+	{ "fn sum(n, acc = 0) { if n == 0 { acc } else { sum(n - 1, acc: acc + n) } } sum(100000);", "5000050000" },
+	{ `
+fn isEven(n) { if n == 0 { true } else { isOdd(n - 1) } }
+fn isOdd(n) { if n == 0 { false } else { isEven(n - 1) } }
+isEven(1000000)
+`, "true" },
+	{ "fn loop(n) { for i in 0..1 { if n == 0 { return \"done\" } return loop(n - 1) } } loop(100000);", "done" },
+	{ `fn f() { f() + 1 } try { f() } catch (e) { e["kind"] }`, "StackOverflowError" },
+}
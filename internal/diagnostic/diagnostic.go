@@ -0,0 +1,81 @@
+package diagnostic
+
+import (
+	"encoding/json"
+	"fmt"
+	"monkey/internal/token"
+	"sort"
+)
+
+
+// Severity tell whether a diagnostic prevent running the program.
+type Severity string
+
+const (
+	ERROR	Severity = "error"
+	WARNING	Severity = "warning"
+)
+
+// Diagnostic is a problem found in a program before running it,
+// located at the token it's about.
+type Diagnostic struct {
+	Severity	Severity
+	Message		string
+	Token		token.Token
+}
+
+func (d Diagnostic) String() string {
+	file := d.Token.File
+
+	if file == "" {
+		file = "<input>"
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s: %s", file, d.Token.Line, d.Token.Column, d.Severity, d.Message)
+}
+
+// MarshalJSON encode a diagnostic for the tools reading them, like
+// an editor, with its position split in file, line and column.
+func (d Diagnostic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct{
+		File		string		`json:"file"`
+		Line		int			`json:"line"`
+		Column		int			`json:"column"`
+		Severity	Severity	`json:"severity"`
+		Message		string		`json:"message"`
+	}{ d.Token.File, d.Token.Line, d.Token.Column, d.Severity, d.Message })
+}
+
+// Errorf return an error diagnostic located at tok.
+func Errorf(tok token.Token, format string, args ...any) Diagnostic {
+	return Diagnostic{ Severity: ERROR, Message: fmt.Sprintf(format, args...), Token: tok }
+}
+
+// Warningf return a warning diagnostic located at tok.
+func Warningf(tok token.Token, format string, args ...any) Diagnostic {
+	return Diagnostic{ Severity: WARNING, Message: fmt.Sprintf(format, args...), Token: tok }
+}
+
+// HasErrors report whether some of diagnostics are errors.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == ERROR {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sort put diagnostics in the order of the source, keeping
+// the order of the ones at the same position.
+func Sort(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Token, diagnostics[j].Token
+
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
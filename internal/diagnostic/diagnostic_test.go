@@ -0,0 +1,79 @@
+package diagnostic
+
+import (
+	"encoding/json"
+	"monkey/internal/token"
+	"slices"
+	"testing"
+)
+
+
+func TestDiagnostic(t *testing.T) {
+
+	t.Run("it should locate diagnostics in their file", func(t *testing.T) {
+		tests := []struct{
+			diagnostic	Diagnostic
+			expected	string
+		}{
+			{ Errorf(token.Token{ File: "main.mk", Line: 3, Column: 7 }, "undefined name: %s", "x"), "main.mk:3:7: error: undefined name: x" },
+			{ Warningf(token.Token{ Line: 1, Column: 2 }, "x shadows a global"), "<input>:1:2: warning: x shadows a global" },
+		}
+
+		for i, tt := range tests {
+			if got := tt.diagnostic.String(); got != tt.expected {
+				t.Fatalf("[test #%d]: Expecting %q, but got %q\n", i, tt.expected, got)
+			}
+		}
+	})
+
+	t.Run("it should encode diagnostics in JSON", func(t *testing.T) {
+		diagnostics := []Diagnostic{
+			Warningf(token.Token{ File: "main.mk", Line: 2, Column: 5 }, "x is declared but never used"),
+			Errorf(token.Token{ Line: 1, Column: 1 }, "undefined name: %s", "y"),
+		}
+		expected := `[{"file":"main.mk","line":2,"column":5,"severity":"warning","message":"x is declared but never used"},` +
+			`{"file":"","line":1,"column":1,"severity":"error","message":"undefined name: y"}]`
+
+		got, err := json.Marshal(diagnostics)
+
+		if err != nil {
+			t.Fatalf("Expecting no error, but got %s\n", err)
+		}
+
+		if string(got) != expected {
+			t.Fatalf("Expecting %s, but got %s\n", expected, got)
+		}
+	})
+
+	t.Run("it should tell whether diagnostics hold errors", func(t *testing.T) {
+		warning := Warningf(token.Token{}, "warning")
+
+		if HasErrors([]Diagnostic{ warning }) {
+			t.Fatalf("Expecting a warning not to be an error\n")
+		}
+
+		if !HasErrors([]Diagnostic{ warning, Errorf(token.Token{}, "error") }) {
+			t.Fatalf("Expecting an error to be found\n")
+		}
+	})
+
+	t.Run("it should sort diagnostics by position", func(t *testing.T) {
+		diagnostics := []Diagnostic{
+			Errorf(token.Token{ Line: 2, Column: 1 }, "c"),
+			Errorf(token.Token{ Line: 1, Column: 5 }, "b"),
+			Warningf(token.Token{ Line: 1, Column: 1 }, "a"),
+			Errorf(token.Token{ Line: 2, Column: 1 }, "d"),
+		}
+		Sort(diagnostics)
+
+		got := []string{}
+
+		for _, diagnostic := range diagnostics {
+			got = append(got, diagnostic.Message)
+		}
+
+		if !slices.Equal(got, []string{ "a", "b", "c", "d" }) {
+			t.Fatalf("Expecting [a b c d], but got %v\n", got)
+		}
+	})
+}
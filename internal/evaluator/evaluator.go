@@ -1,241 +1,1058 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
-	"math"
+	"maps"
 	"monkey/internal/ast"
+	"monkey/internal/module"
 	"monkey/internal/object"
+	"monkey/internal/token"
 	"slices"
-	"strconv"
 )
 
 
 var (
-	NULL	= &object.Null{}
-	TRUE 	= &object.Boolean{ Value: true }
-	FALSE	= &object.Boolean{ Value: false }
+	NULL	= object.NULL
+	TRUE 	= object.TRUE
+	FALSE	= object.FALSE
 )
 
-var booleanOperators = []string{ "==", "!=", "<", ">", "<=", ">=" }
+// DEFAULT_MAX_CALL_DEPTH is the number of nested calls a program can make
+// before its evaluation fail with a stack overflow error. It's well under
+// what the Go stack can hold, so a runaway recursion never crash the process.
+const DEFAULT_MAX_CALL_DEPTH = 10000
 
+// Rough sizes, in bytes, used to account for the memory allocated
+// by a program.
+const (
+	OBJECT_SIZE			= 16
+	ENVIRONMENT_SIZE	= 64
+)
+
+
+// Evaluator hold the state of the evaluation of a program, like the
+// current call depth, and the limits it must run within. An evaluator
+// must not be used by several goroutines at once.
+//
+// The budgets are checked at each loop iteration and each function call,
+// where a runaway program spend its time. Once one is exceeded, the
+// evaluation stop with a BudgetExceededError.
+type Evaluator struct {
+	MaxCallDepth		int // maximum number of nested calls
+	MaxSteps			int64 // maximum number of evaluated nodes, loop iterations and calls, 0 for no limit
+	MaxAllocatedBytes	int64 // maximum number of bytes allocated, 0 for no limit
+	ModulePath			[]string // directories where imported files are looked up
+
+	ctx					context.Context
+	depth				int
+	steps				int64
+	allocated			int64
+	literals			map[ast.Node]object.Object // objects of the number literals
+	modules				module.Loader
+}
 
-func Eval(node ast.Node) object.Object {
+func New() *Evaluator {
+	return &Evaluator{
+		MaxCallDepth: DEFAULT_MAX_CALL_DEPTH,
+		ctx: context.Background(),
+	}
+}
+
+// Eval evaluate node with a new evaluator using the default settings.
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(node, env)
+}
+
+// EvalContext evaluate node like Eval, but stop with a BudgetExceededError
+// as soon as ctx is cancelled or its deadline is passed.
+func (ev *Evaluator) EvalContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	previous := ev.ctx
+	ev.ctx = ctx
+	defer func() { ev.ctx = previous }()
+
+	return ev.Eval(node, env)
+}
+
+func (ev *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	ev.steps++
 
 	switch node := node.(type) {
 	
 	case *ast.Program:
-		return evalStatement(node.Statements)
+		return ev.evalStatement(node.Statements, env)
 
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression)
+		return ev.Eval(node.Expression, env)
 
-	case *ast.IntegerLiteral:
-		return &object.Integer{ Value: node.Value }
+	case *ast.BlockStatement:
+		return ev.evalBlockStatement(node, env)
 
-	case *ast.FloatLiteral:
-		return &object.Float{ Value: node.Value }
+	case *ast.ReturnStatement:
+		value := ev.evalTail(node.ReturnValue, env)
+
+		if isError(value) {
+			return value
+		}
+		return &object.ReturnValue{ Value: value }
+
+	case *ast.DeclarationStatement:
+		return ev.evalDeclarationStatement(node, env)
+
+	case *ast.Identifier:
+		return evalIdentifier(node, env)
+
+	case *ast.IntegerLiteral, *ast.FloatLiteral:
+		return ev.evalNumberLiteral(node)
 
 	case *ast.Boolean:
-		return evalToNativeBool(node.Value)
+		return object.NativeBool(node.Value)
+
+	case *ast.StringLiteral:
+		return ev.allocate(&object.String{ Value: node.Value })
+
+	case *ast.HashLiteral:
+		return ev.evalHashLiteral(node, env)
+
+	case *ast.ArrayLiteral:
+		elements := ev.evalExpressions(node.Elements, env)
+
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return ev.allocate(&object.Array{ Elements: elements })
+
+	case *ast.IndexExpression:
+		return ev.evalIndexExpression(node, env)
+
+	case *ast.SliceExpression:
+		return ev.allocate(ev.evalSliceExpression(node, env))
+
+	case *ast.RangeExpression:
+		return ev.evalRangeExpression(node, env)
+
+	case *ast.ForInStatement:
+		return ev.evalForInStatement(node, env)
+
+	case *ast.ImportStatement:
+		return ev.evalImportStatement(node, env)
+
+	case *ast.ExportStatement:
+		return ev.Eval(node.Statement, env)
+
+	case *ast.MemberExpression:
+		return ev.evalMemberExpression(node, env)
+
+	case *ast.ThrowStatement:
+		return ev.evalThrowStatement(node, env)
+
+	case *ast.TryStatement:
+		return ev.evalTryStatement(node, env)
+
+	case *ast.FunctionLiteral:
+		return newFunction(node, env)
+
+	case *ast.FunctionDeclaration:
+		// Already bound when its block was hoisted.
+		return nil
+
+	case *ast.IfElseExpression:
+		return ev.evalIfElseExpression(node, env)
+
+	case *ast.FunctionCallExpression:
+		return ev.evalFunctionCall(node, env)
 
 	case *ast.PrefixExpression:
-		right := Eval(node.Right)
-		return evaluatePrefixExpression(node.Operator, right)
+		if node.Operator == "++" || node.Operator == "--" {
+			return locateError(ev.evalUpdateExpression(node.Operator, node.Right, true, env), node.Token)
+		}
+		right := ev.Eval(node.Right, env)
+
+		if isError(right) {
+			return right
+		}
+		return locateError(object.Prefix(node.Operator, right), node.Token)
+
+	case *ast.PostfixExpression:
+		return locateError(ev.evalUpdateExpression(node.Operator, node.Left, false, env), node.Token)
 
 	case *ast.InfixExpression:
-		left := Eval(node.Left)
-		right := Eval(node.Right)
-		return evaluateInfixExpression(node.Operator, left, right)
+		left := ev.Eval(node.Left, env)
+
+		if isError(left) {
+			return left
+		}
+		right := ev.Eval(node.Right, env)
+
+		if isError(right) {
+			return right
+		}
+		return ev.allocate(locateError(object.Infix(node.Operator, left, right), node.Token))
 	}
 
 	return nil
 }
 
 
-func evalStatement(statements []ast.Statement) object.Object {
+func (ev *Evaluator) evalStatement(statements []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
+	hoistFunctionDeclarations(statements, env)
+
 	for _, stmt := range statements {
-		result = Eval(stmt)
+		result = ev.Eval(stmt, env)
+
+		switch result := result.(type) {
+
+		case *object.ReturnValue:
+			if call, ok := result.Value.(*tailCall); ok {
+				return ev.callFunction(call)
+			}
+			return result.Value
+
+		case *object.Error:
+			return result
+		}
+	}
+
+	return result
+}
+
+// evalBlockStatement evaluate the statements of a block. Unlike for
+// a program, a return value is passed up untouched, so it can stop
+// the evaluation of the enclosing blocks up to the function call.
+func (ev *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object
+
+	hoistFunctionDeclarations(block.Statements, env)
+
+	for _, stmt := range block.Statements {
+		result = ev.Eval(stmt, env)
+
+		if isError(result) || isReturnValue(result) {
+			return result
+		}
 	}
 
 	return result
 }
 
-func evalToNativeBool(val bool) *object.Boolean {
-	if val {
-		return TRUE
+// hoistFunctionDeclarations bind the functions declared in a list of
+// statements before any of them is evaluated, so they can call each
+// other regardless of the order they are declared in.
+func hoistFunctionDeclarations(statements []ast.Statement, env *object.Environment) {
+	for _, stmt := range statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+
+		if declaration, ok := stmt.(*ast.FunctionDeclaration); ok {
+			env.SetResolved(declaration.Name.Value, declaration.Name.Binding, newFunction(declaration.Function, env), false)
+		}
+	}
+}
+
+func newFunction(node *ast.FunctionLiteral, env *object.Environment) *object.Function {
+	return &object.Function{
+		Name: node.Name,
+		Parameters: node.Params,
+		Body: node.Body,
+		Env: env,
 	}
-	return FALSE
 }
 
-func evaluatePrefixExpression(operator string, right object.Object) object.Object {
-	if operator == "!" {
-		return evalBangOperatorExpression(right)
+// evalNumberLiteral return the object of an integer or float literal.
+// Numbers are never modified, so the object is created the first time
+// the literal is evaluated only.
+func (ev *Evaluator) evalNumberLiteral(node ast.Node) object.Object {
+	// Small integers are shared already, which is cheaper than a lookup.
+	if integer, ok := node.(*ast.IntegerLiteral); ok && integer.Value >= object.SMALL_INTEGER_MIN && integer.Value <= object.SMALL_INTEGER_MAX {
+		return object.NewInteger(integer.Value)
+	}
+
+	if obj, ok := ev.literals[node]; ok {
+		return obj
+	}
+
+	var obj object.Object
+
+	switch node := node.(type) {
+
+	case *ast.IntegerLiteral:
+		obj = object.NewInteger(node.Value)
+
+	case *ast.FloatLiteral:
+		obj = &object.Float{ Value: node.Value }
 	}
 
-	if operator == "-" {
-		return evalMinusOperatorExpression(right)
+	if ev.literals == nil {
+		ev.literals = map[ast.Node]object.Object{}
+	}
+	ev.literals[node] = obj
+
+	return obj
+}
+
+func (ev *Evaluator) evalIfElseExpression(node *ast.IfElseExpression, env *object.Environment) object.Object {
+	condition := ev.Eval(node.Condition, env)
+
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return ev.Eval(node.Consequence, object.NewScopeEnvironment(env, node.Consequence.Scope))
+	}
+
+	if node.Alternative != nil {
+		return ev.Eval(node.Alternative, object.NewScopeEnvironment(env, node.Alternative.Scope))
 	}
 
 	return NULL
 }
 
-func evalBangOperatorExpression(right object.Object) object.Object {
+func (ev *Evaluator) evalDeclarationStatement(stmt *ast.DeclarationStatement, env *object.Environment) object.Object {
+	name := stmt.Name.Value
 
-	switch right.Type() {
+	if env.IsConstantResolved(name, stmt.Name.Binding) {
+		return newErrorAt(stmt.Name.Token, "cannot redeclare constant: %s", name)
+	}
+	value := ev.Eval(stmt.Value, env)
 
-	case object.BOOLEAN_OBJ:
-		value := right.(*object.Boolean).Value
-		return evalToNativeBool(!value)
+	if isError(value) {
+		return value
+	}
 
-	case object.INTEGER_OBJ, object.FLOAT_OBJ:
-		value := getObjectNumberValue(right)
-		return evalToNativeBool(value == 0.0)
+	env.SetResolved(name, stmt.Name.Binding, value, stmt.Token.Type == token.CONST)
 
-	default:
-		return FALSE
+	return nil
+}
+
+func evalIdentifier(identifier *ast.Identifier, env *object.Environment) object.Object {
+	if value, ok := env.GetResolved(identifier.Value, identifier.Binding); ok {
+		return value
 	}
 
+	return newErrorAt(identifier.Token, "identifier not found: %s", identifier.Value)
 }
 
-func evalMinusOperatorExpression(right object.Object) object.Object {
-	
-	switch right.Type() {
+// evalExpressions evaluate expressions from left to right. On the
+// first error, it return a slice holding only that error.
+func (ev *Evaluator) evalExpressions(expressions []ast.Expression, env *object.Environment) []object.Object {
+	result := []object.Object{}
+
+	for _, expr := range expressions {
+		evaluated := ev.Eval(expr, env)
+
+		if isError(evaluated) {
+			return []object.Object{ evaluated }
+		}
+		result = append(result, evaluated)
+	}
+
+	return result
+}
+
+// evalUpdateExpression evaluate `++` and `--` applied to target.
+// The prefix form return the updated value while the postfix
+// form return the value the target had before the update.
+func (ev *Evaluator) evalUpdateExpression(operator string, target ast.Expression, prefix bool, env *object.Environment) object.Object {
+	old, store := ev.evalAssignableTarget(operator, target, env)
+
+	if isError(old) {
+		return old
+	}
+
+	updated := object.Update(operator, old)
+
+	if isError(updated) {
+		return updated
+	}
+	store(updated)
+
+	if prefix {
+		return updated
+	}
+
+	return old
+}
+
+// evalAssignableTarget return the current value of target, which is
+// an identifier or an array element, along with a function storing
+// a new value in it.
+func (ev *Evaluator) evalAssignableTarget(operator string, target ast.Expression, env *object.Environment) (object.Object, func(object.Object)) {
+
+	switch target := target.(type) {
+
+	case *ast.Identifier:
+		if env.IsConstantResolved(target.Value, target.Binding) {
+			return newError("cannot assign to constant: %s", target.Value), nil
+		}
+		value := evalIdentifier(target, env)
+
+		return value, func(updated object.Object) {
+			env.AssignResolved(target.Value, target.Binding, updated)
+		}
+
+	case *ast.IndexExpression:
+		left := ev.Eval(target.Left, env)
+
+		if isError(left) {
+			return left, nil
+		}
+		index := ev.Eval(target.Index, env)
+
+		if isError(index) {
+			return index, nil
+		}
+		array, ok := left.(*object.Array)
 
-	case object.BOOLEAN_OBJ:
-		if boolean, ok := right.(*object.Boolean); ok && boolean.Value {
-			return &object.Integer{ Value: -1 }
+		if !ok {
+			return newErrorAt(target.Token, "cannot assign to an index of %s", left.Type()), nil
 		}
-		return &object.Integer{ Value: 0 }
+		i, err := object.ResolveIndex(index, int64(len(array.Elements)))
 
-	case object.INTEGER_OBJ:
-		value := right.(*object.Integer).Value
-		return &object.Integer{ Value: -value }
+		if err != nil {
+			return locateError(err, target.Token), nil
+		}
 
-	case object.FLOAT_OBJ:
-		value := right.(*object.Float).Value
-		return &object.Float{ Value: -value }
+		return array.Elements[i], func(updated object.Object) {
+			array.Elements[i] = updated
+		}
 
 	default:
-		return NULL
+		return newError("invalid operand for %s: %s", operator, target.String()), nil
 	}
 }
 
+func (ev *Evaluator) evalIndexExpression(node *ast.IndexExpression, env *object.Environment) object.Object {
+	left := ev.Eval(node.Left, env)
+
+	if isError(left) {
+		return left
+	}
+	index := ev.Eval(node.Index, env)
 
-func evaluateInfixExpression(operator string, left, right object.Object) object.Object {
+	if isError(index) {
+		return index
+	}
 
-	// In case we're dealing with booleans
+	return locateError(object.Index(left, index), node.Token)
+}
 
-	if left.Type() == object.BOOLEAN_OBJ && right.Type() == object.BOOLEAN_OBJ {
-		switch operator {
-		case "!=":
-			return evalToNativeBool(left != right)
+// evalHashLiteral evaluate the pairs of a hash in the order they are
+// written. A key set twice keep the last value.
+func (ev *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
 
-		case "<", ">", "<=", ">=":
-			leftVal := getObjectNumberValue(left)
-			rightVal := getObjectNumberValue(right)
+	for i, keyNode := range node.Keys {
+		key := ev.Eval(keyNode, env)
 
-			return evaluateArithmeticOperatorExpression(operator, leftVal, rightVal)
+		if isError(key) {
+			return key
+		}
+		hashable, ok := key.(object.Hashable)
 
-		default:
-			return evalToNativeBool(left == right)
+		if !ok {
+			return newErrorAt(node.Token, "unusable as hash key: %s", key.Type())
+		}
+		value := ev.Eval(node.Values[i], env)
+
+		if isError(value) {
+			return value
 		}
+		hash.Set(hashable, value)
 	}
 
-	// Otherwise
+	return ev.allocate(hash)
+}
 
-	leftValue := getObjectNumberValue(left)
-	rightValue := getObjectNumberValue(right)
+// evalMemberExpression evaluate `object.member`, see object.Member.
+func (ev *Evaluator) evalMemberExpression(node *ast.MemberExpression, env *object.Environment) object.Object {
+	obj := ev.Eval(node.Object, env)
 
-	if slices.Contains(booleanOperators, operator) {
-		return evaluateLogicalOperatorExpression(operator, leftValue, rightValue)
+	if isError(obj) {
+		return obj
 	}
 
-	return evaluateArithmeticOperatorExpression(operator, leftValue, rightValue)
+	return locateError(object.Member(obj, node.Member.Value), node.Member.Token)
 }
 
+// evalSliceExpression evaluate `left[start:stop:step]`, see object.Slice.
+func (ev *Evaluator) evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := ev.Eval(node.Left, env)
 
-func evaluateArithmeticOperatorExpression(operator string, leftValue, rightValue float64) object.Object {
+	if isError(left) {
+		return left
+	}
 
-	var result float64
+	bounds := []object.Object{}
 
-	switch operator {
-	
-	case "+":
-		result = leftValue + rightValue
+	for _, bound := range []ast.Expression{ node.Start, node.Stop, node.Step } {
+		if bound == nil {
+			bounds = append(bounds, nil)
+			continue
+		}
+		evaluated := ev.Eval(bound, env)
 
-	case "-":
-		result = leftValue - rightValue
+		if isError(evaluated) {
+			return evaluated
+		}
+		bounds = append(bounds, evaluated)
+	}
 
-	case "*":
-		result = leftValue * rightValue
+	return locateError(object.Slice(left, bounds[0], bounds[1], bounds[2]), node.Token)
+}
 
-	case "/":
-		result = leftValue / rightValue
+func (ev *Evaluator) evalRangeExpression(node *ast.RangeExpression, env *object.Environment) object.Object {
+	start := ev.Eval(node.Start, env)
+
+	if isError(start) {
+		return start
+	}
+	end := ev.Eval(node.End, env)
+
+	if isError(end) {
+		return end
+	}
+
+	return locateError(object.NewRange(start, end, node.Inclusive), node.Token)
+}
+
+// evalForInStatement run the loop body once for every item of an
+// array, every one-byte string of a string or every integer of a
+// range. Each iteration get its own environment holding the item.
+func (ev *Evaluator) evalForInStatement(node *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := ev.Eval(node.Iterable, env)
+
+	if isError(iterable) {
+		return iterable
+	}
+
+	iterate := func(item object.Object) object.Object {
+		ev.steps++
+
+		if err := ev.checkBudgets(node.Token); err != nil {
+			return err
+		}
+		loopEnv := object.NewScopeEnvironment(env, node.Body.Scope)
+		loopEnv.SetResolved(node.Variable.Value, node.Variable.Binding, item, false)
+
+		return ev.evalBlockStatement(node.Body, loopEnv)
+	}
+
+	// A return value, like an error, stops the loop and
+	// is passed up to the enclosing function call.
+	stop := func(result object.Object) bool {
+		return isError(result) || isReturnValue(result)
+	}
+
+	switch iterable := iterable.(type) {
+
+	case *object.Array:
+		for _, element := range iterable.Elements {
+			if result := iterate(element); stop(result) {
+				return result
+			}
+		}
+
+	case *object.String:
+		for _, char := range iterable.Value {
+			if result := iterate(&object.String{ Value: string(char) }); stop(result) {
+				return result
+			}
+		}
+
+	case *object.Range:
+		// The integers are produced one at a time, so
+		// iterating a huge range doesn't allocate it.
+		for i := int64(0); i < iterable.Len(); i++ {
+			if result := iterate(object.NewInteger(iterable.Start + i)); stop(result) {
+				return result
+			}
+		}
 
-	case "%":
-		result = math.Mod(leftValue, rightValue)
-	
 	default:
-		return NULL
+		return newErrorAt(node.Token, "cannot iterate over %s", iterable.Type())
+	}
 
+	return nil
+}
+
+// evalThrowStatement raise the thrown value as an error, see object.Throw.
+func (ev *Evaluator) evalThrowStatement(node *ast.ThrowStatement, env *object.Environment) object.Object {
+	value := ev.Eval(node.Value, env)
+
+	if isError(value) {
+		return value
 	}
-	
-	value, err := strconv.Atoi(fmt.Sprintf("%g", result))
+
+	return locateError(object.Throw(value), node.Token)
+}
+
+// evalTryStatement run the try block, then the catch block if the try
+// block raised an error, and always the finally block last. An error or
+// a return value of the finally block replace the one pending from the
+// previous blocks. Budget errors can't be caught nor replaced by the
+// finally block, so a script can't escape the limits it's run with.
+func (ev *Evaluator) evalTryStatement(node *ast.TryStatement, env *object.Environment) object.Object {
+	result := ev.resolveTailCall(ev.evalBlockStatement(node.Block, object.NewScopeEnvironment(env, node.Block.Scope)))
+
+	if err, ok := result.(*object.Error); ok && node.Catch != nil && err.Kind != object.BUDGET_EXCEEDED_ERROR {
+		catchEnv := object.NewScopeEnvironment(env, node.Catch.Scope)
+
+		if node.CatchParam != nil {
+			catchEnv.SetResolved(node.CatchParam.Value, node.CatchParam.Binding, &object.ErrorValue{ Error: err }, false)
+		}
+		result = ev.evalBlockStatement(node.Catch, catchEnv)
+	}
+
+	if node.Finally == nil {
+		return result
+	}
+	result = ev.resolveTailCall(result)
+	finally := ev.evalBlockStatement(node.Finally, object.NewScopeEnvironment(env, node.Finally.Scope))
+
+	if err, ok := result.(*object.Error); ok && err.Kind == object.BUDGET_EXCEEDED_ERROR {
+		return err
+	}
+
+	if isError(finally) || isReturnValue(finally) {
+		return finally
+	}
+
+	return result
+}
+
+// resolveTailCall make the tail call carried by a return value, if any.
+// A `return` inside a try statement isn't in tail position: the call must
+// be made before leaving the statement, so its errors can be caught and
+// the finally block run after it.
+func (ev *Evaluator) resolveTailCall(result object.Object) object.Object {
+	returnValue, ok := result.(*object.ReturnValue)
+
+	if !ok {
+		return result
+	}
+	call, ok := returnValue.Value.(*tailCall)
+
+	if !ok {
+		return result
+	}
+	value := ev.callFunction(call)
+
+	if isError(value) {
+		return value
+	}
+
+	return &object.ReturnValue{ Value: value }
+}
+
+func (ev *Evaluator) evalFunctionCall(node *ast.FunctionCallExpression, env *object.Environment) object.Object {
+	call := ev.prepareFunctionCall(node, env)
+
+	if call, ok := call.(*tailCall); ok {
+		return ev.callFunction(call)
+	}
+
+	return call
+}
+
+// tailCall is a call whose callee and arguments are evaluated but which
+// is not made yet. Calls in tail position are passed up that way to the
+// enclosing callFunction, which make them in its loop instead of
+// recursing, so tail recursion doesn't grow the Go stack.
+type tailCall struct {
+	node		*ast.FunctionCallExpression
+	fn			*object.Function
+	positional	[]object.Object
+	named		map[string]object.Object
+}
+
+func (tc *tailCall) Type() object.ObjectType { return object.TAIL_CALL_OBJ }
+func (tc *tailCall) Inspect() string { return tc.node.String() }
+
+// prepareFunctionCall evaluate the callee and the arguments of a call,
+// returning the call to make or an error. Builtin methods don't grow
+// the Go stack of the program, so they are called right away and
+// their result is returned instead.
+func (ev *Evaluator) prepareFunctionCall(node *ast.FunctionCallExpression, env *object.Environment) object.Object {
+	function := ev.Eval(node.Function, env)
+
+	if isError(function) {
+		return function
+	}
+
+	switch function.(type) {
+
+	case *object.Function, *object.BoundMethod:
+
+	default:
+		return newErrorAt(node.Token, "not a function: %s", function.Type())
+	}
+
+	positional, named, err := ev.evalFunctionCallArguments(node, env)
+
 	if err != nil {
-		return &object.Float{ Value: result }
+		return err
+	}
+
+	if method, ok := function.(*object.BoundMethod); ok {
+		return ev.callMethod(node, method, positional, named)
 	}
 
-	return &object.Integer{ Value: int64(value) }
+	return &tailCall{ node: node, fn: function.(*object.Function), positional: positional, named: named }
 }
 
-func evaluateLogicalOperatorExpression(operator string, leftValue, rightValue float64) object.Object {
-	switch operator {
-	
-	case "==":
-		return evalToNativeBool(leftValue == rightValue)
+// callMethod call a builtin method, giving it the way to call back the
+// functions it receive. Errors it raise are located at the call, and
+// its result is charged to the allocation budget like a literal.
+func (ev *Evaluator) callMethod(node *ast.FunctionCallExpression, method *object.BoundMethod, positional []object.Object, named map[string]object.Object) object.Object {
+	if len(named) != 0 {
+		return newErrorAt(node.Token, "method %s does not accept named arguments", method.Name)
+	}
+	apply := func(fn object.Object, args ...object.Object) object.Object {
+		var result object.Object
+
+		switch fn := fn.(type) {
+
+		case *object.Function:
+			result = ev.callFunction(&tailCall{ node: node, fn: fn, positional: args, named: map[string]object.Object{} })
+
+		case *object.BoundMethod:
+			result = ev.callMethod(node, fn, args, nil)
+
+		default:
+			return newErrorAt(node.Token, "not a function: %s", fn.Type())
+		}
+
+		if result == nil {
+			return NULL
+		}
+		return result
+	}
+
+	return ev.allocate(locateError(method.Method(apply, method.Receiver, positional...), node.Token))
+}
+
+// callFunction make call, then every tail call its body end with,
+// until a body produce an actual value.
+//
+// An error raised by a body get the call added to its stack. As a tail
+// call replace the call it's made from, only the last function of a
+// chain of tail calls appear in the stack, at the site of the first call.
+func (ev *Evaluator) callFunction(call *tailCall) object.Object {
+	if ev.depth >= ev.MaxCallDepth {
+		err := newErrorAt(call.node.Token, "maximum call depth exceeded (%d)", ev.MaxCallDepth)
+		err.Kind = object.STACK_OVERFLOW_ERROR
+
+		return err
+	}
+	ev.depth++
+	defer func() { ev.depth-- }()
+
+	site := call.node.Token
+
+	for {
+		ev.steps++
 
-	case "!=":
-		return evalToNativeBool(leftValue != rightValue)
+		if err := ev.checkBudgets(call.node.Token); err != nil {
+			return err
+		}
+		fnEnv, err := ev.bindFunctionArguments(call.node, call.fn, call.positional, call.named)
+
+		if err != nil {
+			return err
+		}
+		ev.allocated += ENVIRONMENT_SIZE
+		result := ev.evalTail(call.fn.Body, fnEnv)
+
+		if err, ok := result.(*object.Error); ok {
+			return addStackFrame(err, call.fn, site)
+		}
 
-	case "<":
-		return evalToNativeBool(leftValue < rightValue)
+		if returnValue, ok := result.(*object.ReturnValue); ok {
+			result = returnValue.Value
+		}
+		next, ok := result.(*tailCall)
 
-	case ">":
-		return evalToNativeBool(leftValue > rightValue)
+		// A body that is empty or ends with a statement
+		// without a value, like a `let`, give null.
+		if result == nil {
+			return NULL
+		}
 
-	case "<=":
-		return evalToNativeBool(leftValue <= rightValue)
+		if !ok {
+			return result
+		}
+		call = next
+	}
+}
 
-	case ">=":
-		return evalToNativeBool(leftValue >= rightValue)
+// checkBudgets return a BudgetExceededError located at tok if the
+// context of the evaluation is done or a budget is exceeded.
+func (ev *Evaluator) checkBudgets(tok token.Token) *object.Error {
+	var err *object.Error
+
+	switch {
+
+	case ev.ctx.Err() != nil:
+		err = newErrorAt(tok, "evaluation stopped: %s", ev.ctx.Err())
+
+	case ev.MaxSteps > 0 && ev.steps > ev.MaxSteps:
+		err = newErrorAt(tok, "step limit exceeded (%d)", ev.MaxSteps)
+
+	case ev.MaxAllocatedBytes > 0 && ev.allocated > ev.MaxAllocatedBytes:
+		err = newErrorAt(tok, "allocation limit exceeded (%d bytes)", ev.MaxAllocatedBytes)
 
 	default:
+		return nil
+	}
+	err.Kind = object.BUDGET_EXCEEDED_ERROR
+
+	return err
+}
+
+// allocate account for the memory taken by obj, roughly estimated
+// as a fixed object size plus the bytes of a string or the elements
+// of an array. Memory is never given back to the budget, even when
+// the object is no longer used.
+func (ev *Evaluator) allocate(obj object.Object) object.Object {
+	size := int64(OBJECT_SIZE)
+
+	switch obj := obj.(type) {
+
+	case *object.String:
+		size += int64(len(obj.Value))
+
+	case *object.Array:
+		size += int64(len(obj.Elements)) * OBJECT_SIZE
+
+	case *object.Error:
+		return obj
+	}
+	ev.allocated += size
+
+	return obj
+}
+
+func addStackFrame(err *object.Error, fn *object.Function, site token.Token) *object.Error {
+	name := fn.Name
+
+	if name == "" {
+		name = "<anonymous>"
+	}
+	err.Stack = append(err.Stack, object.StackFrame{
+		Function: name,
+		File: site.File,
+		Line: site.Line,
+		Column: site.Column,
+	})
+
+	return err
+}
+
+// evalTail evaluate node, which is in tail position in a function body:
+// its value is the value of the function. A call found there is returned
+// as a tailCall instead of being made. Tail positions are the last
+// statement of the body, the branches of an `if` in tail position and
+// the value of a `return`.
+func (ev *Evaluator) evalTail(node ast.Node, env *object.Environment) object.Object {
+
+	switch node := node.(type) {
+
+	case *ast.BlockStatement:
+		hoistFunctionDeclarations(node.Statements, env)
+
+		for i, stmt := range node.Statements {
+			if i == len(node.Statements) - 1 {
+				return ev.evalTail(stmt, env)
+			}
+			result := ev.Eval(stmt, env)
+
+			if isError(result) || isReturnValue(result) {
+				return result
+			}
+		}
+		return nil
+
+	case *ast.ExpressionStatement:
+		return ev.evalTail(node.Expression, env)
+
+	case *ast.FunctionCallExpression:
+		return ev.prepareFunctionCall(node, env)
+
+	case *ast.IfElseExpression:
+		condition := ev.Eval(node.Condition, env)
+
+		if isError(condition) {
+			return condition
+		}
+
+		if isTruthy(condition) {
+			return ev.evalTail(node.Consequence, object.NewScopeEnvironment(env, node.Consequence.Scope))
+		}
+
+		if node.Alternative != nil {
+			return ev.evalTail(node.Alternative, object.NewScopeEnvironment(env, node.Alternative.Scope))
+		}
 		return NULL
 	}
+
+	return ev.Eval(node, env)
 }
 
+// evalFunctionCallArguments evaluate the arguments of a call from left
+// to right. Spread arguments are expanded into the positional ones.
+func (ev *Evaluator) evalFunctionCallArguments(node *ast.FunctionCallExpression, env *object.Environment) ([]object.Object, map[string]object.Object, *object.Error) {
+	positional := []object.Object{}
+	named := map[string]object.Object{}
 
-// getObjectNumberValue return the object float64 representation value.
-// For integer, for example, the value will get converted to float64
-// and then return. Also, NULL & FALSE objects return 0 while TRUE return 1.
-func getObjectNumberValue(obj object.Object) float64 {
-	
-	switch obj.Type() {
+	for _, arg := range node.Arguments {
+
+		switch arg := arg.(type) {
+
+		case *ast.SpreadExpression:
+			value := ev.Eval(arg.Value, env)
 
-	case object.INTEGER_OBJ:
-		return float64(obj.(*object.Integer).Value)
+			if isError(value) {
+				return nil, nil, value.(*object.Error)
+			}
+			array, ok := value.(*object.Array)
 
-	case object.FLOAT_OBJ:
-		return obj.(*object.Float).Value
+			if !ok {
+				return nil, nil, newErrorAt(arg.Token, "cannot spread %s, expected an ARRAY", value.Type())
+			}
+			positional = append(positional, array.Elements...)
 
-	case object.BOOLEAN_OBJ:
-		if boolean, ok := obj.(*object.Boolean); ok && boolean.Value {
-			return 1
+		case *ast.NamedArgument:
+			value := ev.Eval(arg.Value, env)
+
+			if isError(value) {
+				return nil, nil, value.(*object.Error)
+			}
+			named[arg.Name.Value] = value
+
+		default:
+			value := ev.Eval(arg, env)
+
+			if isError(value) {
+				return nil, nil, value.(*object.Error)
+			}
+			positional = append(positional, value)
+		}
+	}
+
+	return positional, named, nil
+}
+
+// bindFunctionArguments return the environment of a call, where each
+// parameter is bound to, in order of priority, its positional argument,
+// its named argument or its default value. Default values are evaluated
+// in that environment, so they can refer to the previous parameters.
+// A rest parameter collect the remaining positional arguments in an array.
+func (ev *Evaluator) bindFunctionArguments(node *ast.FunctionCallExpression, fn *object.Function, positional []object.Object, named map[string]object.Object) (*object.Environment, *object.Error) {
+	fnEnv := object.NewScopeEnvironment(fn.Env, fn.Body.Scope)
+	hasRest := false
+
+	for i, param := range fn.Parameters {
+		name := param.Name.Value
+
+		if param.Rest {
+			hasRest = true
+			rest := []object.Object{}
+
+			if i < len(positional) {
+				rest = append(rest, positional[i:]...)
+			}
+			fnEnv.SetResolved(name, param.Name.Binding, &object.Array{ Elements: rest }, false)
+
+			if _, ok := named[name]; ok {
+				return nil, newErrorAt(node.Token, "cannot pass rest parameter %s by name", name)
+			}
+			continue
+		}
+
+		value, isNamed := named[name]
+		delete(named, name)
+
+		switch {
+
+		case i < len(positional) && isNamed:
+			return nil, newErrorAt(node.Token, "got multiple values for parameter %s", name)
+
+		case i < len(positional):
+			value = positional[i]
+
+		case isNamed:
+
+		case param.Default != nil:
+			value = ev.Eval(param.Default, fnEnv)
+
+			if isError(value) {
+				return nil, value.(*object.Error)
+			}
+
+		default:
+			return nil, newErrorAt(node.Token, "missing argument for parameter %s", name)
 		}
-		return 0
+
+		fnEnv.SetResolved(name, param.Name.Binding, value, false)
 	}
 
-	return 0
+	if !hasRest && len(positional) > len(fn.Parameters) {
+		return nil, newErrorAt(
+			node.Token,
+			"too many arguments: want at most %d, got %d",
+			len(fn.Parameters), len(positional),
+		)
+	}
+
+	if len(named) > 0 {
+		name := slices.Sorted(maps.Keys(named))[0]
+
+		return nil, newErrorAt(node.Token, "unknown parameter name: %s", name)
+	}
+
+	return fnEnv, nil
+}
+
+func newError(format string, args ...any) *object.Error {
+	return &object.Error{ Message: fmt.Sprintf(format, args...) }
+}
+
+// newErrorAt return an error located at the given token.
+func newErrorAt(tok token.Token, format string, args ...any) *object.Error {
+	err := newError(format, args...)
+	err.File = tok.File
+	err.Line = tok.Line
+	err.Column = tok.Column
+
+	return err
+}
+
+// locateError set the position of obj to the one of tok when obj is
+// an error raised without a position, like the ones of the operators.
+func locateError(obj object.Object, tok token.Token) object.Object {
+	if err, ok := obj.(*object.Error); ok && err.Line == 0 {
+		err.File = tok.File
+		err.Line = tok.Line
+		err.Column = tok.Column
+	}
+
+	return obj
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR_OBJ
+}
+
+// isTruthy report whether obj is considered true by conditions:
+// false, null and zero numbers are falsy, anything else is truthy.
+func isTruthy(obj object.Object) bool {
+	return object.IsTruthy(obj)
+}
+
+func isReturnValue(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.RETURN_VALUE_OBJ
 }
@@ -1,10 +1,18 @@
 package evaluator
 
 import (
+	"context"
+	"monkey/internal/corpus"
 	"monkey/internal/lexer"
 	"monkey/internal/object"
 	"monkey/internal/parser"
+	"monkey/internal/resolver"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -28,6 +36,8 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"2 * (5 + 10)", 30},
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
+		{"1000 * 1000", 1000000},
+		{"123456789 + 1", 123456790},
 	}
 
 	for _, tt := range tests {
@@ -127,6 +137,1656 @@ func TestEvalBangOperator(t *testing.T) {
 }
 
 
+func TestEvalPowerExpression(t *testing.T) {
+
+	t.Run("it should stay exact with integers", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	int64
+		}{
+			{ "2 ** 10", 1024 },
+			{ "2 ** 3 ** 2", 512 },
+			{ "(2 ** 3) ** 2", 64 },
+			{ "5 ** 0", 1 },
+			{ "0 ** 0", 1 },
+			{ "-2 ** 2", -4 },
+			{ "(-2) ** 3", -8 },
+			{ "2 * 3 ** 2", 18 },
+			{ "3 ** 39", 4052555153018976267 },
+			{ "2 ** 62", 4611686018427387904 },
+			{ "(-2) ** 63", -9223372036854775808 },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testIntegerObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+
+	t.Run("it should fall back to float", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	float64
+		}{
+			{ "2 ** -1", 0.5 },
+			{ "2 ** -2", 0.25 },
+			{ "2.5 ** 2", 6.25 },
+			{ "6.25 ** 0.5", 2.5 },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testFloatObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+
+	t.Run("it should report overflows", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "2 ** 63", "integer overflow: 2 ** 63" },
+			{ "10 ** 19", "integer overflow: 10 ** 19" },
+			{ "3 ** 40 + 1", "integer overflow: 3 ** 40" },
+			{ "-(7 ** 100)", "integer overflow: 7 ** 100" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testErrorObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+}
+
+func TestEvalBitwiseExpression(t *testing.T) {
+
+	t.Run("it should operate on integers", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	int64
+		}{
+			{ "12 & 10", 8 },
+			{ "12 | 10", 14 },
+			{ "12 ^ 10", 6 },
+			{ "~0", -1 },
+			{ "~5", -6 },
+			{ "1 << 4", 16 },
+			{ "256 >> 4", 16 },
+			{ "-16 >> 2", -4 },
+			{ "1 << 2 + 1", 8 },
+						{ "1 | 2 ^ 3 & 4", 3 },
+			{ "1 << 64", 0 },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testIntegerObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+
+	t.Run("it should reject floats and booleans", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "1.5 & 1", "unsupported operand types for &: FLOAT and INTEGER" },
+			{ "1 | true", "unsupported operand types for |: INTEGER and BOOLEAN" },
+			{ "true ^ false", "unsupported operand types for ^: BOOLEAN and BOOLEAN" },
+			{ "2.0 << 1", "unsupported operand types for <<: FLOAT and INTEGER" },
+			{ "~1.5", "unsupported operand type for ~: FLOAT" },
+			{ "~true", "unsupported operand type for ~: BOOLEAN" },
+			{ "1 >> -1", "negative shift count: -1" },
+			{ "6 & 3 == 3", "unsupported operand types for &: INTEGER and BOOLEAN" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testErrorObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+}
+
+func TestEvalDeclarationStatement(t *testing.T) {
+
+	t.Run("it should bind values to names", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	int64
+		}{
+			{ "let a = 5; a;", 5 },
+			{ "let a = 5 * 5; a;", 25 },
+			{ "let a = 5; let b = a; b;", 5 },
+			{ "let a = 5; let b = a; let c = a + b + 5; c;", 15 },
+			{ "const A = 2; A ** 3;", 8 },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testIntegerObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+
+	t.Run("it should report errors", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "foobar", "identifier not found: foobar" },
+			{ "let a = b;", "identifier not found: b" },
+			{ "const A = 1; let A = 2;", "cannot redeclare constant: A" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testErrorObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+}
+
+func TestEvalUpdateExpression(t *testing.T) {
+
+	t.Run("it should return the old or the new value", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	int64
+		}{
+			{ "let i = 0; i++;", 0 },
+			{ "let i = 0; i++; i;", 1 },
+			{ "let i = 0; ++i;", 1 },
+			{ "let i = 0; ++i; i;", 1 },
+			{ "let i = 5; i--;", 5 },
+			{ "let i = 5; i--; i;", 4 },
+			{ "let i = 5; --i;", 4 },
+			{ "let i = 1; i++ + i++;", 3 },
+			{ "let i = 1; ++i * ++i;", 6 },
+			{ "let i = 1; -i++;", -1 },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testIntegerObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+
+	t.Run("it should update floats", func(t *testing.T) {
+		evaluated := testEval("let f = 1.5; f++; f;")
+		testFloatObject(t, evaluated, 2.5)
+	})
+
+	t.Run("it should report errors", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "i++", "identifier not found: i" },
+			{ "const I = 1; I++;", "cannot assign to constant: I" },
+			{ "let b = true; --b;", "unsupported operand type for --: BOOLEAN" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testErrorObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+}
+
+func TestEvalStringExpression(t *testing.T) {
+
+	t.Run("it should concatenate and compare strings", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	any
+		}{
+			{ `"Hello World!"`, "Hello World!" },
+			{ `"Hello" + " " + "World!"`, "Hello World!" },
+			{ `"a" == "a"`, true },
+			{ `"a" != "a"`, false },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			if expected, ok := tt.expected.(string); ok {
+				if !testStringObject(t, evaluated, expected) {
+					return
+				}
+				continue
+			}
+
+			if !testBooleanObject(t, evaluated, tt.expected.(bool)) {
+				return
+			}
+		}
+	})
+
+	t.Run("it should reject other operators", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ `"a" - "b"`, "unsupported operand types for -: STRING and STRING" },
+			{ `"a" + 1`, "unsupported operand types for +: STRING and INTEGER" },
+			{ `[1] + [2]`, "unsupported operand types for +: ARRAY and ARRAY" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testErrorObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+}
+
+func TestEvalIndexExpression(t *testing.T) {
+
+	t.Run("it should index arrays and strings", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	any
+		}{
+			{ "[1, 2, 3][0]", 1 },
+			{ "[1, 2, 3][2]", 3 },
+			{ "let i = 0; [1][i];", 1 },
+			{ "[1, 2, 3][1 + 1];", 3 },
+			{ "let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6 },
+			{ "[1, 2, 3][-1]", 3 },
+			{ "let a = [1, 2, 3]; a[1]++; a[1];", 3 },
+			{ "let a = [1, 2, 3]; --a[-1];", 2 },
+			{ `"abc"[1]`, "b" },
+			{ `"abc"[-3]`, "a" },
+			{ `"héllo"[1]`, "é" },
+			{ `"héllo"[-4]`, "é" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			if expected, ok := tt.expected.(string); ok {
+				if !testStringObject(t, evaluated, expected) {
+					return
+				}
+				continue
+			}
+
+			if !testIntegerObject(t, evaluated, int64(tt.expected.(int))) {
+				return
+			}
+		}
+	})
+
+	t.Run("it should report errors at the source position", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+			line		int
+			column		int
+		}{
+			{ "[1, 2, 3][3]", "index out of range: 3 with length 3", 1, 10 },
+			{ "let a = [1];\na[-2]", "index out of range: -2 with length 1", 2, 2 },
+			{ `"abc"[true]`, "index must be an integer, got BOOLEAN", 1, 6 },
+			{ "5[0]", "index operator not supported: INTEGER", 1, 2 },
+			{ `let s = "abc"; s[0]++;`, "cannot assign to an index of STRING", 1, 17 },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			if !testErrorObject(t, evaluated, tt.expected) {
+				return
+			}
+			testErrorPosition(t, evaluated, tt.line, tt.column)
+		}
+	})
+}
+
+func TestEvalSliceExpression(t *testing.T) {
+
+	t.Run("it should slice arrays and strings", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "[1, 2, 3, 4, 5][1:3]", "[2, 3]" },
+			{ "[1, 2, 3, 4, 5][:2]", "[1, 2]" },
+			{ "[1, 2, 3, 4, 5][3:]", "[4, 5]" },
+			{ "[1, 2, 3, 4, 5][:]", "[1, 2, 3, 4, 5]" },
+			{ "[1, 2, 3, 4, 5][::-1]", "[5, 4, 3, 2, 1]" },
+			{ "[1, 2, 3, 4, 5][::2]", "[1, 3, 5]" },
+			{ "[1, 2, 3, 4, 5][-2:]", "[4, 5]" },
+			{ "[1, 2, 3, 4, 5][3:1]", "[]" },
+			{ "[1, 2, 3, 4, 5][3:1:-1]", "[4, 3]" },
+			{ "[1, 2, 3, 4, 5][5::-2]", "[5, 3, 1]" },
+			{ "[][::-1]", "[]" },
+			{ `"Hello World"[:5]`, "Hello" },
+			{ `"Hello World"[6:]`, "World" },
+			{ `"abc"[::-1]`, "cba" },
+			{ `"abcdef"[1::2]`, "bdf" },
+			{ `"abc"[2:1]`, "" },
+			{ `"héllo"[1:3]`, "él" },
+			{ `"héllo"[::-1]`, "olléh" },
+			{ `"日本語"[::2]`, "日語" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			if isError(evaluated) {
+				t.Fatalf("Unexpected error for %q: %s\n", tt.input, evaluated.Inspect())
+			}
+
+			if evaluated.Inspect() != tt.expected {
+				t.Fatalf(
+					"Expecting %q to evaluate to %q, but got %q\n",
+					tt.input, tt.expected, evaluated.Inspect(),
+				)
+			}
+		}
+	})
+
+	t.Run("it should copy sliced arrays", func(t *testing.T) {
+		input := `
+let a = [1, 2, 3];
+let b = a[:];
+b[0]++;
+a[1]++;
+[a, b]
+`
+		evaluated := testEval(input)
+
+		if evaluated.Inspect() != "[[1, 3, 3], [2, 2, 3]]" {
+			t.Fatalf(
+				"Expecting slices to be independent copies, but got %q\n",
+				evaluated.Inspect(),
+			)
+		}
+	})
+
+	t.Run("it should report errors at the source position", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+			line		int
+			column		int
+		}{
+			{ "[1, 2][0:3]", "slice bounds out of range: 3 with length 2", 1, 7 },
+			{ "[1, 2][-3:]", "slice bounds out of range: -3 with length 2", 1, 7 },
+			{ `"ab"[::0]`, "slice step cannot be zero", 1, 5 },
+			{ `"ab"[1.5:]`, "slice bounds must be integers, got FLOAT", 1, 5 },
+			{ "5[1:]", "slice operator not supported: INTEGER", 1, 2 },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			if !testErrorObject(t, evaluated, tt.expected) {
+				return
+			}
+			testErrorPosition(t, evaluated, tt.line, tt.column)
+		}
+	})
+}
+
+func TestEvalRangeExpression(t *testing.T) {
+	tests := []struct{
+		input		string
+		expected	string
+		length		int64
+	}{
+		{ "1..10", "1..10", 9 },
+		{ "1..=10", "1..=10", 10 },
+		{ "let n = 3; 0..n * 2", "0..6", 6 },
+		{ "5..1", "5..1", 0 },
+		{ "1..=1", "1..=1", 1 },
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		rangeObj, ok := evaluated.(*object.Range)
+
+		if !ok {
+			t.Fatalf(
+				"Expecting %q to evaluate to a *object.Range, but got %T (%+v)\n",
+				tt.input, evaluated, evaluated,
+			)
+		}
+
+		if rangeObj.Inspect() != tt.expected || rangeObj.Len() != tt.length {
+			t.Fatalf(
+				"Expecting range %s with length %d, but got %s with length %d\n",
+				tt.expected, tt.length, rangeObj.Inspect(), rangeObj.Len(),
+			)
+		}
+	}
+
+	evaluated := testEval("1..2.5")
+	testErrorObject(t, evaluated, "range bounds must be integers, got INTEGER and FLOAT")
+}
+
+func TestEvalForInStatement(t *testing.T) {
+
+	t.Run("it should iterate", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	int64
+		}{
+			{ "let n = 0; for i in 0..10 { n++; } n;", 10 },
+			{ "let n = 0; for i in 1..=10 { let n = n; } n;", 0 },
+			{ "let n = 0; for (x in [1, 2, 3]) { for y in 0..x { n++; } } n;", 6 },
+			{ "let n = 0; for c in \"abc\" { n++; } n;", 3 },
+			{ "let n = 0; for c in \"héllo\" { if c == \"é\" { n++ } } n;", 1 },
+			{ "let n = 0; for c in \"日本語\" { n++; } n;", 3 },
+			{ "let a = [1, 2]; for i in 0..2 { a[i]++; } a[0] + a[1];", 5 },
+			{ "let n = 0; for i in 1000000000..0 { n++; } n;", 0 },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testIntegerObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+
+	t.Run("it should report errors", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "for i in 5 { }", "cannot iterate over INTEGER" },
+			{ "for i in 0..3 { i[0]; }", "index operator not supported: INTEGER" },
+			{ "for i in 0..3 { } i;", "identifier not found: i" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testErrorObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+}
+
+func TestEvalIfElseExpression(t *testing.T) {
+	tests := []struct{
+		input		string
+		expected	any
+	}{
+		{ "if (true) { 10 }", 10 },
+		{ "if (false) { 10 }", nil },
+		{ "if (1) { 10 }", 10 },
+		{ "if (0) { 10 } else { 20 }", 20 },
+		{ "if (1 < 2) { 10 }", 10 },
+		{ "if 1 > 2 { 10 } else { 20 }", 20 },
+		{ "let x = 5; if x > 10 { 1 } else if x > 3 { 2 } else { 3 }", 2 },
+		{ "let x = 0; if x > 10 { 1 } else if x > 3 { 2 } else { 3 }", 3 },
+		{ `if "" { 1 } else { 2 }`, 1 },
+		{ "if (if (false) { 1 }) { 1 } else { 2 }", 2 },
+		{ "let x = 1; if true { let x = 2; } x", 1 },
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		if expected, ok := tt.expected.(int); ok {
+			if !testIntegerObject(t, evaluated, int64(expected)) {
+				return
+			}
+			continue
+		}
+
+		if evaluated != NULL {
+			t.Fatalf("Expecting %q to evaluate to NULL, but got %+v\n", tt.input, evaluated)
+		}
+	}
+}
+
+func TestEvalFunctionDeclaration(t *testing.T) {
+
+	t.Run("it should hoist declarations in their block", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	any
+		}{
+			{ "fn add(x, y) { x + y }; add(1, 2);", 3 },
+			{ "let r = double(4); fn double(x) { x * 2 } r;", 8 },
+			{ "fn fact(n) { if n == 0 { 1 } else { n * fact(n - 1) } } fact(10);", 3628800 },
+			{
+				`
+let r = isEven(10);
+fn isEven(n) { if n == 0 { true } else { isOdd(n - 1) } }
+fn isOdd(n) { if n == 0 { false } else { isEven(n - 1) } }
+r
+`,
+				true,
+			},
+			{
+				`
+fn outer() {
+	let r = inner(2);
+	fn inner(x) { x + base }
+	let base = 40;
+	r
+}
+outer();
+`,
+				"identifier not found: base",
+			},
+			{
+				`
+fn outer() {
+	fn inner(x) { x + base }
+	let base = 40;
+	inner(2)
+}
+outer();
+`,
+				42,
+			},
+			{ "fn outer() { fn inner() { 1 } inner } outer(); inner;", "identifier not found: inner" },
+			{ "let x = f; fn f() { 1 } x();", 1 },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			switch expected := tt.expected.(type) {
+
+			case int:
+				if !testIntegerObject(t, evaluated, int64(expected)) {
+					return
+				}
+
+			case bool:
+				if !testBooleanObject(t, evaluated, expected) {
+					return
+				}
+
+			case string:
+				if !testErrorObject(t, evaluated, expected) {
+					return
+				}
+			}
+		}
+	})
+
+	t.Run("it should know the function name", func(t *testing.T) {
+		evaluated := testEval("fn add(x, y) { x + y } add;")
+		fn, ok := evaluated.(*object.Function)
+
+		if !ok {
+			t.Fatalf("Expecting object to be a *object.Function, but got %T (%+v)\n", evaluated, evaluated)
+		}
+
+		if fn.Name != "add" {
+			t.Fatalf("Expecting fn.Name to be %q, but got %q\n", "add", fn.Name)
+		}
+
+		if fn.Inspect() != "fn add(x, y) { (x + y); }" {
+			t.Fatalf("Unexpected fn.Inspect(): %q\n", fn.Inspect())
+		}
+
+		anonymous := testEval("fn(x) { x }").(*object.Function)
+
+		if anonymous.Name != "" {
+			t.Fatalf("Expecting anonymous function to have no name, but got %q\n", anonymous.Name)
+		}
+	})
+}
+
+func TestEvalFunctionObject(t *testing.T) {
+	input := "fn(x, y = 2, ...z) { x + 2; };"
+
+	evaluated := testEval(input)
+	fn, ok := evaluated.(*object.Function)
+
+	if !ok {
+		t.Fatalf("Expecting object to be a *object.Function, but got %T (%+v)\n", evaluated, evaluated)
+	}
+
+	if len(fn.Parameters) != 3 {
+		t.Fatalf("Expecting function to have 3 parameters, but got %d\n", len(fn.Parameters))
+	}
+
+	if fn.Inspect() != "fn(x, y = 2, ...z) { (x + 2); }" {
+		t.Fatalf("Unexpected fn.Inspect(): %q\n", fn.Inspect())
+	}
+}
+
+func TestEvalFunctionCall(t *testing.T) {
+
+	t.Run("it should call functions", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	int64
+		}{
+			{ "let identity = fn(x) { x; }; identity(5);", 5 },
+			{ "let identity = fn(x) { return x; }; identity(5);", 5 },
+			{ "let double = fn(x) { x * 2; }; double(5);", 10 },
+			{ "let add = fn(x, y) { x + y; }; add(5, 5);", 10 },
+			{ "let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20 },
+			{ "fn(x) { x; }(5)", 5 },
+			{ "let f = fn() { return 1; 2; }; f();", 1 },
+			{ "let f = fn() { for i in 0..10 { return i + 7; } }; f();", 7 },
+			{ "let newAdder = fn(x) { fn(y) { x + y } }; let addTwo = newAdder(2); addTwo(3);", 5 },
+			{ "let x = 10; let f = fn() { x++; }; f(); f(); x;", 12 },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testIntegerObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+
+	t.Run("it should bind default, rest, spread and named arguments", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "let f = fn(x, y = 10) { x + y }; f(1);", "11" },
+			{ "let f = fn(x, y = 10) { x + y }; f(1, 2);", "3" },
+			{ "let f = fn(x, y = x * 2) { y }; f(4);", "8" },
+			{ "let f = fn(first, ...rest) { rest }; f(1, 2, 3);", "[2, 3]" },
+			{ "let f = fn(first, ...rest) { rest }; f(1);", "[]" },
+			{ "let f = fn(x, y, z) { [x, y, z] }; let args = [1, 2]; f(...args, 3);", "[1, 2, 3]" },
+			{ "let f = fn(...all) { all }; f(0, ...[1, 2], ...[], 3);", "[0, 1, 2, 3]" },
+			{ "let f = fn(x, y = 2, z = 3) { [x, y, z] }; f(1, z: 30);", "[1, 2, 30]" },
+			{ "let f = fn(x, y) { x - y }; f(y: 1, x: 5);", "4" },
+			{ "let f = fn(x, y = 2, ...r) { [x, y, r] }; f(1, 2, 3, 4);", "[1, 2, [3, 4]]" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			if evaluated == nil || evaluated.Inspect() != tt.expected {
+				t.Fatalf(
+					"Expecting %q to evaluate to %s, but got %+v\n",
+					tt.input, tt.expected, evaluated,
+				)
+			}
+		}
+	})
+
+	t.Run("it should give null when the body has no value", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "fn f() {} let y = f(); y + 1", "1" },
+			{ "fn f() { let x = 1; } [f()]", "[null]" },
+			{ "fn f() { for i in 0..2 { i } } [f(), f()]", "[null, null]" },
+			{ "fn f() {} [f()].map(fn(v) { v })", "[null]" },
+			{ "fn f() { let x = 1; } fn g() { f() } g() == f()", "true" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			if evaluated == nil || evaluated.Inspect() != tt.expected {
+				t.Fatalf(
+					"Expecting %q to evaluate to %s, but got %+v\n",
+					tt.input, tt.expected, evaluated,
+				)
+			}
+		}
+	})
+
+	t.Run("it should report invalid calls", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "let f = fn(x) { x }; f();", "missing argument for parameter x" },
+			{ "let f = fn(x) { x }; f(1, 2);", "too many arguments: want at most 1, got 2" },
+			{ "let f = fn(x) { x }; f(1, x: 2);", "got multiple values for parameter x" },
+			{ "let f = fn(x) { x }; f(y: 2, x: 1);", "unknown parameter name: y" },
+			{ "let f = fn(...r) { r }; f(r: 1);", "cannot pass rest parameter r by name" },
+			{ "let f = fn(x) { x }; f(...1);", "cannot spread INTEGER, expected an ARRAY" },
+			{ "let f = fn(x = y) { x }; f();", "identifier not found: y" },
+			{ "5(1)", "not a function: INTEGER" },
+			{ "let f = fn() { g() }; f();", "identifier not found: g" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testErrorObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+}
+
+
+func TestEvalTailCall(t *testing.T) {
+
+	t.Run("it should run deep tail recursion without growing the stack", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	any
+		}{
+			{ "fn countdown(n) { if n == 0 { 0 } else { countdown(n - 1) } } countdown(1000000);", 0 },
+			{ "fn countdown(n) { if n == 0 { return 0 } return countdown(n - 1) } countdown(100000);", 0 },
+			{ "fn sum(n, acc = 0) { if n == 0 { acc } else { sum(n - 1, acc: acc + n) } } sum(100000);", 5000050000 },
+			{
+				`
+fn isEven(n) { if n == 0 { true } else { isOdd(n - 1) } }
+fn isOdd(n) { if n == 0 { false } else { isEven(n - 1) } }
+isEven(1000000)
+`,
+				true,
+			},
+			{ "fn loop(n) { for i in 0..1 { if n == 0 { return \"done\" } return loop(n - 1) } } loop(100000);", "done" },
+			{ "fn fact(n) { if n == 0 { 1 } else { n * fact(n - 1) } } fact(20);", 2432902008176640000 },
+		}
+
+		for i, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			switch expected := tt.expected.(type) {
+
+			case int:
+				if !testIntegerObject(t, evaluated, int64(expected)) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+
+			case bool:
+				if !testBooleanObject(t, evaluated, expected) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+
+			case string:
+				if !testStringObject(t, evaluated, expected) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+			}
+		}
+	})
+
+	t.Run("it should report errors raised by a tail call", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "fn f(n) { if n == 0 { g() } else { f(n - 1) } } f(10);", "identifier not found: g" },
+			{ "fn f(n) { return f(n - 1, 2) } f(1);", "too many arguments: want at most 1, got 2" },
+			{ "let x = 1; fn f() { x() } f();", "not a function: INTEGER" },
+			{ "return (fn(x) { x })(7, 8);", "too many arguments: want at most 1, got 2" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testErrorObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+	})
+
+	t.Run("it should make a returned call at the top level", func(t *testing.T) {
+		evaluated := testEval("fn f(x) { x * 2 } return f(21); 0;")
+
+		testIntegerObject(t, evaluated, 42)
+	})
+}
+
+
+func TestEvalStackTrace(t *testing.T) {
+	input := `fn inner(x) {
+	x + missing
+}
+fn outer(x) {
+	let r = inner(x);
+	r
+}
+let f = fn() { outer(1) + 1 };
+f();`
+
+	program := parser.New(lexer.NewFile("main.mk", input)).ParseProgram()
+	evaluated := Eval(program, object.NewEnvironment())
+	err, ok := evaluated.(*object.Error)
+
+	if !ok {
+		t.Fatalf("Expecting object to be an *object.Error, but got %T (%+v)\n", evaluated, evaluated)
+	}
+
+	t.Run("it should record each active call", func(t *testing.T) {
+		expected := []object.StackFrame{
+			{ Function: "inner", File: "main.mk", Line: 5, Column: 15 },
+			{ Function: "outer", File: "main.mk", Line: 8, Column: 21 },
+			{ Function: "<anonymous>", File: "main.mk", Line: 9, Column: 2 },
+		}
+
+		if !slices.Equal(err.Stack, expected) {
+			t.Fatalf("Expecting err.Stack to be %+v, but got %+v\n", expected, err.Stack)
+		}
+	})
+
+	t.Run("it should print the stack like a Go panic", func(t *testing.T) {
+		expected := `ERROR: identifier not found: missing
+
+inner(...)
+	main.mk:2:6
+outer(...)
+	main.mk:5:15
+<anonymous>(...)
+	main.mk:8:21
+<main>
+	main.mk:9:2
+`
+
+		if err.StackTrace() != expected {
+			t.Fatalf("Expecting stack trace to be:\n%s\nbut got:\n%s", expected, err.StackTrace())
+		}
+	})
+
+	t.Run("it should record the original site of a chain of tail calls", func(t *testing.T) {
+		input := "fn a(s) { 1 - s }\nfn b(s) { a(s) }\n\n\nb(\"x\")"
+		program := parser.New(lexer.NewFile("main.mk", input)).ParseProgram()
+		evaluated := Eval(program, object.NewEnvironment())
+		err, ok := evaluated.(*object.Error)
+
+		if !ok {
+			t.Fatalf("Expecting object to be an *object.Error, but got %T (%+v)\n", evaluated, evaluated)
+		}
+
+		expected := []object.StackFrame{
+			{ Function: "a", File: "main.mk", Line: 5, Column: 2 },
+		}
+
+		if !slices.Equal(err.Stack, expected) {
+			t.Fatalf("Expecting err.Stack to be %+v, but got %+v\n", expected, err.Stack)
+		}
+	})
+
+	t.Run("it should locate operator errors", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			line		int
+			column		int
+		}{
+			{ "let a = 1;\n  \"a\" - 1", 2, 7 },
+			{ "~1.5", 1, 1 },
+			{ "let s = \"a\"; s++", 1, 15 },
+			{ "undefined", 1, 1 },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			if !testErrorPosition(t, evaluated, tt.line, tt.column) {
+				return
+			}
+		}
+	})
+
+	t.Run("it should have no stack at the top level", func(t *testing.T) {
+		err := testEval("1 + x").(*object.Error)
+
+		if len(err.Stack) != 0 {
+			t.Fatalf("Expecting err.Stack to be empty, but got %+v\n", err.Stack)
+		}
+	})
+}
+
+
+func TestEvalCallDepth(t *testing.T) {
+	runaway := "fn f(n) { 1 + f(n + 1) } f(0);"
+
+	t.Run("it should stop a runaway recursion with a stack overflow error", func(t *testing.T) {
+		evaluated := testEval(runaway)
+		err, ok := evaluated.(*object.Error)
+
+		if !ok {
+			t.Fatalf("Expecting object to be an *object.Error, but got %T (%+v)\n", evaluated, evaluated)
+		}
+
+		if err.Kind != object.STACK_OVERFLOW_ERROR {
+			t.Fatalf("Expecting err.Kind to be %q, but got %q\n", object.STACK_OVERFLOW_ERROR, err.Kind)
+		}
+
+		if len(err.Stack) != DEFAULT_MAX_CALL_DEPTH {
+			t.Fatalf("Expecting err.Stack to hold %d frames, but got %d\n", DEFAULT_MAX_CALL_DEPTH, len(err.Stack))
+		}
+		testErrorObject(t, err, "maximum call depth exceeded (10000)")
+
+		if !strings.Contains(err.StackTrace(), "\n...9900 additional frames elided...\n<main>\n") {
+			t.Fatalf("Expecting the stack trace to elide the outer frames, but got:\n%s", err.StackTrace())
+		}
+	})
+
+	t.Run("it should use the configured maximum call depth", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	any
+		}{
+			{ runaway, "maximum call depth exceeded (50)" },
+			{ "fn f(n) { if n == 0 { 0 } else { 1 + f(n - 1) } } f(49);", 49 },
+			{ "fn f(n) { if n == 0 { 0 } else { 1 + f(n - 1) } } f(50);", "maximum call depth exceeded (50)" },
+			{ "fn countdown(n) { if n == 0 { 0 } else { countdown(n - 1) } } countdown(1000);", 0 },
+		}
+
+		for i, tt := range tests {
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+			ev := New()
+			ev.MaxCallDepth = 50
+
+			evaluated := ev.Eval(program, object.NewEnvironment())
+
+			switch expected := tt.expected.(type) {
+
+			case int:
+				if !testIntegerObject(t, evaluated, int64(expected)) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+
+			case string:
+				if !testErrorObject(t, evaluated, expected) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+			}
+		}
+	})
+
+	t.Run("it should be usable again after a stack overflow", func(t *testing.T) {
+		ev := New()
+		env := object.NewEnvironment()
+
+		ev.Eval(parser.New(lexer.New(runaway)).ParseProgram(), env)
+		evaluated := ev.Eval(parser.New(lexer.New("fn g() { 1 } g();")).ParseProgram(), env)
+
+		testIntegerObject(t, evaluated, 1)
+	})
+}
+
+
+func TestEvalBudgets(t *testing.T) {
+	infiniteLoop := "fn loop(n) { loop(n + 1) } loop(0);"
+
+	evalWith := func(ctx context.Context, ev *Evaluator, input string) object.Object {
+		program := parser.New(lexer.New(input)).ParseProgram()
+
+		return ev.EvalContext(ctx, program, object.NewEnvironment())
+	}
+
+	testBudgetError := func(t *testing.T, evaluated object.Object, expected string) {
+		if !testErrorObject(t, evaluated, expected) {
+			t.FailNow()
+		}
+
+		if kind := evaluated.(*object.Error).Kind; kind != object.BUDGET_EXCEEDED_ERROR {
+			t.Fatalf("Expecting err.Kind to be %q, but got %q\n", object.BUDGET_EXCEEDED_ERROR, kind)
+		}
+	}
+
+	t.Run("it should stop when the step limit is exceeded", func(t *testing.T) {
+		tests := []string{
+			infiniteLoop,
+			"for i in 0..1000000000 { i }",
+			"for i in 0..300000000 { }",
+			"fn f() { } for i in 0..300000000 { f() }",
+		}
+
+		for _, input := range tests {
+			ev := New()
+			ev.MaxSteps = 1000
+
+			testBudgetError(t, evalWith(context.Background(), ev, input), "step limit exceeded (1000)")
+		}
+	})
+
+	t.Run("it should stop when the allocation limit is exceeded", func(t *testing.T) {
+		tests := []string{
+			"fn grow(s) { grow(s + s) } grow(\"ab\");",
+			"for i in 0..1000000000 { [i, i, i] }",
+			"fn grow(s) { grow([s, s].join(\"\")) } grow(\"ab\");",
+		}
+
+		for _, input := range tests {
+			ev := New()
+			ev.MaxAllocatedBytes = 1 << 20
+
+			testBudgetError(t, evalWith(context.Background(), ev, input), "allocation limit exceeded (1048576 bytes)")
+		}
+	})
+
+	t.Run("it should stop when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Millisecond)
+		defer cancel()
+
+		testBudgetError(t, evalWith(ctx, New(), infiniteLoop), "evaluation stopped: context deadline exceeded")
+
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		testBudgetError(t, evalWith(cancelled, New(), "fn f() { 1 } f();"), "evaluation stopped: context canceled")
+	})
+
+	t.Run("it should run programs within their budgets", func(t *testing.T) {
+		ev := New()
+		ev.MaxSteps = 1000000
+		ev.MaxAllocatedBytes = 1 << 20
+
+		evaluated := evalWith(context.Background(), ev, "fn sum(n) { if n == 0 { 0 } else { n + sum(n - 1) } } sum(100);")
+
+		testIntegerObject(t, evaluated, 5050)
+	})
+}
+
+
+func TestEvalTryStatement(t *testing.T) {
+
+	t.Run("it should catch errors and run finally blocks", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	any
+		}{
+			{ `try { throw "boom" } catch (e) { e["message"] }`, "boom" },
+			{ `try { throw 42 } catch (e) { e["message"] }`, "42" },
+			{ `try { 1 + missing } catch (e) { e["message"] }`, "identifier not found: missing" },
+			{ `try { 1 + missing } catch (e) { e["kind"] }`, "Error" },
+			{ `try { [1][5] } catch { "caught" }`, "caught" },
+			{ `try { 1 } catch (e) { 2 }`, 1 },
+			{ `let n = 0; fn f() { try { return 1 } finally { n++ } } f() + n * 10`, 11 },
+			{ `fn f() { try { return 1 } finally { return 2 } } f()`, 2 },
+			{ `fn f() { try { throw "a" } catch (e) { return e["message"] } finally { "ignored" } } f()`, "a" },
+			{ `fn f() { try { throw "a" } finally { return "b" } } f()`, "b" },
+			{
+				`
+let n = 0;
+fn f() { try { missing } finally { n++ } }
+fn g() { try { f() } catch { n } }
+g()
+`,
+				1,
+			},
+			{
+				`
+try {
+	try { throw "inner" } catch (e) { throw e }
+} catch (e) {
+	e["message"]
+}
+`,
+				"inner",
+			},
+			{ `fn bad() { missing } fn f() { try { return bad() } catch (e) { "caught" } } f()`, "caught" },
+			{ `fn f() { f() + 1 } try { f() } catch (e) { e["kind"] }`, object.STACK_OVERFLOW_ERROR },
+			{ `fn f() { try { 1 } catch { 2 } } f()`, 1 },
+		}
+
+		for i, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			switch expected := tt.expected.(type) {
+
+			case int:
+				if !testIntegerObject(t, evaluated, int64(expected)) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+
+			case string:
+				if !testStringObject(t, evaluated, expected) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+			}
+		}
+	})
+
+	t.Run("it should expose the calls a caught error went through", func(t *testing.T) {
+		input := `
+fn risky() { throw "boom" }
+fn wrap() { risky() + 1 }
+try { wrap() } catch (e) { e["stack"] }
+`
+		evaluated := testEval(input)
+		stack, ok := evaluated.(*object.Array)
+
+		if !ok {
+			t.Fatalf("Expecting object to be an *object.Array, but got %T (%+v)\n", evaluated, evaluated)
+		}
+
+		if len(stack.Elements) != 2 {
+			t.Fatalf("Expecting stack to hold 2 frames, but got %d\n", len(stack.Elements))
+		}
+		testStringObject(t, stack.Elements[0], "risky (<input>:3:18)")
+		testStringObject(t, stack.Elements[1], "wrap (<input>:4:11)")
+	})
+
+	t.Run("it should raise uncaught errors", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ `throw "boom"`, "boom" },
+			{ `fn f() { throw "boom" } try { f() } finally { 1 }`, "boom" },
+			{ `try { 1 } finally { throw "from finally" }`, "from finally" },
+			{ `try { throw "a" } catch (e) { throw "b" }`, "b" },
+			{ `try { throw "a" } catch (e) { e[0] }`, "error field must be a string, got INTEGER" },
+			{ `try { throw "a" } catch (e) { e["foo"] }`, "unknown error field: foo" },
+		}
+
+		for _, tt := range tests {
+			evaluated := testEval(tt.input)
+			if !testErrorObject(t, evaluated, tt.expected) {
+				return
+			}
+		}
+
+		testErrorPosition(t, testEval("let x = 1;\n  throw \"boom\""), 2, 3)
+	})
+
+	t.Run("it should not catch budget errors", func(t *testing.T) {
+		tests := []string{
+			"fn loop() { loop() } try { loop() } catch (e) { \"caught\" }",
+			"fn loop() { loop() } fn f() { try { loop() } finally { return \"escaped\" } } f()",
+			"fn loop() { loop() } fn f() { try { loop() } catch (e) { 1 } finally { throw \"escaped\" } } f()",
+		}
+
+		for _, input := range tests {
+			ev := New()
+			ev.MaxSteps = 1000
+
+			evaluated := ev.Eval(parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+
+			if !testErrorObject(t, evaluated, "step limit exceeded (1000)") {
+				return
+			}
+		}
+	})
+}
+
+
+func TestEvalModules(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	shared := filepath.Join(dir, "shared")
+
+	files := map[string]string{
+		"lib/math.mk": `
+import "util.mk" as util
+export const base = 7;
+export fn square(x) { util.twice(x * x) / 2 }
+export let loads = 0;
+loads++;
+export fn load() { loads++ }
+let hidden = 1;
+`,
+		"lib/util.mk": "export fn twice(x) { x * 2 }",
+		"shared/strings.mk": `export let greeting = "hello";`,
+		"a.mk": `import "b.mk" as b export let a = 1;`,
+		"b.mk": `import "a.mk" as a export let b = 2;`,
+		"main.mk": `import "back.mk" as back`,
+		"back.mk": `import "main.mk" as main export let back = 1;`,
+		"broken.mk": "export let = 1;",
+		"failing.mk": "export let x = 1 + missing;",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	evalMain := func(input string) object.Object {
+		program := parser.New(lexer.NewFile(filepath.Join(dir, "main.mk"), input)).ParseProgram()
+		ev := New()
+		ev.ModulePath = []string{ lib, shared }
+
+		return ev.Eval(program, object.NewEnvironment())
+	}
+
+	t.Run("it should expose the exported names of a module", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	any
+		}{
+			{ `import "lib/math.mk" as math math.square(math.base)`, 49 },
+			{ `import "lib/math.mk" as first import "lib/math.mk" as second first.loads + second.loads`, 2 },
+			{ `import "lib/math.mk" as math math.load(); math.loads`, 2 },
+			{ `import "math.mk" as math math.base`, 7 },
+			{ `import "strings.mk" as s s.greeting`, "hello" },
+		}
+
+		for i, tt := range tests {
+			evaluated := evalMain(tt.input)
+
+			switch expected := tt.expected.(type) {
+
+			case int:
+				if !testIntegerObject(t, evaluated, int64(expected)) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+
+			case string:
+				if !testStringObject(t, evaluated, expected) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+			}
+		}
+	})
+
+	t.Run("it should report import errors", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ `import "nope.mk" as nope`, `cannot find module "nope.mk"` },
+			{ `import "lib" as lib`, `cannot find module "lib"` },
+			{ `import "a.mk" as a`, "import cycle: a.mk -> b.mk -> a.mk" },
+			{ `import "back.mk" as back`, "import cycle: main.mk -> back.mk -> main.mk" },
+			{ `import "broken.mk" as broken`, `cannot import "broken.mk": Expected next token to be 'identifier', but got '=' instead.` },
+			{ `import "failing.mk" as failing`, "identifier not found: missing" },
+			{ `import "math.mk" as math math.hidden`, "module math.mk has no exported member hidden" },
+			{ `let x = 1; x.y`, "INTEGER has no member y" },
+		}
+
+		for i, tt := range tests {
+			evaluated := evalMain(tt.input)
+
+			if !testErrorObject(t, evaluated, tt.expected) {
+				t.Fatalf("[test #%d]\n", i)
+			}
+		}
+	})
+
+	t.Run("it should locate errors in the module they come from", func(t *testing.T) {
+		err := evalMain(`import "failing.mk" as failing`).(*object.Error)
+
+		if err.File != filepath.Join(dir, "failing.mk") || err.Line != 1 || err.Column != 20 {
+			t.Fatalf("Expecting error at failing.mk:1:20, but got %s:%d:%d\n", err.File, err.Line, err.Column)
+		}
+	})
+}
+
+
+func TestEvalHashLiteral(t *testing.T) {
+	input := `let two = "two"; { "one": 10 - 9, two: 1 + 1, "thr" + "ee": 6 / 2, 4: 4, true: 5, false: 6, "one": 1 }`
+
+	evaluated := testEval(input)
+	hash, ok := evaluated.(*object.Hash)
+
+	if !ok {
+		t.Fatalf("Expecting object to be an *object.Hash, but got %T (%+v)\n", evaluated, evaluated)
+	}
+
+	expected := []struct{
+		key		object.Hashable
+		value	int64
+	}{
+		{ &object.String{ Value: "one" }, 1 },
+		{ &object.String{ Value: "two" }, 2 },
+		{ &object.String{ Value: "three" }, 3 },
+		{ &object.Integer{ Value: 4 }, 4 },
+		{ TRUE, 5 },
+		{ FALSE, 6 },
+	}
+
+	if len(hash.Keys) != len(expected) {
+		t.Fatalf("Expecting hash to have %d keys, but got %d\n", len(expected), len(hash.Keys))
+	}
+
+	for i, tt := range expected {
+		if hash.Keys[i] != tt.key.HashKey() {
+			t.Fatalf("[test #%d]: Expecting key %s at position %d\n", i, tt.key.Inspect(), i)
+		}
+		value, _ := hash.Get(tt.key)
+		testIntegerObject(t, value, tt.value)
+	}
+
+	if hash.Inspect() != "{one: 1, two: 2, three: 3, 4: 4, true: 5, false: 6}" {
+		t.Fatalf("Unexpected hash.Inspect(): %q\n", hash.Inspect())
+	}
+}
+
+func TestEvalMemberExpression(t *testing.T) {
+
+	t.Run("it should resolve hash keys, error fields and methods", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	any
+		}{
+			{ `let h = { "a": 1, "b": { "c": 2 } }; h.a + h.b.c`, 3 },
+			{ `let h = { "a": 1 }; h["a"]`, 1 },
+			{ `let h = { "a": 1 }; h.missing`, nil },
+			{ `{ 1: "one" }[1]`, "one" },
+			{ `{ "f": fn(x) { x * 2 } }.f(21)`, 42 },
+			{ `"abc".upper()`, "ABC" },
+			{ `let up = "abc".upper; up()`, "ABC" },
+			{ `[1, 2, 3].map(fn(x) { x * 10 })[2]`, 30 },
+			{ `let k = 3; [1, 2].map(fn(x) { x + k }).map(fn(x) { x * 2 })[1]`, 10 },
+			{ `["a", "b"].map(fn(s) { s.upper() })[1]`, "B" },
+			{ `try { throw "boom" } catch (e) { e.message }`, "boom" },
+			{ `try { [1].map(fn(x) { throw "inside" }) } catch (e) { e.message }`, "inside" },
+		}
+
+		for i, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			switch expected := tt.expected.(type) {
+
+			case int:
+				if !testIntegerObject(t, evaluated, int64(expected)) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+
+			case string:
+				if !testStringObject(t, evaluated, expected) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+
+			case nil:
+				if evaluated != NULL {
+					t.Fatalf("[test #%d]: Expecting NULL, but got %T (%+v)\n", i, evaluated, evaluated)
+				}
+			}
+		}
+	})
+
+	t.Run("it should report invalid member accesses and method calls", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ `let x = 1; x.upper()`, "INTEGER has no member upper" },
+			{ `"abc".nope`, "STRING has no member nope" },
+			{ `"abc".upper(1)`, "wrong number of arguments for upper: want 0, got 1" },
+			{ `[1].map(1)`, "not a function: INTEGER" },
+			{ `[1].map(f: 1)`, "method map does not accept named arguments" },
+			{ `[1].map(fn(x, y) { x + y })`, "missing argument for parameter y" },
+			{ `{ [1]: 2 }`, "unusable as hash key: ARRAY" },
+			{ `{ "a": 1 }[[1]]`, "unusable as hash key: ARRAY" },
+			{ `try { throw "a" } catch (e) { e.nope }`, "unknown error field: nope" },
+		}
+
+		for i, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			if !testErrorObject(t, evaluated, tt.expected) {
+				t.Fatalf("[test #%d]\n", i)
+			}
+		}
+	})
+
+	t.Run("it should locate method errors at the call", func(t *testing.T) {
+		evaluated := testEval("let s = \"abc\";\n  s.upper(1)")
+
+		testErrorPosition(t, evaluated, 2, 10)
+	})
+}
+
+
+func TestEvalBuiltinMethods(t *testing.T) {
+
+	t.Run("it should call the methods of strings, arrays and hashes", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	any
+		}{
+			{ `"a,b,c".split(",").join("-")`, "a-b-c" },
+			{ `"abc".split("").join(" ")`, "a b c" },
+			{ `"  hi \t".trim()`, "hi" },
+			{ `"AbC".lower()`, "abc" },
+			{ `"monkey".contains("key")`, true },
+			{ `"monkey".contains("ape")`, false },
+			{ `"a-b-a".replace("a", "o")`, "o-b-o" },
+			{ `"monkey".startsWith("mon")`, true },
+			{ `"monkey".startsWith("key")`, false },
+			{ `[1, 2, 3, 4].filter(fn(x) { x % 2 == 0 }).join(",")`, "2,4" },
+			{ `[1, 2, 3, 4].reduce(fn(acc, x) { acc + x })`, 10 },
+			{ `[1, 2, 3].reduce(fn(acc, x) { acc + x }, 10)`, 16 },
+			{ `[].reduce(fn(acc, x) { acc + x }, 0)`, 0 },
+			{ `[3, 1.5, 2].sort().join(" ")`, "1.5 2 3" },
+			{ `["b", "c", "a"].sort().join("")`, "abc" },
+			{ `[1, 3, 2].sort(fn(a, b) { b - a }).join("")`, "321" },
+			{ `let a = [2, 1]; a.sort(); a.join("")`, "21" },
+			{ `[1, "a", true].join("|")`, "1|a|true" },
+			{ `[1, 2, 3].indexOf(2)`, 1 },
+			{ `[1, 2, 3].indexOf(2.0)`, 1 },
+			{ `["a"].indexOf("b")`, -1 },
+			{ `{ "b": 1, "a": 2 }.keys().join(",")`, "b,a" },
+			{ `{ "b": 1, "a": 2 }.values().join(",")`, "1,2" },
+			{ `{ 1: "one" }.has(1)`, true },
+			{ `{ 1: "one" }.has("1")`, false },
+			{ `let h = { "a": 1, "b": 2 }; h.delete("a"); h.keys().join(",")`, "b" },
+			{ `{ "a": 1 }.delete("b")`, false },
+			{ `let n = 2; [1, 2, 3].map(fn(x) { x * n }).filter(fn(x) { x > 2 }).reduce(fn(a, b) { a + b })`, 10 },
+		}
+
+		for i, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			switch expected := tt.expected.(type) {
+
+			case int:
+				if !testIntegerObject(t, evaluated, int64(expected)) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+
+			case string:
+				if !testStringObject(t, evaluated, expected) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+
+			case bool:
+				if !testBooleanObject(t, evaluated, expected) {
+					t.Fatalf("[test #%d]\n", i)
+				}
+			}
+		}
+	})
+
+	t.Run("it should report invalid method calls and callback errors", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ `"abc".split(1)`, "argument 1 of split must be a STRING, got INTEGER" },
+			{ `"abc".replace("a")`, "wrong number of arguments for replace: want 2, got 1" },
+			{ `[].reduce(fn(a, b) { a + b })`, "reduce of an empty array without initial value" },
+			{ `[1, "a"].sort()`, "cannot compare STRING and INTEGER" },
+			{ `[1, 2].sort(fn(a, b) { "a" })`, "sort comparison must return a number, got STRING" },
+			{ `[1, 2].sort(fn(a, b) { x })`, "identifier not found: x" },
+			{ `[1, 2].filter(fn(x) { throw "bad element" })`, "bad element" },
+			{ `{}.has([1])`, "unusable as hash key: ARRAY" },
+		}
+
+		for i, tt := range tests {
+			evaluated := testEval(tt.input)
+
+			if !testErrorObject(t, evaluated, tt.expected) {
+				t.Fatalf("[test #%d]\n", i)
+			}
+		}
+	})
+}
+
+func TestEvalSharedNumbers(t *testing.T) {
+
+	t.Run("it should share the small integers", func(t *testing.T) {
+		first := testEval("1 + 1")
+		second := testEval("[1, 2, 3][1]")
+
+		if first != second || first != object.NewInteger(2) {
+			t.Fatalf("Expecting the same object for 2, but got %p and %p\n", first, second)
+		}
+
+		if testEval("1000 * 1000") == testEval("1000000") {
+			t.Fatalf("Expecting large integers not to be shared\n")
+		}
+	})
+
+	t.Run("it should create the object of a number literal once", func(t *testing.T) {
+		result := testEval("let f = fn() { [123456789, 1.5] }; [f(), f()]").(*object.Array)
+		a := result.Elements[0].(*object.Array)
+		b := result.Elements[1].(*object.Array)
+
+		for i := range a.Elements {
+			if a.Elements[i] != b.Elements[i] {
+				t.Fatalf("Expecting the same object for %s, but got two\n", a.Elements[i].Inspect())
+			}
+		}
+	})
+}
+
+func TestEvalResolvedProgram(t *testing.T) {
+
+	t.Run("it should give the same results as without resolution", func(t *testing.T) {
+		tests := []string{
+			"let a = 1; fn f(b) { let c = b * 2; if (c) { a + b + c } } f(3)",
+			"let x = 1; if (true) { let y = x; let x = 2; [y, x] }",
+			"let x = 1; fn f() { let y = x; let x = 2; y } f()",
+			"fn f(a, b = a * 2, ...rest) { [a, b, rest] } [f(1), f(1, b: 5), f(1, 2, 3, 4)]",
+			"let d = 10; fn f(a = d) { let d = 1; a } f()",
+			"fn counter() { let c = 0; fn() { c++; c } } let k = counter(); k(); k()",
+			"let i = 5; let sum = 0; for (i in 0..4) { let j = i * i; sum++ } [i, sum]",
+			"try { throw 1 } catch (e) { e } ",
+			"let e = 1; try { missing } catch (e) { e.message } finally { e }",
+			"const a = 1; if (true) { let a = 2 }",
+			"fn f() { const a = 1; a++ } f()",
+			"let a = 1; if (true) { a++; a++ }; a",
+			"fn f() { g() } fn g() { h } let h = 3; f()",
+			"fn fib(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } } fib(15)",
+			"fn even(n) { if (n == 0) { true } else { odd(n - 1) } } fn odd(n) { if (n == 0) { false } else { even(n - 1) } } even(1001)",
+			"fn f() { y } f()",
+		}
+
+		for i, input := range tests {
+			unresolved := Eval(parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+			resolved := testEval(input)
+
+			if resolved == nil || unresolved == nil {
+				if resolved != unresolved {
+					t.Fatalf("[test #%d] %s\nExpecting %v, but got %v\n", i, input, unresolved, resolved)
+				}
+				continue
+			}
+
+			if resolved.Inspect() != unresolved.Inspect() {
+				t.Fatalf("[test #%d] %s\nExpecting %s, but got %s\n", i, input, unresolved.Inspect(), resolved.Inspect())
+			}
+		}
+	})
+
+	t.Run("it should resolve the imported modules", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "counter.mk"), []byte("export fn count(n) { let total = 0; for (i in 0..n) { total++ } total }"), 0644)
+
+		program := parser.New(lexer.NewFile(filepath.Join(dir, "main.mk"), `import "counter.mk" as c; c.count(5)`)).ParseProgram()
+		resolver.Resolve(program)
+
+		testIntegerObject(t, Eval(program, object.NewEnvironment()), 5)
+	})
+}
+
+// The snippets are shared with the tests of the virtual machine, which
+// must give the same results.
+func TestEvalSnippets(t *testing.T) {
+
+	t.Run("it should give the expected results", func(t *testing.T) {
+		for i, snippet := range corpus.SNIPPETS {
+			if got := testEval(snippet.Input).Inspect(); got != snippet.Expected {
+				t.Errorf("[test #%d] %s\nExpecting %q, but got %q\n", i, snippet.Input, snippet.Expected, got)
+			}
+		}
+	})
+
+	t.Run("it should give the expected results through deep calls", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("skipping the deep snippets in short mode")
+		}
+
+		for i, snippet := range corpus.DEEP_SNIPPETS {
+			if got := testEval(snippet.Input).Inspect(); got != snippet.Expected {
+				t.Errorf("[test #%d] %s\nExpecting %q, but got %q\n", i, snippet.Input, snippet.Expected, got)
+			}
+		}
+	})
+}
+
+func BenchmarkEvalFibonacci(b *testing.B) {
+	program := parser.New(lexer.New(`
+		fn fib(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }
+		fib(20)
+	`)).ParseProgram()
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		Eval(program, object.NewEnvironment())
+	}
+}
+
+func BenchmarkEvalLoop(b *testing.B) {
+	program := parser.New(lexer.New(`
+		let count = 0;
+		for (i in 0..10000) { if (i % 3 * 2 == 0) { count++ } }
+		count
+	`)).ParseProgram()
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		Eval(program, object.NewEnvironment())
+	}
+}
+
+
+func BenchmarkEvalCorpus(b *testing.B) {
+	for _, program := range corpus.Programs() {
+		b.Run(program.Name, func(b *testing.B) {
+			parsed := parser.New(lexer.NewFile(program.File, program.Source)).ParseProgram()
+
+			b.ReportAllocs()
+
+			for b.Loop() {
+				Eval(parsed, object.NewEnvironment())
+			}
+		})
+	}
+}
+
+// BenchmarkEvalResolvedCorpus is BenchmarkEvalCorpus with resolved programs,
+// whose local names are read from slots.
+func BenchmarkEvalResolvedCorpus(b *testing.B) {
+	for _, program := range corpus.Programs() {
+		b.Run(program.Name, func(b *testing.B) {
+			parsed := parser.New(lexer.NewFile(program.File, program.Source)).ParseProgram()
+			resolver.Resolve(parsed)
+
+			b.ReportAllocs()
+
+			for b.Loop() {
+				Eval(parsed, object.NewEnvironment())
+			}
+		})
+	}
+}
+
 
 // Helpers functions:
 
@@ -206,11 +1866,80 @@ func testBooleanObject(t *testing.T, got object.Object, expected bool) bool {
 }
 
 
+func testStringObject(t *testing.T, got object.Object, expected string) bool {
+	obj, ok := got.(*object.String)
+
+	if !ok {
+		t.Errorf(
+			"Expecting obj to be of type object.String, but got %T (%+v)\n",
+			got, got,
+		)
+
+		return false
+	}
+
+	if expected != obj.Value {
+		t.Errorf(
+			"Expecting obj.Value to be %q, but got %q\n",
+			expected, obj.Value,
+		)
+
+		return false
+	}
+
+	return true
+}
+
+
+func testErrorPosition(t *testing.T, got object.Object, line, column int) bool {
+	obj := got.(*object.Error)
+
+	if obj.Line != line || obj.Column != column {
+		t.Errorf(
+			"Expecting error %q at %d:%d, but got %d:%d\n",
+			obj.Message, line, column, obj.Line, obj.Column,
+		)
+
+		return false
+	}
+
+	return true
+}
+
+
+func testErrorObject(t *testing.T, got object.Object, expected string) bool {
+	obj, ok := got.(*object.Error)
+
+	if !ok {
+		t.Errorf(
+			"Expecting obj to be of type object.Error, but got %T (%+v)\n",
+			got, got,
+		)
+
+		return false
+	}
+
+	if expected != obj.Message {
+		t.Errorf(
+			"Expecting obj.Message to be %q, but got %q\n",
+			expected, obj.Message,
+		)
+
+		return false
+	}
+
+	return true
+}
+
+
 func testEval(input string) object.Object {
 	lex := lexer.New(input)
 	parser := parser.New(lex)
 	program := parser.ParseProgram()
+	resolver.Resolve(program)
+
+	env := object.NewEnvironment()
 
-	return Eval(program)
+	return Eval(program, env)
 }
 
@@ -0,0 +1,24 @@
+package evaluator
+
+import (
+	"monkey/internal/ast"
+	"monkey/internal/object"
+)
+
+
+// evalImportStatement bind the module at the imported path to the alias.
+// A module is evaluated the first time it's imported only, later imports
+// share the same module, and so the same bindings.
+func (ev *Evaluator) evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	run := func(program *ast.Program, env *object.Environment) object.Object {
+		return ev.Eval(program, env)
+	}
+	imported := ev.modules.Import(node.Path.Value, node.Token.File, ev.ModulePath, run)
+
+	if isError(imported) {
+		return locateError(imported, node.Token)
+	}
+	env.SetResolved(node.Alias.Value, node.Alias.Binding, imported, false)
+
+	return nil
+}
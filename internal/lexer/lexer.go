@@ -8,9 +8,13 @@ import (
 
 type Lexer struct {
 	input      string
+	file       string
 	currentPos int  // current char position in input (current char)
 	nextPos    int  // next char position (after current char)
 	char       byte // current char under examination
+	line       int  // line of the current char
+	lineStart  int  // position of the first char of the current line
+	previous   token.TokenType // type of the last token read
 }
 
 
@@ -18,17 +22,41 @@ func (lex *Lexer) NextToken() (_token token.Token) {
 
 	lex.skipWhitespace()
 
+	line, column := lex.line, lex.currentPos - lex.lineStart + 1
+
+	defer func() {
+		_token.File = lex.file
+		_token.Line = line
+		_token.Column = column
+		lex.previous = _token.Type
+	}()
+
 	switch {
 
 	case lex.char == 0:
 		_token = lex.newToken(token.EOF, "")
 
+	case lex.char == '"':
+		literal, ok := lex.readString()
+		_token.Literal = literal
+		_token.Type = token.STRING
+
+		// The input ended before the closing quote, so
+		// there is nothing left to read.
+		if !ok {
+			_token.Type = token.ILLEGAL
+			return
+		}
+
 	case lex.isStartOfNumber():
 		literal, _type := lex.readNumber()
 		_token.Literal = literal
 		_token.Type = _type
 		return
 	
+	case lex.isSubtractionOfNegative():
+		_token = lex.newSpecialCharToken(lex.char)
+
 	case lex.isStartOfTwoCharToken():
 		_token = lex.getTwoCharToken()
 
@@ -55,6 +83,11 @@ func (lex *Lexer) NextToken() (_token token.Token) {
 // is the equivalent of NUL, in our case an EOF.
 func (lex *Lexer) readChar() {
 
+	if lex.char == '\n' {
+		lex.line++
+		lex.lineStart = lex.nextPos
+	}
+
 	if lex.nextPos >= len(lex.input) {
 		lex.char = 0
 	} else {
@@ -75,7 +108,7 @@ func (lex *Lexer) peekChar() byte {
 
 // skipWhitespace skip all space, tab, newline or carriage return
 func (lex *Lexer) skipWhitespace() {
-	if slices.Contains([]byte{' ', '\t', '\n', '\r'}, lex.char) {
+	for slices.Contains([]byte{' ', '\t', '\n', '\r'}, lex.char) {
 		lex.readChar()
 	}
 }
@@ -92,7 +125,33 @@ func (lex *Lexer) readWord() string {
 	return lex.input[currentPos:lex.currentPos]
 }
 
+// readString read a string literal delimited by double quotes and
+// return its raw content, escape sequences included. The second value
+// is false if the input ended before the closing quote.
+func (lex *Lexer) readString() (string, bool) {
+	start := lex.nextPos
+
+	for {
+		lex.readChar()
+
+		if lex.char == '\\' && lex.peekChar() != 0 {
+			lex.readChar()
+			continue
+		}
+
+		if lex.char == '"' {
+			return lex.input[start:lex.currentPos], true
+		}
+
+		if lex.char == 0 {
+			return lex.input[start:lex.currentPos], false
+		}
+	}
+}
+
 // readNumber read and return the value and the type of the number.
+// A number contains at most one '.', and a '.' followed by another
+// one is the start of a range like `1..10`.
 func (lex *Lexer) readNumber() (string, token.TokenType) {
 	var number string
 
@@ -100,6 +159,9 @@ func (lex *Lexer) readNumber() (string, token.TokenType) {
 
 	for helper.IsDigit(lex.char) || lex.char == '.' {
 		if lex.char == '.' {
+			if _type == token.FLOAT || lex.peekChar() == '.' {
+				break
+			}
 			_type = token.FLOAT
 			number += string(lex.char)
 		} else {
@@ -128,6 +190,15 @@ func (lex *Lexer) getTwoCharToken() token.Token {
 	lex.readChar()
 	_literal += string(lex.char)
 
+	if _type, ok := token.THREE_CHARS[_literal + string(lex.peekChar())]; ok {
+		lex.readChar()
+
+		return token.Token{
+			Type: _type,
+			Literal: _literal + string(lex.char),
+		}
+	}
+
 	if _type, ok := token.TWO_CHARS[_literal]; ok {
 		return token.Token{
 			Type: _type,
@@ -142,9 +213,24 @@ func (lex *Lexer) getTwoCharToken() token.Token {
 }
 
 // isStartOfTwoCharToken check if the current token is a start
-// of a two character token like '==' or '!='
+// of a two character token like '==', '!=' or '**'
 func (lex *Lexer) isStartOfTwoCharToken() bool {
-	return slices.Contains([]byte{ '=', '!', '<', '>' }, lex.char) && lex.peekChar() == '='
+	_, ok := token.TWO_CHARS[string([]byte{ lex.char, lex.peekChar() })]
+
+	return ok
+}
+
+// isSubtractionOfNegative check if the current token is a '--' that
+// follows a value that can't be decremented, like the literal of
+// `5--3`, in which case it's a minus followed by a negation.
+func (lex *Lexer) isSubtractionOfNegative() bool {
+	if lex.char != '-' || lex.peekChar() != '-' {
+		return false
+	}
+
+	return slices.Contains([]token.TokenType{
+		token.INTEGER, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.RPAREN,
+	}, lex.previous)
 }
 
 // newSpecialCharToken return new special character like '+', '=' token
@@ -156,8 +242,17 @@ func (lex *Lexer) newSpecialCharToken(char byte) token.Token {
 }
 
 func New(input string) *Lexer {
-	lex := &Lexer{input: input}
+	lex := &Lexer{input: input, line: 1}
 	lex.readChar()
 
 	return lex
 }
+
+// NewFile return a lexer over the content of a source file, whose
+// tokens know the name of the file they come from.
+func NewFile(file, input string) *Lexer {
+	lex := New(input)
+	lex.file = file
+
+	return lex
+}
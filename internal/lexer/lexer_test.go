@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"monkey/internal/corpus"
 	"monkey/internal/token"
 	"testing"
 )
@@ -137,6 +138,12 @@ func TestIsStartOfTwoCharToken(t *testing.T) {
 		if lex.isStartOfTwoCharToken() {
 			t.Fatal("Expected isStartOfTwoCharToken() to return false, but got true.")
 		}
+
+		lex = Lexer{ input: "x < -1", currentPos: 2, nextPos: 3, char: '<' }
+
+		if lex.isStartOfTwoCharToken() {
+			t.Fatal("Expected isStartOfTwoCharToken() to return false for '<', but got true.")
+		}
 	})
 
 	t.Run("it should return true", func(t *testing.T) {
@@ -149,6 +156,11 @@ func TestIsStartOfTwoCharToken(t *testing.T) {
 			{ "x != 0", '!' },
 			{ "x <= 0", '<' },
 			{ "x >= 0", '>' },
+			{ "x ** 2", '*' },
+			{ "x << 2", '<' },
+			{ "x >> 2", '>' },
+			{ "x ++ ", '+' },
+			{ "x -- ", '-' },
 		}
 
 		for i, s := range lexDataSlices {
@@ -181,6 +193,20 @@ return false;
 }
 10 == 10;
 10.5 != 9;
+2 ** 3;
+~a & b | c ^ d;
+1 << 2 <= 8 >> 1;
+i++ + --j;
+5--3;
+"foo bar";
+"a\"b";
+for x in 1..10 { }
+1..=3;
+1.5..2;
+a[1:2];
+f(...xs);
+try {} catch (e) {} finally {} throw e
+import "lib.mk" as lib export lib.x .5
 `
 
 	tests := []struct {
@@ -261,6 +287,94 @@ return false;
 		{token.NOT_EQUAL, "!="},
 		{token.INTEGER, "9"},
 		{token.SEMICOLON, ";"},
+		{token.INTEGER, "2"},
+		{token.POWER, "**"},
+		{token.INTEGER, "3"},
+		{token.SEMICOLON, ";"},
+		{token.TILDE, "~"},
+		{token.IDENTIFIER, "a"},
+		{token.AMPERSAND, "&"},
+		{token.IDENTIFIER, "b"},
+		{token.PIPE, "|"},
+		{token.IDENTIFIER, "c"},
+		{token.CARET, "^"},
+		{token.IDENTIFIER, "d"},
+		{token.SEMICOLON, ";"},
+		{token.INTEGER, "1"},
+		{token.LEFT_SHIFT, "<<"},
+		{token.INTEGER, "2"},
+		{token.LESSER_OR_EQUAL_TO, "<="},
+		{token.INTEGER, "8"},
+		{token.RIGHT_SHIFT, ">>"},
+		{token.INTEGER, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "i"},
+		{token.INCREMENT, "++"},
+		{token.PLUS, "+"},
+		{token.DECREMENT, "--"},
+		{token.IDENTIFIER, "j"},
+		{token.SEMICOLON, ";"},
+		{token.INTEGER, "5"},
+		{token.MINUS, "-"},
+		{token.MINUS, "-"},
+		{token.INTEGER, "3"},
+		{token.SEMICOLON, ";"},
+		{token.STRING, "foo bar"},
+		{token.SEMICOLON, ";"},
+		{token.STRING, `a\"b`},
+		{token.SEMICOLON, ";"},
+		{token.FOR, "for"},
+		{token.IDENTIFIER, "x"},
+		{token.IN, "in"},
+		{token.INTEGER, "1"},
+		{token.DOT_DOT, ".."},
+		{token.INTEGER, "10"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.INTEGER, "1"},
+		{token.DOT_DOT_EQUAL, "..="},
+		{token.INTEGER, "3"},
+		{token.SEMICOLON, ";"},
+		{token.FLOAT, "1.5"},
+		{token.DOT_DOT, ".."},
+		{token.INTEGER, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "a"},
+		{token.LBRACKET, "["},
+		{token.INTEGER, "1"},
+		{token.COLON, ":"},
+		{token.INTEGER, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "f"},
+		{token.LPAREN, "("},
+		{token.ELLIPSIS, "..."},
+		{token.IDENTIFIER, "xs"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.TRY, "try"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.CATCH, "catch"},
+		{token.LPAREN, "("},
+		{token.IDENTIFIER, "e"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.FINALLY, "finally"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.THROW, "throw"},
+		{token.IDENTIFIER, "e"},
+		{token.IMPORT, "import"},
+		{token.STRING, "lib.mk"},
+		{token.AS, "as"},
+		{token.IDENTIFIER, "lib"},
+		{token.EXPORT, "export"},
+		{token.IDENTIFIER, "lib"},
+		{token.DOT, "."},
+		{token.IDENTIFIER, "x"},
+		{token.FLOAT, ".5"},
 		{token.EOF, ""},
 	}
 
@@ -286,3 +400,83 @@ return false;
 
 	}
 }
+
+func TestNextTokenPosition(t *testing.T) {
+	input := `let x = 5;
+  x[0..2]
+"unterminated`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENTIFIER, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INTEGER, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENTIFIER, 2, 3},
+		{token.LBRACKET, 2, 4},
+		{token.INTEGER, 2, 5},
+		{token.DOT_DOT, 2, 6},
+		{token.INTEGER, 2, 8},
+		{token.RBRACKET, 2, 9},
+		{token.ILLEGAL, 3, 1},
+		{token.EOF, 3, 14},
+	}
+
+	lex := New(input)
+
+	for i, tt := range tests {
+		_token := lex.NextToken()
+
+		if _token.Type != tt.expectedType {
+			t.Fatalf(
+				"[test #%d] - Wrong token type. Expected %q, got %q\n",
+				i, token.GetLiteralByType(tt.expectedType), token.GetLiteralByType(_token.Type),
+			)
+		}
+
+		if _token.Line != tt.expectedLine || _token.Column != tt.expectedColumn {
+			t.Fatalf(
+				"[test #%d] - Wrong token position. Expected %d:%d, got %d:%d\n",
+				i, tt.expectedLine, tt.expectedColumn, _token.Line, _token.Column,
+			)
+		}
+	}
+}
+
+func TestNextTokenFile(t *testing.T) {
+	lex := NewFile("main.mk", "let x = 5;")
+
+	for _token := lex.NextToken(); ; _token = lex.NextToken() {
+
+		if _token.File != "main.mk" {
+			t.Fatalf("Expecting token %q to come from %q, but got %q\n", _token.Literal, "main.mk", _token.File)
+		}
+
+		if _token.Type == token.EOF {
+			break
+		}
+	}
+
+	if _token := New("x").NextToken(); _token.File != "" {
+		t.Fatalf("Expecting token to have no file, but got %q\n", _token.File)
+	}
+}
+
+
+func BenchmarkNextToken(b *testing.B) {
+	source := corpus.Source(1 << 20)
+
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+
+	for b.Loop() {
+		lex := New(source)
+
+		for tok := lex.NextToken(); tok.Type != token.EOF; tok = lex.NextToken() {
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package module
+
+import (
+	"fmt"
+	"monkey/internal/ast"
+	"monkey/internal/lexer"
+	"monkey/internal/object"
+	"monkey/internal/parser"
+	"monkey/internal/resolver"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+
+// Loader import the modules of a program for the evaluator or the VM: it
+// find the imported files, run each of them once, on its first import, and
+// detect import cycles. Later imports of a file share the same module, and
+// so the same bindings.
+type Loader struct {
+	modules		map[string]*object.Module // by absolute path
+	importing	[]string // modules being loaded, to detect cycles
+}
+
+// Run run the resolved program of a module in env, the environment
+// the module keep its bindings in, returning an error if it fails.
+type Run func(program *ast.Program, env *object.Environment) object.Object
+
+// Import return the module imported as path by the file from, running it
+// with run the first time. A relative path is first looked up from the
+// directory of from, then from each directory of search, in order.
+//
+// The errors of the import itself have no position, for the caller to
+// locate them at the import statement.
+func (l *Loader) Import(path, from string, search []string, run Run) object.Object {
+	resolved, err := Resolve(path, from, search)
+
+	if err != nil {
+		return err
+	}
+
+	if module, ok := l.modules[resolved]; ok {
+		return module
+	}
+
+	return l.load(path, from, resolved, run)
+}
+
+// Resolve return the absolute path of the file imported as path by the
+// file from, as Import look it up.
+func Resolve(path, from string, search []string) (string, *object.Error) {
+	candidates := []string{ path }
+
+	if !filepath.IsAbs(path) {
+		candidates = []string{ filepath.Join(filepath.Dir(from), path) }
+
+		for _, dir := range search {
+			candidates = append(candidates, filepath.Join(dir, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			absolute, err := filepath.Abs(candidate)
+
+			if err != nil {
+				return "", newError("cannot import %q: %s", path, err)
+			}
+			return absolute, nil
+		}
+	}
+
+	return "", newError("cannot find module %q", path)
+}
+
+// load parse, resolve and run the file at resolved in a new environment,
+// then cache the resulting module. Importing a file that is still being
+// loaded is an import cycle, reported with the chain of imports. The
+// file being run isn't imported, but it's loading too until its imports
+// are done, so a cycle going back to it is reported like the other ones.
+func (l *Loader) load(path, from, resolved string, run Run) object.Object {
+	if len(l.importing) == 0 {
+		if main, err := filepath.Abs(from); err == nil {
+			l.importing = append(l.importing, main)
+			defer func() { l.importing = nil }()
+		}
+	}
+
+	if slices.Contains(l.importing, resolved) {
+		chain := l.importing[slices.Index(l.importing, resolved):]
+		names := []string{}
+
+		for _, imported := range append(chain, resolved) {
+			names = append(names, filepath.Base(imported))
+		}
+		return newError("import cycle: %s", strings.Join(names, " -> "))
+	}
+
+	source, err := os.ReadFile(resolved)
+
+	if err != nil {
+		return newError("cannot import %q: %s", path, err)
+	}
+	parser := parser.New(lexer.NewFile(resolved, string(source)))
+	program := parser.ParseProgram()
+
+	if len(parser.Errors()) != 0 {
+		return newError("cannot import %q: %s", path, parser.Errors()[0])
+	}
+
+	// The names a module fail to resolve are reported when it's run,
+	// so diagnostics are only used to check the file being run.
+	resolver.Resolve(program)
+
+	l.importing = append(l.importing, resolved)
+	defer func() { l.importing = l.importing[:len(l.importing) - 1] }()
+
+	module := &object.Module{
+		Path: resolved,
+		Env: object.NewEnvironment(),
+		Exports: map[string]bool{},
+	}
+
+	if result := run(program, module.Env); result != nil && result.Type() == object.ERROR_OBJ {
+		return result
+	}
+
+	for _, stmt := range program.Statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			module.Exports[export.Name()] = true
+		}
+	}
+
+	if l.modules == nil {
+		l.modules = map[string]*object.Module{}
+	}
+	l.modules[resolved] = module
+
+	return module
+}
+
+func newError(format string, args ...any) *object.Error {
+	return &object.Error{ Message: fmt.Sprintf(format, args...) }
+}
@@ -0,0 +1,107 @@
+package object
+
+import (
+	"bytes"
+	"monkey/internal/ast"
+	"monkey/internal/code"
+	"slices"
+	"strings"
+)
+
+
+// CompiledFunction is the bytecode of a function, or of a whole program,
+// along with the constant pool its instructions refer to.
+//
+// Scope is the scope the resolver gave the body of the function, whose
+// environment hold the parameters, and Blocks the ones of the blocks
+// entered with OpPushScope, so the environments of a call have the slots
+// its instructions refer to. They're empty when the source wasn't resolved.
+type CompiledFunction struct {
+	Name			string // empty for anonymous functions and programs
+	Parameters		[]CompiledParameter
+	Instructions	code.Instructions
+	Constants		[]Object // shared by all the functions compiled together
+	File			string
+	Positions		[]Position // of the statements and the instructions that can fail, sorted by offset
+	Scope			*ast.Scope
+	Blocks			[]*ast.Scope // by OpPushScope operand
+}
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string { return cf.Signature() + " { <compiled> }" }
+
+// Signature return the name and parameters of the function,
+// as they're written in its declaration.
+func (cf *CompiledFunction) Signature() string {
+	var output bytes.Buffer
+
+	params := []string{}
+
+	for _, param := range cf.Parameters {
+		params = append(params, param.String())
+	}
+
+	output.WriteString("fn")
+
+	if cf.Name != "" {
+		output.WriteString(" " + cf.Name)
+	}
+
+	output.WriteString("(")
+	output.WriteString(strings.Join(params, ", "))
+	output.WriteString(")")
+
+	return output.String()
+}
+
+// PositionAt return the position of the source expression the
+// instruction at offset was compiled from, if it's known.
+func (cf *CompiledFunction) PositionAt(offset int) (Position, bool) {
+	i, ok := slices.BinarySearchFunc(cf.Positions, offset, func(position Position, offset int) int {
+		return position.Offset - offset
+	})
+
+	if !ok {
+		return Position{}, false
+	}
+
+	return cf.Positions[i], true
+}
+
+// CompiledParameter is a function parameter. Its default value, if any,
+// is compiled as a function without parameters, evaluated in the
+// environment of the call.
+type CompiledParameter struct {
+	Name		string
+	Default		*CompiledFunction
+	Rest		bool
+}
+
+func (param CompiledParameter) String() string {
+	switch {
+
+	case param.Rest:
+		return "..." + param.Name
+
+	case param.Default != nil:
+		return param.Name + " = ..."
+	}
+
+	return param.Name
+}
+
+// Position locate the source of the instruction at Offset.
+type Position struct {
+	Offset		int
+	Line		int
+	Column		int
+}
+
+// Closure is a compiled function along with the environment it was
+// created in. For the program, it's a function like any other, so
+// its type is FUNCTION.
+type Closure struct {
+	Fn		*CompiledFunction
+	Env		*Environment
+}
+func (c *Closure) Type() ObjectType { return FUNCTION_OBJ }
+func (c *Closure) Inspect() string { return c.Fn.Inspect() }
@@ -0,0 +1,254 @@
+package object
+
+import "monkey/internal/ast"
+
+
+// Environment hold the values bound to names with `let` and `const`.
+// An enclosed environment fall back to its outer one for the names
+// it doesn't bind itself.
+//
+// The environment of a resolved block keep the names of its scope in
+// slots, indexed like the scope, that resolved identifiers read without
+// looking their name up. Other names are kept in maps.
+type Environment struct {
+	store		map[string]Object
+	constants	map[string]bool
+	scope		*ast.Scope
+	slots		[]slot
+	outer		*Environment
+}
+
+// slot hold the value bound to a name of a scope, nil until it's declared.
+type slot struct {
+	value		Object
+	constant	bool
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{
+		store: make(map[string]Object),
+		constants: make(map[string]bool),
+	}
+}
+
+// NewEnclosedEnvironment return a new environment, like for a
+// block body, whose lookups fall back to outer.
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+
+	return env
+}
+
+// NewScopeEnvironment return a new environment enclosed in outer for a
+// block the resolver gave scope to, or a plain enclosed one if scope is nil.
+func NewScopeEnvironment(outer *Environment, scope *ast.Scope) *Environment {
+	if scope == nil {
+		return NewEnclosedEnvironment(outer)
+	}
+
+	return &Environment{
+		scope: scope,
+		slots: make([]slot, len(scope.Names)),
+		outer: outer,
+	}
+}
+
+// Outer return the environment this one fall back to,
+// nil for a top-level environment.
+func (env *Environment) Outer() *Environment {
+	return env.outer
+}
+
+// Get return the value bound to name, if any.
+func (env *Environment) Get(name string) (Object, bool) {
+	if i := env.slotIndex(name); i >= 0 && env.slots[i].value != nil {
+		return env.slots[i].value, true
+	}
+	obj, ok := env.store[name]
+
+	if !ok && env.outer != nil {
+		return env.outer.Get(name)
+	}
+
+	return obj, ok
+}
+
+// Set bind a value to name in this environment, creating
+// the binding if needed.
+func (env *Environment) Set(name string, value Object) Object {
+	if i := env.slotIndex(name); i >= 0 {
+		env.slots[i].value = value
+		return value
+	}
+
+	if env.store == nil {
+		env.store = make(map[string]Object)
+		env.constants = make(map[string]bool)
+	}
+	env.store[name] = value
+
+	return value
+}
+
+// SetConstant bind a value to name and mark the binding as constant,
+// so it can't be updated later.
+func (env *Environment) SetConstant(name string, value Object) Object {
+	if i := env.slotIndex(name); i >= 0 {
+		env.slots[i].constant = true
+		return env.Set(name, value)
+	}
+	env.Set(name, value)
+	env.constants[name] = true
+
+	return value
+}
+
+// Assign update an existing binding in the environment that
+// define it. It return false if name is not bound.
+func (env *Environment) Assign(name string, value Object) bool {
+	if i := env.slotIndex(name); i >= 0 && env.slots[i].value != nil {
+		env.slots[i].value = value
+		return true
+	}
+
+	if _, ok := env.store[name]; ok {
+		env.store[name] = value
+		return true
+	}
+
+	if env.outer != nil {
+		return env.outer.Assign(name, value)
+	}
+
+	return false
+}
+
+// IsConstant report whether the binding name resolve to
+// was declared with `const`.
+func (env *Environment) IsConstant(name string) bool {
+	if i := env.slotIndex(name); i >= 0 && env.slots[i].value != nil {
+		return env.slots[i].constant
+	}
+
+	if _, ok := env.store[name]; ok {
+		return env.constants[name]
+	}
+
+	if env.outer != nil {
+		return env.outer.IsConstant(name)
+	}
+
+	return false
+}
+
+// GetResolved return the value bound to an identifier named name with
+// binding. A slot not declared yet, like a name used before its `let`,
+// fall back to looking name up, so the result is always the one of Get.
+func (env *Environment) GetResolved(name string, binding ast.Binding) (Object, bool) {
+	switch binding.Kind {
+
+	case ast.LOCAL:
+		if s := env.resolvedSlot(name, binding); s != nil && s.value != nil {
+			return s.value, true
+		}
+
+	case ast.GLOBAL:
+		if global := env.up(binding.Depth); global != nil {
+			return global.Get(name)
+		}
+	}
+
+	return env.Get(name)
+}
+
+// SetResolved bind a value to a declared identifier named name with
+// binding, marking it as constant when asked, like Set and SetConstant.
+func (env *Environment) SetResolved(name string, binding ast.Binding, value Object, constant bool) Object {
+	if binding.Kind == ast.LOCAL && binding.Depth == 0 {
+		if s := env.resolvedSlot(name, binding); s != nil {
+			s.value = value
+			s.constant = s.constant || constant
+			return value
+		}
+	}
+
+	if constant {
+		return env.SetConstant(name, value)
+	}
+
+	return env.Set(name, value)
+}
+
+// AssignResolved update the binding of an identifier named name with
+// binding, like Assign.
+func (env *Environment) AssignResolved(name string, binding ast.Binding, value Object) bool {
+	switch binding.Kind {
+
+	case ast.LOCAL:
+		if s := env.resolvedSlot(name, binding); s != nil && s.value != nil {
+			s.value = value
+			return true
+		}
+
+	case ast.GLOBAL:
+		if global := env.up(binding.Depth); global != nil {
+			return global.Assign(name, value)
+		}
+	}
+
+	return env.Assign(name, value)
+}
+
+// IsConstantResolved report whether the binding of an identifier named
+// name with binding was declared with `const`, like IsConstant.
+func (env *Environment) IsConstantResolved(name string, binding ast.Binding) bool {
+	switch binding.Kind {
+
+	case ast.LOCAL:
+		if s := env.resolvedSlot(name, binding); s != nil && s.value != nil {
+			return s.constant
+		}
+
+	case ast.GLOBAL:
+		if global := env.up(binding.Depth); global != nil {
+			return global.IsConstant(name)
+		}
+	}
+
+	return env.IsConstant(name)
+}
+
+// resolvedSlot return the slot a LOCAL binding refer to, or nil if the
+// environments don't have the layout the resolver gave them.
+func (env *Environment) resolvedSlot(name string, binding ast.Binding) *slot {
+	target := env.up(binding.Depth)
+
+	if target == nil || binding.Slot >= len(target.slots) || target.scope.Names[binding.Slot] != name {
+		return nil
+	}
+
+	return &target.slots[binding.Slot]
+}
+
+func (env *Environment) up(depth int) *Environment {
+	for ; depth > 0 && env != nil; depth-- {
+		env = env.outer
+	}
+
+	return env
+}
+
+func (env *Environment) slotIndex(name string) int {
+	if env.scope == nil {
+		return -1
+	}
+
+	for i, scoped := range env.scope.Names {
+		if scoped == name {
+			return i
+		}
+	}
+
+	return -1
+}
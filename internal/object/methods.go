@@ -0,0 +1,471 @@
+package object
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+
+// ApplyFunction call fn, a Monkey function or any other callable value,
+// with args. It's how a builtin method call back into the program.
+type ApplyFunction func(fn Object, args ...Object) Object
+
+// Method is a builtin method, implemented in Go, called on receiver.
+// Like any Monkey function, it report failures by returning an *Error.
+type Method func(apply ApplyFunction, receiver Object, args ...Object) Object
+
+// BoundMethod is a builtin method taken from a value, like `"abc".upper`,
+// which remember the value it will be called on.
+type BoundMethod struct {
+	Name		string
+	Receiver	Object
+	Method		Method
+}
+func (bm *BoundMethod) Type() ObjectType { return METHOD_OBJ }
+func (bm *BoundMethod) Inspect() string {
+	return fmt.Sprintf("<method %s.%s>", strings.ToLower(bm.Receiver.Type().String()), bm.Name)
+}
+
+// METHODS hold the method table of each type having builtin methods.
+// Methods never modify their receiver, except `delete` on hashes.
+var METHODS = map[ObjectType]map[string]Method{
+	STRING_OBJ: {
+		"split": stringSplit,
+		"trim": stringTrim,
+		"upper": stringUpper,
+		"lower": stringLower,
+		"contains": stringContains,
+		"replace": stringReplace,
+		"startsWith": stringStartsWith,
+	},
+	ARRAY_OBJ: {
+		"map": arrayMap,
+		"filter": arrayFilter,
+		"reduce": arrayReduce,
+		"sort": arraySort,
+		"join": arrayJoin,
+		"indexOf": arrayIndexOf,
+	},
+	HASH_OBJ: {
+		"keys": hashKeys,
+		"values": hashValues,
+		"has": hashHas,
+		"delete": hashDelete,
+	},
+}
+
+// LookupMethod return the builtin method name of receiver bound
+// to it, if its type has one.
+func LookupMethod(receiver Object, name string) (*BoundMethod, bool) {
+	method, ok := METHODS[receiver.Type()][name]
+
+	if !ok {
+		return nil, false
+	}
+
+	return &BoundMethod{ Name: name, Receiver: receiver, Method: method }, true
+}
+
+
+// String methods:
+
+
+// stringSplit split the string around each occurrence of the separator.
+// An empty separator split it into its characters, one rune per string.
+func stringSplit(apply ApplyFunction, receiver Object, args ...Object) Object {
+	separator, err := stringArgument("split", args, 0, 1)
+
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(receiver.(*String).Value, separator)
+	elements := make([]Object, len(parts))
+
+	for i, part := range parts {
+		elements[i] = &String{ Value: part }
+	}
+
+	return &Array{ Elements: elements }
+}
+
+// stringTrim remove the leading and trailing white spaces.
+func stringTrim(apply ApplyFunction, receiver Object, args ...Object) Object {
+	if err := checkArgumentCount("trim", args, 0); err != nil {
+		return err
+	}
+
+	return &String{ Value: strings.TrimSpace(receiver.(*String).Value) }
+}
+
+func stringUpper(apply ApplyFunction, receiver Object, args ...Object) Object {
+	if err := checkArgumentCount("upper", args, 0); err != nil {
+		return err
+	}
+
+	return &String{ Value: strings.ToUpper(receiver.(*String).Value) }
+}
+
+func stringLower(apply ApplyFunction, receiver Object, args ...Object) Object {
+	if err := checkArgumentCount("lower", args, 0); err != nil {
+		return err
+	}
+
+	return &String{ Value: strings.ToLower(receiver.(*String).Value) }
+}
+
+func stringContains(apply ApplyFunction, receiver Object, args ...Object) Object {
+	substring, err := stringArgument("contains", args, 0, 1)
+
+	if err != nil {
+		return err
+	}
+
+	return NativeBool(strings.Contains(receiver.(*String).Value, substring))
+}
+
+// stringReplace replace every occurrence of its first argument
+// by the second one.
+func stringReplace(apply ApplyFunction, receiver Object, args ...Object) Object {
+	old, err := stringArgument("replace", args, 0, 2)
+
+	if err != nil {
+		return err
+	}
+	replacement, err := stringArgument("replace", args, 1, 2)
+
+	if err != nil {
+		return err
+	}
+
+	return &String{ Value: strings.ReplaceAll(receiver.(*String).Value, old, replacement) }
+}
+
+func stringStartsWith(apply ApplyFunction, receiver Object, args ...Object) Object {
+	prefix, err := stringArgument("startsWith", args, 0, 1)
+
+	if err != nil {
+		return err
+	}
+
+	return NativeBool(strings.HasPrefix(receiver.(*String).Value, prefix))
+}
+
+
+// Array methods:
+
+
+// arrayMap return a new array holding the result of fn called
+// on each element.
+func arrayMap(apply ApplyFunction, receiver Object, args ...Object) Object {
+	if err := checkArgumentCount("map", args, 1); err != nil {
+		return err
+	}
+	elements := receiver.(*Array).Elements
+	mapped := make([]Object, len(elements))
+
+	for i, element := range elements {
+		result := apply(args[0], element)
+
+		if isError(result) {
+			return result
+		}
+		mapped[i] = result
+	}
+
+	return &Array{ Elements: mapped }
+}
+
+// arrayFilter return a new array holding the elements for which
+// fn return a truthy value.
+func arrayFilter(apply ApplyFunction, receiver Object, args ...Object) Object {
+	if err := checkArgumentCount("filter", args, 1); err != nil {
+		return err
+	}
+	filtered := []Object{}
+
+	for _, element := range receiver.(*Array).Elements {
+		result := apply(args[0], element)
+
+		if isError(result) {
+			return result
+		}
+
+		if IsTruthy(result) {
+			filtered = append(filtered, element)
+		}
+	}
+
+	return &Array{ Elements: filtered }
+}
+
+// arrayReduce combine the elements from left to right with fn, called
+// with the accumulated value and an element. Without initial value,
+// the first element is used, so the array must not be empty.
+func arrayReduce(apply ApplyFunction, receiver Object, args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments for reduce: want 1 or 2, got %d", len(args))
+	}
+	elements := receiver.(*Array).Elements
+
+	var accumulator Object
+
+	if len(args) == 2 {
+		accumulator = args[1]
+	} else if len(elements) == 0 {
+		return newError("reduce of an empty array without initial value")
+	} else {
+		accumulator, elements = elements[0], elements[1:]
+	}
+
+	for _, element := range elements {
+		accumulator = apply(args[0], accumulator, element)
+
+		if isError(accumulator) {
+			return accumulator
+		}
+	}
+
+	return accumulator
+}
+
+// arraySort return a new array holding the elements in increasing
+// order, keeping the order of equal elements. Without comparison
+// function, the elements must be all numbers or all strings. The
+// comparison function is called with two elements and return a
+// negative number when the first one come first, a positive number
+// when it come last, and zero when they're equal.
+func arraySort(apply ApplyFunction, receiver Object, args ...Object) Object {
+	if len(args) > 1 {
+		return newError("wrong number of arguments for sort: want 0 or 1, got %d", len(args))
+	}
+	sorted := slices.Clone(receiver.(*Array).Elements)
+
+	var failure Object
+
+	compare := func(a, b Object) int {
+		if failure != nil {
+			return 0
+		}
+		result, err := compareObjects(a, b)
+
+		if len(args) == 1 {
+			result, err = 0, nil
+			order := apply(args[0], a, b)
+
+			switch order := order.(type) {
+
+			case *Integer:
+				result = cmp.Compare(order.Value, 0)
+
+			case *Float:
+				result = cmp.Compare(order.Value, 0)
+
+			case *Error:
+				err = order
+
+			default:
+				err = newError("sort comparison must return a number, got %s", order.Type())
+			}
+		}
+
+		if err != nil {
+			failure = err
+		}
+		return result
+	}
+	slices.SortStableFunc(sorted, compare)
+
+	if failure != nil {
+		return failure
+	}
+
+	return &Array{ Elements: sorted }
+}
+
+// arrayJoin concatenate the elements, with the separator between them.
+// Strings are joined as is, other elements as they're printed.
+func arrayJoin(apply ApplyFunction, receiver Object, args ...Object) Object {
+	separator, err := stringArgument("join", args, 0, 1)
+
+	if err != nil {
+		return err
+	}
+	parts := []string{}
+
+	for _, element := range receiver.(*Array).Elements {
+		parts = append(parts, element.Inspect())
+	}
+
+	return &String{ Value: strings.Join(parts, separator) }
+}
+
+// arrayIndexOf return the position of the first element equal to
+// the argument, or -1 if there is none.
+func arrayIndexOf(apply ApplyFunction, receiver Object, args ...Object) Object {
+	if err := checkArgumentCount("indexOf", args, 1); err != nil {
+		return err
+	}
+	index := slices.IndexFunc(receiver.(*Array).Elements, func(element Object) bool {
+		return Equal(element, args[0])
+	})
+
+	return NewInteger(int64(index))
+}
+
+
+// Hash methods:
+
+
+// hashKeys return the keys, in the order they were first set in.
+func hashKeys(apply ApplyFunction, receiver Object, args ...Object) Object {
+	if err := checkArgumentCount("keys", args, 0); err != nil {
+		return err
+	}
+	hash := receiver.(*Hash)
+	keys := make([]Object, len(hash.Keys))
+
+	for i, key := range hash.Keys {
+		keys[i] = hash.Pairs[key].Key
+	}
+
+	return &Array{ Elements: keys }
+}
+
+// hashValues return the values, in the order of their keys.
+func hashValues(apply ApplyFunction, receiver Object, args ...Object) Object {
+	if err := checkArgumentCount("values", args, 0); err != nil {
+		return err
+	}
+	hash := receiver.(*Hash)
+	values := make([]Object, len(hash.Keys))
+
+	for i, key := range hash.Keys {
+		values[i] = hash.Pairs[key].Value
+	}
+
+	return &Array{ Elements: values }
+}
+
+func hashHas(apply ApplyFunction, receiver Object, args ...Object) Object {
+	key, err := hashableArgument("has", args)
+
+	if err != nil {
+		return err
+	}
+	_, ok := receiver.(*Hash).Get(key)
+
+	return NativeBool(ok)
+}
+
+// hashDelete remove the key from the hash, and report whether
+// it was there.
+func hashDelete(apply ApplyFunction, receiver Object, args ...Object) Object {
+	key, err := hashableArgument("delete", args)
+
+	if err != nil {
+		return err
+	}
+
+	return NativeBool(receiver.(*Hash).Delete(key))
+}
+
+
+// Equal report whether a and b are the same value: numbers are compared
+// by value, even between integers and floats, like strings and booleans.
+// Other objects are only equal to themselves.
+func Equal(a, b Object) bool {
+	switch a := a.(type) {
+
+	case *Integer, *Float:
+		if b.Type() != INTEGER_OBJ && b.Type() != FLOAT_OBJ {
+			return false
+		}
+		return toFloat(a) == toFloat(b)
+
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	}
+
+	return a == b
+}
+
+// compareObjects order two numbers or two strings.
+func compareObjects(a, b Object) (int, *Error) {
+	aString, aIsString := a.(*String)
+	bString, bIsString := b.(*String)
+
+	switch {
+
+	case aIsString && bIsString:
+		return strings.Compare(aString.Value, bString.Value), nil
+
+	case isNumber(a) && isNumber(b):
+		return cmp.Compare(toFloat(a), toFloat(b)), nil
+	}
+
+	return 0, newError("cannot compare %s and %s", a.Type(), b.Type())
+}
+
+func isNumber(obj Object) bool {
+	return obj.Type() == INTEGER_OBJ || obj.Type() == FLOAT_OBJ
+}
+
+func toFloat(obj Object) float64 {
+	if i, ok := obj.(*Integer); ok {
+		return float64(i.Value)
+	}
+
+	return obj.(*Float).Value
+}
+
+func isError(obj Object) bool {
+	return obj != nil && obj.Type() == ERROR_OBJ
+}
+
+
+func checkArgumentCount(method string, args []Object, want int) *Error {
+	if len(args) != want {
+		return newError("wrong number of arguments for %s: want %d, got %d", method, want, len(args))
+	}
+
+	return nil
+}
+
+// stringArgument return the string argument at position i of a method
+// taking count arguments.
+func stringArgument(method string, args []Object, i, count int) (string, *Error) {
+	if err := checkArgumentCount(method, args, count); err != nil {
+		return "", err
+	}
+	value, ok := args[i].(*String)
+
+	if !ok {
+		return "", newError("argument %d of %s must be a STRING, got %s", i + 1, method, args[i].Type())
+	}
+
+	return value.Value, nil
+}
+
+func hashableArgument(method string, args []Object) (Hashable, *Error) {
+	if err := checkArgumentCount(method, args, 1); err != nil {
+		return nil, err
+	}
+	key, ok := args[0].(Hashable)
+
+	if !ok {
+		return nil, newError("unusable as hash key: %s", args[0].Type())
+	}
+
+	return key, nil
+}
+
+func newError(format string, args ...any) *Error {
+	return &Error{ Message: fmt.Sprintf(format, args...) }
+}
@@ -1,6 +1,13 @@
 package object
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"monkey/internal/ast"
+	"slices"
+	"strings"
+)
 
 
 type ObjectType int
@@ -11,8 +18,45 @@ const (
 	INTEGER_OBJ
 	FLOAT_OBJ
 	BOOLEAN_OBJ
+	STRING_OBJ
+	ARRAY_OBJ
+	HASH_OBJ
+	RANGE_OBJ
+	FUNCTION_OBJ
+	RETURN_VALUE_OBJ
+	TAIL_CALL_OBJ
+	ERROR_OBJ
+	ERROR_VALUE_OBJ
+	MODULE_OBJ
+	METHOD_OBJ
+	COMPILED_FUNCTION_OBJ
+	ITERATOR_OBJ
+	ARGUMENT_OBJ
 )
 
+var TYPE_NAMES = map[ObjectType]string{
+	NULL_OBJ: "NULL",
+	INTEGER_OBJ: "INTEGER",
+	FLOAT_OBJ: "FLOAT",
+	BOOLEAN_OBJ: "BOOLEAN",
+	STRING_OBJ: "STRING",
+	ARRAY_OBJ: "ARRAY",
+	HASH_OBJ: "HASH",
+	RANGE_OBJ: "RANGE",
+	FUNCTION_OBJ: "FUNCTION",
+	RETURN_VALUE_OBJ: "RETURN_VALUE",
+	TAIL_CALL_OBJ: "TAIL_CALL",
+	ERROR_OBJ: "ERROR",
+	ERROR_VALUE_OBJ: "ERROR_VALUE",
+	MODULE_OBJ: "MODULE",
+	METHOD_OBJ: "METHOD",
+	COMPILED_FUNCTION_OBJ: "COMPILED_FUNCTION",
+	ITERATOR_OBJ: "ITERATOR",
+	ARGUMENT_OBJ: "ARGUMENT",
+}
+
+func (t ObjectType) String() string { return TYPE_NAMES[t] }
+
 
 type Object interface {
 	Type()		ObjectType
@@ -26,6 +70,33 @@ type Integer struct {
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
 
+// The integers from SMALL_INTEGER_MIN to SMALL_INTEGER_MAX are created
+// once and shared, like TRUE and FALSE, as they're the most used ones.
+const (
+	SMALL_INTEGER_MIN = -128
+	SMALL_INTEGER_MAX = 1023
+)
+
+var smallIntegers = func() []*Integer {
+	integers := make([]*Integer, SMALL_INTEGER_MAX - SMALL_INTEGER_MIN + 1)
+
+	for i := range integers {
+		integers[i] = &Integer{ Value: int64(i + SMALL_INTEGER_MIN) }
+	}
+
+	return integers
+}()
+
+// NewInteger return an Integer holding value, which is shared when
+// it's small. Integers are never modified, so sharing them is safe.
+func NewInteger(value int64) *Integer {
+	if value >= SMALL_INTEGER_MIN && value <= SMALL_INTEGER_MAX {
+		return smallIntegers[value - SMALL_INTEGER_MIN]
+	}
+
+	return &Integer{ Value: value }
+}
+
 
 
 type Float struct {
@@ -44,10 +115,376 @@ func (b *Boolean) Inspect() string { return fmt.Sprintf("%t", b.Value) }
 
 
 
+type String struct {
+	Value		string
+}
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string { return s.Value }
+
+
+
+type Array struct {
+	Elements	[]Object
+}
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	var output bytes.Buffer
+
+	elements := []string{}
+
+	for _, element := range a.Elements {
+		elements = append(elements, element.Inspect())
+	}
+
+	output.WriteString("[")
+	output.WriteString(strings.Join(elements, ", "))
+	output.WriteString("]")
+
+	return output.String()
+}
+
+
+
+// HashKey identify the value of a key in a hash: two keys of
+// the same type and value have the same HashKey.
+type HashKey struct {
+	Type	ObjectType
+	Value	uint64
+}
+
+// Hashable is implemented by the objects usable as hash keys.
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{ Type: i.Type(), Value: uint64(i.Value) }
+}
+
+func (b *Boolean) HashKey() HashKey {
+	if b.Value {
+		return HashKey{ Type: b.Type(), Value: 1 }
+	}
+
+	return HashKey{ Type: b.Type(), Value: 0 }
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+
+	return HashKey{ Type: s.Type(), Value: h.Sum64() }
+}
+
+
+type HashPair struct {
+	Key		Object
+	Value	Object
+}
+
+// Hash map keys to values. Keys remember the order they were first
+// set in, so a hash is always listed in the same order.
+type Hash struct {
+	Pairs	map[HashKey]HashPair
+	Keys	[]HashKey // in insertion order
+}
+
+func NewHash() *Hash {
+	return &Hash{ Pairs: map[HashKey]HashPair{} }
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string {
+	var output bytes.Buffer
+
+	pairs := []string{}
+
+	for _, key := range h.Keys {
+		pair := h.Pairs[key]
+		pairs = append(pairs, pair.Key.Inspect() + ": " + pair.Value.Inspect())
+	}
+
+	output.WriteString("{")
+	output.WriteString(strings.Join(pairs, ", "))
+	output.WriteString("}")
+
+	return output.String()
+}
+
+// Get return the value of key, if any.
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.Pairs[key.HashKey()]
+
+	return pair.Value, ok
+}
+
+// Delete remove key from the hash, and report whether it was set.
+func (h *Hash) Delete(key Hashable) bool {
+	hashKey := key.HashKey()
+
+	if _, ok := h.Pairs[hashKey]; !ok {
+		return false
+	}
+	delete(h.Pairs, hashKey)
+	h.Keys = slices.DeleteFunc(h.Keys, func(k HashKey) bool { return k == hashKey })
+
+	return true
+}
+
+// Set bind value to key, keeping the position of key if it's already set.
+func (h *Hash) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+
+	if _, ok := h.Pairs[hashKey]; !ok {
+		h.Keys = append(h.Keys, hashKey)
+	}
+	h.Pairs[hashKey] = HashPair{ Key: key, Value: value }
+}
+
+
+
+// Range is the lazy sequence of integers produced by `start..end`,
+// or `start..=end` when Inclusive. Its values are computed when
+// needed, so a range never hold more than its bounds.
+type Range struct {
+	Start		int64
+	End			int64
+	Inclusive	bool
+}
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	if r.Inclusive {
+		return fmt.Sprintf("%d..=%d", r.Start, r.End)
+	}
+
+	return fmt.Sprintf("%d..%d", r.Start, r.End)
+}
+
+// Len return the number of integers in the range. A range
+// whose end is before its start is empty.
+func (r *Range) Len() int64 {
+	length := r.End - r.Start
+
+	if r.Inclusive {
+		length++
+	}
+
+	return max(length, 0)
+}
+
+
+
+type Function struct {
+	Name		string // empty for anonymous functions
+	Parameters	[]*ast.Parameter
+	Body		*ast.BlockStatement
+	Env			*Environment
+}
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+func (f *Function) Inspect() string {
+	var output bytes.Buffer
+
+	params := []string{}
+
+	for _, param := range f.Parameters {
+		params = append(params, param.String())
+	}
+
+	output.WriteString("fn")
+
+	if f.Name != "" {
+		output.WriteString(" " + f.Name)
+	}
+
+	output.WriteString("(")
+	output.WriteString(strings.Join(params, ", "))
+	output.WriteString(") ")
+	output.WriteString(f.Body.String())
+
+	return output.String()
+}
+
+
+
+// Module is an imported file. Its members are the names it exports,
+// read from the environment it was evaluated in, so they always hold
+// their current value.
+type Module struct {
+	Path	string
+	Env		*Environment
+	Exports	map[string]bool
+}
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string { return "<module " + m.Path + ">" }
+
+// Member return the value of the exported name, if any.
+func (m *Module) Member(name string) (Object, bool) {
+	if !m.Exports[name] {
+		return nil, false
+	}
+
+	return m.Env.Get(name)
+}
+
+// ReturnValue wrap the value of a `return` statement while
+// it's carried up to the function call.
+type ReturnValue struct {
+	Value		Object
+}
+func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
+func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
+
+
+
 type Null struct {}
 func (b *Null) Type() ObjectType { return NULL_OBJ }
 func (b *Null) Inspect() string { return "null" }
 
+// The only null and boolean values, so they can be compared by pointer.
+var (
+	NULL	= &Null{}
+	TRUE	= &Boolean{ Value: true }
+	FALSE	= &Boolean{ Value: false }
+)
+
+func NativeBool(value bool) *Boolean {
+	if value {
+		return TRUE
+	}
+
+	return FALSE
+}
+
+// IsTruthy report whether obj is considered true by conditions:
+// false, null and zero numbers are falsy, anything else is truthy.
+func IsTruthy(obj Object) bool {
+	switch obj := obj.(type) {
+
+	case *Boolean:
+		return obj.Value
 
+	case *Integer:
+		return obj.Value != 0
 
+	case *Float:
+		return obj.Value != 0
 
+	case *Null, nil:
+		return false
+
+	default:
+		return true
+	}
+}
+
+
+
+// Kinds of errors that a program may want to tell apart from the others.
+const (
+	GENERIC_ERROR			= "Error" // kind exposed for an error without kind
+	STACK_OVERFLOW_ERROR	= "StackOverflowError"
+	BUDGET_EXCEEDED_ERROR	= "BudgetExceededError" // context done, step or allocation limit exceeded
+)
+
+// Error is a runtime error. Stack hold the function calls the error
+// went through, from the innermost one to the outermost one.
+type Error struct {
+	Kind		string // empty for a generic error
+	Message		string
+	File		string
+	Line		int
+	Column		int
+	Stack		[]StackFrame
+}
+func (e *Error) Type() ObjectType { return ERROR_OBJ }
+func (e *Error) Inspect() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("ERROR: %s (line %d, column %d)", e.describe(), e.Line, e.Column)
+	}
+
+	return "ERROR: " + e.describe()
+}
+
+// describe return the message of the error, prefixed by its kind if any.
+func (e *Error) describe() string {
+	if e.Kind != "" {
+		return e.Kind + ": " + e.Message
+	}
+
+	return e.Message
+}
+
+// MAX_STACK_TRACE_FRAMES is the number of calls a stack trace list
+// before eliding the outer ones, like Go does.
+const MAX_STACK_TRACE_FRAMES = 100
+
+// StackTrace return the error message followed by the active calls,
+// like a Go panic: each function is listed with the position it was
+// at, the innermost one first, down to the top level of the program.
+//
+//	ERROR: identifier not found: x
+//
+//	inner(...)
+//		main.mk:2:5
+//	outer(...)
+//		main.mk:6:10
+//	<main>
+//		main.mk:9:6
+func (e *Error) StackTrace() string {
+	var output bytes.Buffer
+
+	output.WriteString("ERROR: " + e.describe() + "\n")
+
+	if e.Line == 0 {
+		return output.String()
+	}
+	output.WriteString("\n")
+
+	file, line, column := e.File, e.Line, e.Column
+
+	for i, frame := range e.Stack {
+		if i < MAX_STACK_TRACE_FRAMES {
+			output.WriteString(frame.Function + "(...)\n")
+			output.WriteString("\t" + formatPosition(file, line, column) + "\n")
+		} else if i == MAX_STACK_TRACE_FRAMES {
+			fmt.Fprintf(&output, "...%d additional frames elided...\n", len(e.Stack) - i)
+		}
+
+		file, line, column = frame.File, frame.Line, frame.Column
+	}
+	output.WriteString("<main>\n")
+	output.WriteString("\t" + formatPosition(file, line, column) + "\n")
+
+	return output.String()
+}
+
+// StackFrame is a function call that was active when an error occurred,
+// located at the position of the call.
+type StackFrame struct {
+	Function	string // "<anonymous>" for a function without name
+	File		string
+	Line		int
+	Column		int
+}
+
+func (frame StackFrame) String() string {
+	return frame.Function + " (" + formatPosition(frame.File, frame.Line, frame.Column) + ")"
+}
+
+// ErrorValue is an error caught by a `catch` block. Unlike an Error,
+// which stop the evaluation, it's an ordinary value that can be bound,
+// passed around and thrown again.
+type ErrorValue struct {
+	Error	*Error
+}
+func (ev *ErrorValue) Type() ObjectType { return ERROR_VALUE_OBJ }
+func (ev *ErrorValue) Inspect() string { return ev.Error.Inspect() }
+
+func formatPosition(file string, line, column int) string {
+	if file == "" {
+		file = "<input>"
+	}
+
+	return fmt.Sprintf("%s:%d:%d", file, line, column)
+}
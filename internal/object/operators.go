@@ -0,0 +1,670 @@
+package object
+
+import (
+	"math"
+	"path/filepath"
+	"slices"
+	"unicode/utf8"
+)
+
+
+// The semantics of the operators, shared by every execution engine.
+// Errors are returned without position: it's up to the engine to
+// locate them at the expression that failed.
+
+
+var booleanOperators = []string{ "==", "!=", "<", ">", "<=", ">=" }
+
+var bitwiseOperators = []string{ "&", "|", "^", "<<", ">>" }
+
+
+// Prefix apply the prefix operator `!`, `-` or `~` to right.
+func Prefix(operator string, right Object) Object {
+
+	switch operator {
+
+	case "!":
+		return NativeBool(!IsTruthy(right))
+
+	case "-":
+		return minusOperator(right)
+
+	case "~":
+		return tildeOperator(right)
+	}
+
+	return NULL
+}
+
+func minusOperator(right Object) Object {
+
+	switch right := right.(type) {
+
+	case *Boolean:
+		if right.Value {
+			return NewInteger(-1)
+		}
+		return NewInteger(0)
+
+	case *Integer:
+		return NewInteger(-right.Value)
+
+	case *Float:
+		return &Float{ Value: -right.Value }
+
+	default:
+		return NULL
+	}
+}
+
+// tildeOperator flip all the bits of an integer. Like the other
+// bitwise operators, it's not defined for floats and booleans.
+func tildeOperator(right Object) Object {
+	if right.Type() != INTEGER_OBJ {
+		return newError("unsupported operand type for ~: %s", right.Type())
+	}
+	value := right.(*Integer).Value
+
+	return NewInteger(^value)
+}
+
+
+// Infix apply a binary operator to left and right.
+func Infix(operator string, left, right Object) Object {
+
+	if slices.Contains(bitwiseOperators, operator) {
+		return bitwiseOperator(operator, left, right)
+	}
+
+	if left.Type() == STRING_OBJ && right.Type() == STRING_OBJ {
+		return stringOperator(operator, left, right)
+	}
+
+	// The following arithmetic and comparisons only make
+	// sense for numbers and booleans.
+	if !isNumeric(left) || !isNumeric(right) {
+		return newError(
+			"unsupported operand types for %s: %s and %s",
+			operator, left.Type(), right.Type(),
+		)
+	}
+
+	// Integer exponentiation stays exact, negative exponents
+	// fall back to the float arithmetic below.
+	if operator == "**" && left.Type() == INTEGER_OBJ && right.Type() == INTEGER_OBJ {
+		base := left.(*Integer).Value
+		exponent := right.(*Integer).Value
+
+		if exponent >= 0 {
+			return integerPower(base, exponent)
+		}
+	}
+
+	// In case we're dealing with booleans
+
+	if left.Type() == BOOLEAN_OBJ && right.Type() == BOOLEAN_OBJ {
+		switch operator {
+		case "!=":
+			return NativeBool(left != right)
+
+		case "<", ">", "<=", ">=":
+			return arithmeticOperator(operator, numberValue(left), numberValue(right))
+
+		default:
+			return NativeBool(left == right)
+		}
+	}
+
+	// Otherwise
+
+	leftValue := numberValue(left)
+	rightValue := numberValue(right)
+
+	if slices.Contains(booleanOperators, operator) {
+		return comparisonOperator(operator, leftValue, rightValue)
+	}
+
+	return arithmeticOperator(operator, leftValue, rightValue)
+}
+
+func arithmeticOperator(operator string, leftValue, rightValue float64) Object {
+
+	var result float64
+
+	switch operator {
+
+	case "+":
+		result = leftValue + rightValue
+
+	case "-":
+		result = leftValue - rightValue
+
+	case "*":
+		result = leftValue * rightValue
+
+	case "/":
+		result = leftValue / rightValue
+
+	case "%":
+		result = math.Mod(leftValue, rightValue)
+
+	case "**":
+		result = math.Pow(leftValue, rightValue)
+
+	default:
+		return NULL
+
+	}
+
+	// Integral results within the int64 range are integers. Checking
+	// the value rather than its "%g" form keeps results like 1e6 exact.
+	if result != math.Trunc(result) || result < math.MinInt64 || result >= math.MaxInt64 {
+		return &Float{ Value: result }
+	}
+
+	return NewInteger(int64(result))
+}
+
+func stringOperator(operator string, left, right Object) Object {
+	leftValue := left.(*String).Value
+	rightValue := right.(*String).Value
+
+	switch operator {
+
+	case "+":
+		return &String{ Value: leftValue + rightValue }
+
+	case "==":
+		return NativeBool(leftValue == rightValue)
+
+	case "!=":
+		return NativeBool(leftValue != rightValue)
+
+	default:
+		return newError("unsupported operand types for %s: STRING and STRING", operator)
+	}
+}
+
+// bitwiseOperator apply a bitwise or shift operator. Both operands
+// must be integers, there is no implicit conversion like it's the
+// case for arithmetic operators.
+func bitwiseOperator(operator string, left, right Object) Object {
+	if left.Type() != INTEGER_OBJ || right.Type() != INTEGER_OBJ {
+		return newError(
+			"unsupported operand types for %s: %s and %s",
+			operator, left.Type(), right.Type(),
+		)
+	}
+
+	leftValue := left.(*Integer).Value
+	rightValue := right.(*Integer).Value
+
+	switch operator {
+
+	case "&":
+		return NewInteger(leftValue & rightValue)
+
+	case "|":
+		return NewInteger(leftValue | rightValue)
+
+	case "^":
+		return NewInteger(leftValue ^ rightValue)
+
+	case "<<", ">>":
+		if rightValue < 0 {
+			return newError("negative shift count: %d", rightValue)
+		}
+
+		if operator == "<<" {
+			return NewInteger(leftValue << rightValue)
+		}
+		return NewInteger(leftValue >> rightValue)
+
+	default:
+		return NULL
+	}
+}
+
+// integerPower compute base ** exponent by squaring, returning an
+// error instead of silently wrapping when the result overflow int64.
+func integerPower(base, exponent int64) Object {
+	var ok bool
+
+	result := int64(1)
+	b, e := base, exponent
+
+	for e > 0 {
+		if e&1 == 1 {
+			if result, ok = multiplyInt64(result, b); !ok {
+				return newError("integer overflow: %d ** %d", base, exponent)
+			}
+		}
+		e >>= 1
+
+		if e > 0 {
+			if b, ok = multiplyInt64(b, b); !ok {
+				return newError("integer overflow: %d ** %d", base, exponent)
+			}
+		}
+	}
+
+	return NewInteger(result)
+}
+
+// multiplyInt64 return a * b and false if the product overflowed.
+func multiplyInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	product := a * b
+
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return product, false
+	}
+
+	return product, true
+}
+
+func comparisonOperator(operator string, leftValue, rightValue float64) Object {
+	switch operator {
+
+	case "==":
+		return NativeBool(leftValue == rightValue)
+
+	case "!=":
+		return NativeBool(leftValue != rightValue)
+
+	case "<":
+		return NativeBool(leftValue < rightValue)
+
+	case ">":
+		return NativeBool(leftValue > rightValue)
+
+	case "<=":
+		return NativeBool(leftValue <= rightValue)
+
+	case ">=":
+		return NativeBool(leftValue >= rightValue)
+
+	default:
+		return NULL
+	}
+}
+
+// numberValue return the float64 value of a number. NULL and FALSE
+// are worth 0 while TRUE is worth 1.
+func numberValue(obj Object) float64 {
+
+	switch obj := obj.(type) {
+
+	case *Integer:
+		return float64(obj.Value)
+
+	case *Float:
+		return obj.Value
+
+	case *Boolean:
+		if obj.Value {
+			return 1
+		}
+		return 0
+	}
+
+	return 0
+}
+
+// isNumeric report whether obj can be used as a number
+// by the arithmetic operators.
+func isNumeric(obj Object) bool {
+	switch obj.Type() {
+	case INTEGER_OBJ, FLOAT_OBJ, BOOLEAN_OBJ, NULL_OBJ:
+		return true
+	}
+
+	return false
+}
+
+
+// Update return the value of `++` or `--` applied to old.
+func Update(operator string, old Object) Object {
+	delta := int64(1)
+
+	if operator == "--" {
+		delta = -1
+	}
+
+	switch old := old.(type) {
+
+	case *Integer:
+		return NewInteger(old.Value + delta)
+
+	case *Float:
+		return &Float{ Value: old.Value + float64(delta) }
+
+	default:
+		return newError("unsupported operand type for %s: %s", operator, old.Type())
+	}
+}
+
+
+// Index return `left[index]`: an element of an array, a one-character
+// string of a string, the value of a hash key, NULL when the key is
+// missing, or a field of a caught error.
+func Index(left, index Object) Object {
+
+	switch left := left.(type) {
+
+	case *Array:
+		i, err := ResolveIndex(index, int64(len(left.Elements)))
+
+		if err != nil {
+			return err
+		}
+		return left.Elements[i]
+
+	case *String:
+		i, err := ResolveIndex(index, int64(utf8.RuneCountInString(left.Value)))
+
+		if err != nil {
+			return err
+		}
+		from := runeOffset(left.Value, i)
+		_, size := utf8.DecodeRuneInString(left.Value[from:])
+
+		return &String{ Value: left.Value[from:from + int64(size)] }
+
+	case *Hash:
+		key, ok := index.(Hashable)
+
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+
+		if value, ok := left.Get(key); ok {
+			return value
+		}
+		return NULL
+
+	case *ErrorValue:
+		return ErrorField(left.Error, index)
+
+	default:
+		return newError("index operator not supported: %s", left.Type())
+	}
+}
+
+// ResolveIndex check that index is an integer within a sequence of the
+// given length. Negative indexes count from the end of the sequence.
+func ResolveIndex(index Object, length int64) (int64, *Error) {
+	integer, ok := index.(*Integer)
+
+	if !ok {
+		return 0, newError("index must be an integer, got %s", index.Type())
+	}
+	i := integer.Value
+
+	if i < 0 {
+		i += length
+	}
+
+	if i < 0 || i >= length {
+		return 0, newError("index out of range: %d with length %d", integer.Value, length)
+	}
+
+	return i, nil
+}
+
+// ErrorField return the `message`, `kind` or `stack` field of a caught
+// error. The stack is an array holding a string for each call the error
+// went through before being caught, innermost first.
+func ErrorField(err *Error, field Object) Object {
+	name, ok := field.(*String)
+
+	if !ok {
+		return newError("error field must be a string, got %s", field.Type())
+	}
+
+	switch name.Value {
+
+	case "message":
+		return &String{ Value: err.Message }
+
+	case "kind":
+		if err.Kind == "" {
+			return &String{ Value: GENERIC_ERROR }
+		}
+		return &String{ Value: err.Kind }
+
+	case "stack":
+		frames := make([]Object, len(err.Stack))
+
+		for i, frame := range err.Stack {
+			frames[i] = &String{ Value: frame.String() }
+		}
+		return &Array{ Elements: frames }
+
+	default:
+		return newError("unknown error field: %s", name.Value)
+	}
+}
+
+// Member return `obj.name`. On a hash, the member is the value of the
+// string key of the same name, falling back to the builtin method, and
+// NULL when there is none, like indexing a missing key. On a module,
+// it's an exported name, on a caught error, one of its fields, and on
+// other values, a builtin method.
+func Member(obj Object, name string) Object {
+
+	switch obj := obj.(type) {
+
+	case *Module:
+		value, ok := obj.Member(name)
+
+		if !ok {
+			return newError("module %s has no exported member %s", filepath.Base(obj.Path), name)
+		}
+		return value
+
+	case *ErrorValue:
+		return ErrorField(obj.Error, &String{ Value: name })
+
+	case *Hash:
+		if value, ok := obj.Get(&String{ Value: name }); ok {
+			return value
+		}
+
+		if method, ok := LookupMethod(obj, name); ok {
+			return method
+		}
+		return NULL
+	}
+
+	if method, ok := LookupMethod(obj, name); ok {
+		return method
+	}
+
+	return newError("%s has no member %s", obj.Type(), name)
+}
+
+
+// Slice return `left[start:stop:step]` on arrays and strings, where
+// the bounds are nil when omitted.
+//
+// Slicing an array always copy the selected elements into a new array,
+// so updating an element of the slice never affect the original array,
+// and the other way around. Strings are sliced by character, not
+// byte, and are immutable: with a step of 1 the slice share the bytes
+// of the original string, other steps build a new one.
+func Slice(left, start, stop, step Object) Object {
+	var length int64
+
+	switch left := left.(type) {
+
+	case *Array:
+		length = int64(len(left.Elements))
+
+	case *String:
+		length = int64(utf8.RuneCountInString(left.Value))
+
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+
+	from, to, by, err := resolveSliceBounds(start, stop, step, length)
+
+	if err != nil {
+		return err
+	}
+
+	switch left := left.(type) {
+
+	case *String:
+		if by == 1 {
+			return &String{ Value: left.Value[runeOffset(left.Value, from):runeOffset(left.Value, max(from, to))] }
+		}
+		runes := []rune(left.Value)
+		selected := []rune{}
+
+		for i := from; (by > 0 && i < to) || (by < 0 && i > to); i += by {
+			selected = append(selected, runes[i])
+		}
+		return &String{ Value: string(selected) }
+
+	default:
+		array := left.(*Array)
+		elements := []Object{}
+
+		for i := from; (by > 0 && i < to) || (by < 0 && i > to); i += by {
+			elements = append(elements, array.Elements[i])
+		}
+		return &Array{ Elements: elements }
+	}
+}
+
+// runeOffset return the offset in bytes of the character at index i
+// of s, or the length of s when i is its number of characters.
+func runeOffset(s string, i int64) int64 {
+	if int64(len(s)) == int64(utf8.RuneCountInString(s)) {
+		return i
+	}
+
+	for offset := range s {
+		if i == 0 {
+			return int64(offset)
+		}
+		i--
+	}
+
+	return int64(len(s))
+}
+
+// resolveSliceBounds turn the optional bounds of a slice over a sequence
+// of the given length into concrete positions. Omitted bounds cover the
+// whole sequence, walked backward when the step is negative, in which
+// case stop is -1 so the first item is included.
+func resolveSliceBounds(start, stop, step Object, length int64) (int64, int64, int64, *Error) {
+	by := int64(1)
+
+	if step != nil {
+		integer, ok := step.(*Integer)
+
+		if !ok {
+			return 0, 0, 0, newError("slice bounds must be integers, got %s", step.Type())
+		}
+		by = integer.Value
+	}
+
+	if by == 0 {
+		return 0, 0, 0, newError("slice step cannot be zero")
+	}
+
+	from, to := int64(0), length
+
+	if by < 0 {
+		from, to = length - 1, -1
+	}
+
+	if start != nil {
+		resolved, err := resolveSliceBound(start, length)
+
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		from = resolved
+
+		// Walking backward from the end starts on the last item.
+		if by < 0 && from == length {
+			from = length - 1
+		}
+	}
+
+	if stop != nil {
+		resolved, err := resolveSliceBound(stop, length)
+
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		to = resolved
+	}
+
+	return from, to, by, nil
+}
+
+// resolveSliceBound check that bound is an integer within [0, length],
+// negative bounds counting from the end of the sequence.
+func resolveSliceBound(bound Object, length int64) (int64, *Error) {
+	integer, ok := bound.(*Integer)
+
+	if !ok {
+		return 0, newError("slice bounds must be integers, got %s", bound.Type())
+	}
+	resolved := integer.Value
+
+	if resolved < 0 {
+		resolved += length
+	}
+
+	if resolved < 0 || resolved > length {
+		return 0, newError("slice bounds out of range: %d with length %d", integer.Value, length)
+	}
+
+	return resolved, nil
+}
+
+
+// NewRange return the range `start..end`, or `start..=end`
+// when inclusive.
+func NewRange(start, end Object, inclusive bool) Object {
+	startInt, startOk := start.(*Integer)
+	endInt, endOk := end.(*Integer)
+
+	if !startOk || !endOk {
+		return newError("range bounds must be integers, got %s and %s", start.Type(), end.Type())
+	}
+
+	return &Range{ Start: startInt.Value, End: endInt.Value, Inclusive: inclusive }
+}
+
+// Throw return the error raised by throwing value. A string become the
+// message of the error, while a caught error is raised again as is,
+// keeping its position and the calls it went through.
+func Throw(value Object) *Error {
+
+	switch value := value.(type) {
+
+	case *ErrorValue:
+		err := *value.Error
+		err.Stack = slices.Clone(value.Error.Stack)
+
+		return &err
+
+	case *String:
+		return newError("%s", value.Value)
+
+	case nil:
+		return newError("%s", NULL.Inspect())
+
+	default:
+		return newError("%s", value.Inspect())
+	}
+}
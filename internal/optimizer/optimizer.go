@@ -0,0 +1,292 @@
+package optimizer
+
+import (
+	"math"
+	"monkey/internal/ast"
+	"monkey/internal/object"
+	"monkey/internal/token"
+	"strconv"
+	"strings"
+)
+
+
+// Optimize rewrite program in place, and return it, so that it compute
+// the same values and fail with the same errors as the original, with
+// less work:
+//
+//   - prefix and infix operations over integer, float and boolean
+//     literals are replaced by their result, unless the operation fails
+//     or give a float that has no literal, like the infinity of `1 / 0`;
+//   - an if-else expression whose condition is a literal is replaced by
+//     the branch it takes, which is kept in its own block when it holds
+//     more than a single expression, so its bindings stay local.
+//
+// Only the steps and allocations counted against the evaluator's budgets
+// differ, there being fewer of them.
+func Optimize(program *ast.Program) *ast.Program {
+	program.Statements = optimizeStatements(program.Statements)
+
+	return program
+}
+
+
+func optimizeStatements(statements []ast.Statement) []ast.Statement {
+	for i, stmt := range statements {
+		statements[i] = optimizeStatement(stmt)
+	}
+
+	return statements
+}
+
+func optimizeStatement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+
+	case *ast.ExpressionStatement:
+		stmt.Expression = optimizeExpression(stmt.Expression)
+
+	case *ast.DeclarationStatement:
+		stmt.Value = optimizeExpression(stmt.Value)
+
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = optimizeExpression(stmt.ReturnValue)
+
+	case *ast.BlockStatement:
+		optimizeBlock(stmt)
+
+	case *ast.FunctionDeclaration:
+		optimizeFunction(stmt.Function)
+
+	case *ast.ExportStatement:
+		stmt.Statement = optimizeStatement(stmt.Statement)
+
+	case *ast.ThrowStatement:
+		stmt.Value = optimizeExpression(stmt.Value)
+
+	case *ast.TryStatement:
+		optimizeBlock(stmt.Block)
+		optimizeBlock(stmt.Catch)
+		optimizeBlock(stmt.Finally)
+
+	case *ast.ForInStatement:
+		stmt.Iterable = optimizeExpression(stmt.Iterable)
+		optimizeBlock(stmt.Body)
+	}
+
+	return stmt
+}
+
+func optimizeBlock(block *ast.BlockStatement) {
+	if block != nil {
+		block.Statements = optimizeStatements(block.Statements)
+	}
+}
+
+func optimizeFunction(fn *ast.FunctionLiteral) {
+	for _, param := range fn.Params {
+		param.Default = optimizeExpression(param.Default)
+	}
+	optimizeBlock(fn.Body)
+}
+
+func optimizeExpressions(expressions []ast.Expression) []ast.Expression {
+	for i, expr := range expressions {
+		expressions[i] = optimizeExpression(expr)
+	}
+
+	return expressions
+}
+
+func optimizeExpression(expr ast.Expression) ast.Expression {
+	switch expr := expr.(type) {
+
+	case *ast.PrefixExpression:
+		expr.Right = optimizeExpression(expr.Right)
+
+		if expr.Operator == "++" || expr.Operator == "--" {
+			return expr
+		}
+
+		if right := literalValue(expr.Right); right != nil {
+			return foldedLiteral(expr, object.Prefix(expr.Operator, right), firstToken(expr))
+		}
+
+	case *ast.InfixExpression:
+		expr.Left = optimizeExpression(expr.Left)
+		expr.Right = optimizeExpression(expr.Right)
+
+		left := literalValue(expr.Left)
+		right := literalValue(expr.Right)
+
+		if left != nil && right != nil {
+			return foldedLiteral(expr, object.Infix(expr.Operator, left, right), firstToken(expr))
+		}
+
+	case *ast.IfElseExpression:
+		return optimizeIfElseExpression(expr)
+
+	case *ast.PostfixExpression:
+		expr.Left = optimizeExpression(expr.Left)
+
+	case *ast.ArrayLiteral:
+		expr.Elements = optimizeExpressions(expr.Elements)
+
+	case *ast.HashLiteral:
+		expr.Keys = optimizeExpressions(expr.Keys)
+		expr.Values = optimizeExpressions(expr.Values)
+
+	case *ast.FunctionLiteral:
+		optimizeFunction(expr)
+
+	case *ast.FunctionCallExpression:
+		expr.Function = optimizeExpression(expr.Function)
+		expr.Arguments = optimizeExpressions(expr.Arguments)
+
+	case *ast.SpreadExpression:
+		expr.Value = optimizeExpression(expr.Value)
+
+	case *ast.NamedArgument:
+		expr.Value = optimizeExpression(expr.Value)
+
+	case *ast.IndexExpression:
+		expr.Left = optimizeExpression(expr.Left)
+		expr.Index = optimizeExpression(expr.Index)
+
+	case *ast.MemberExpression:
+		expr.Object = optimizeExpression(expr.Object)
+
+	case *ast.SliceExpression:
+		expr.Left = optimizeExpression(expr.Left)
+		expr.Start = optimizeExpression(expr.Start)
+		expr.Stop = optimizeExpression(expr.Stop)
+		expr.Step = optimizeExpression(expr.Step)
+
+	case *ast.RangeExpression:
+		expr.Start = optimizeExpression(expr.Start)
+		expr.End = optimizeExpression(expr.End)
+	}
+
+	return expr
+}
+
+// optimizeIfElseExpression drop the branch an if-else expression can't
+// take. The branch taken is evaluated in its own scope, so it replace
+// the expression only when it's a single expression, which can't bind
+// anything. Otherwise the block is kept behind a `true` condition.
+func optimizeIfElseExpression(expr *ast.IfElseExpression) ast.Expression {
+	expr.Condition = optimizeExpression(expr.Condition)
+	optimizeBlock(expr.Consequence)
+	optimizeBlock(expr.Alternative)
+
+	condition := literalValue(expr.Condition)
+
+	if condition == nil {
+		return expr
+	}
+
+	taken := expr.Alternative
+
+	if object.IsTruthy(condition) {
+		taken = expr.Consequence
+	}
+
+	// Without alternative, a false condition give null, which
+	// has no literal: the expression is kept, but empty.
+	if taken == nil {
+		expr.Consequence = &ast.BlockStatement{ Token: expr.Consequence.Token }
+		return expr
+	}
+
+	if len(taken.Statements) == 1 {
+		if stmt, ok := taken.Statements[0].(*ast.ExpressionStatement); ok && stmt.Expression != nil {
+			return stmt.Expression
+		}
+	}
+
+	return &ast.IfElseExpression{
+		Token: expr.Token,
+		Condition: &ast.Boolean{
+			Token: token.Token{ Type: token.TRUE, Literal: "true", File: expr.Token.File, Line: expr.Token.Line, Column: expr.Token.Column },
+			Value: true,
+		},
+		Consequence: taken,
+	}
+}
+
+// literalValue return the value of an integer, float or boolean
+// literal, or nil for any other expression.
+func literalValue(expr ast.Expression) object.Object {
+	switch expr := expr.(type) {
+
+	case *ast.IntegerLiteral:
+		return object.NewInteger(expr.Value)
+
+	case *ast.FloatLiteral:
+		return &object.Float{ Value: expr.Value }
+
+	case *ast.Boolean:
+		return object.NativeBool(expr.Value)
+	}
+
+	return nil
+}
+
+// foldedLiteral return the literal of value, located at tok, or expr
+// itself when value is an error, to be raised at run time, or has no
+// literal.
+func foldedLiteral(expr ast.Expression, value object.Object, tok token.Token) ast.Expression {
+	switch value := value.(type) {
+
+	case *object.Integer:
+		tok.Type = token.INTEGER
+		tok.Literal = strconv.FormatInt(value.Value, 10)
+		return &ast.IntegerLiteral{ Token: tok, Value: value.Value }
+
+	case *object.Float:
+		if math.IsInf(value.Value, 0) || math.IsNaN(value.Value) {
+			return expr
+		}
+		tok.Type = token.FLOAT
+		tok.Literal = strconv.FormatFloat(value.Value, 'f', -1, 64)
+
+		if !strings.Contains(tok.Literal, ".") {
+			tok.Literal += ".0"
+		}
+		return &ast.FloatLiteral{ Token: tok, Value: value.Value }
+
+	case *object.Boolean:
+		tok.Type = token.FALSE
+		tok.Literal = "false"
+
+		if value.Value {
+			tok.Type = token.TRUE
+			tok.Literal = "true"
+		}
+		return &ast.Boolean{ Token: tok, Value: value.Value }
+	}
+
+	return expr
+}
+
+// firstToken return the token an expression start with,
+// so a folded literal is located where the expression was.
+func firstToken(expr ast.Expression) token.Token {
+	switch expr := expr.(type) {
+
+	case *ast.InfixExpression:
+		return firstToken(expr.Left)
+
+	case *ast.IntegerLiteral:
+		return expr.Token
+
+	case *ast.FloatLiteral:
+		return expr.Token
+
+	case *ast.Boolean:
+		return expr.Token
+
+	case *ast.PrefixExpression:
+		return expr.Token
+	}
+
+	return token.Token{}
+}
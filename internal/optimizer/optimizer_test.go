@@ -0,0 +1,156 @@
+package optimizer
+
+import (
+	"monkey/internal/ast"
+	"monkey/internal/evaluator"
+	"monkey/internal/lexer"
+	"monkey/internal/object"
+	"monkey/internal/parser"
+	"testing"
+)
+
+
+func TestOptimize(t *testing.T) {
+
+	t.Run("it should fold operations over literals", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "2 * 60 * 60", "7200" },
+			{ "-5 + 2", "-3" },
+			{ "1.5 * 2", "3" },
+			{ "7 / 2", "3.5" },
+			{ "0.1 + 0.2", "0.30000000000000004" },
+			{ "2.5 * 2.5 > 6", "true" },
+			{ "!true == false", "true" },
+			{ "true + 1", "2" },
+			{ "~5 & 3", "2" },
+			{ "1 << 4 | 1", "17" },
+			{ "2 ** 10", "1024" },
+			{ "x + 2 * 3", "(x + 6)" },
+			{ "x + 2 + 3", "((x + 2) + 3)" },
+			{ "[1 + 1, { 2 * 2: -(-3) }]", "[2, {4: 3}]" },
+			{ "fn(a = 60 * 60) { a * (1 + 1) }", "fn(a = 3600) { (a * 2); }" },
+			{ "let a = 1 + 2;", "let a = 3;" },
+		}
+
+		for i, tt := range tests {
+			got := testOptimize(t, tt.input).String()
+
+			if got != tt.expected {
+				t.Fatalf("[test #%d]: Expecting %q, but got %q\n", i, tt.expected, got)
+			}
+		}
+	})
+
+	t.Run("it should leave the operations that fail or have no literal", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "1 / 0", "(1 / 0)" },
+			{ "-1.0 / 0", "(-1.0 / 0)" },
+			{ "0.0 / 0", "(0.0 / 0)" },
+			{ "1.5 & 1", "(1.5 & 1)" },
+			{ "~true", "(~true)" },
+			{ "1 + \"a\"", "(1 + \"a\")" },
+			{ "\"a\" + \"b\"", "(\"a\" + \"b\")" },
+		}
+
+		for i, tt := range tests {
+			got := testOptimize(t, tt.input).String()
+
+			if got != tt.expected {
+				t.Fatalf("[test #%d]: Expecting %q, but got %q\n", i, tt.expected, got)
+			}
+		}
+	})
+
+	t.Run("it should remove the branches that can't be taken", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "if (true) { 1 } else { 2 }", "1" },
+			{ "if (1 > 2) { 1 } else { x }", "x" },
+			{ "if (0) { 1 } else if (1) { 2 } else { 3 }", "2" },
+			{ "if (false) { 1 }", "if false { }" },
+			{ "if (true) { let a = 1; a }", "if true { let a = 1; a; }" },
+			{ "if (false) { 1 } else { }", "if true { }" },
+			{ "if (x) { 1 + 1 } else { 2 }", "if x { 2; } else { 2; }" },
+			{ "fn f(n) { if (true) { f(n - 1) } }", "fn f(n) { f((n - 1)); }" },
+		}
+
+		for i, tt := range tests {
+			got := testOptimize(t, tt.input).String()
+
+			if got != tt.expected {
+				t.Fatalf("[test #%d]: Expecting %q, but got %q\n", i, tt.expected, got)
+			}
+		}
+	})
+
+	t.Run("it should give the same results as the original program", func(t *testing.T) {
+		tests := []string{
+			"2 * 60 * 60",
+			"1 / 0",
+			"-1 / 0 < 0",
+			"10 % 0",
+			"9223372036854775807 + 1",
+			"2 ** 64",
+			"2 ** -1",
+			"1.5 & 1",
+			"~true",
+			"if (false) { 1 }",
+			"if (true) { }",
+			"let a = 1; if (true) { let a = 2; a }; a",
+			"let a = 1; if (true) { a++ }; a",
+			"let f = if (1) { fn() { 1 + 1 } }; f()",
+			"fn count(n) { if (n == 0) { 0 } else if (true) { count(n - 1) } } count(100000)",
+			"fn f() { if (true) { missing } } f()",
+			"throw 1 + 1",
+		}
+
+		for i, input := range tests {
+			expected := evaluator.Eval(parse(t, input), object.NewEnvironment())
+			got := evaluator.Eval(testOptimize(t, input), object.NewEnvironment())
+
+			if describe(got) != describe(expected) {
+				t.Fatalf("[test #%d] %s\nExpecting %s, but got %s\n", i, input, describe(expected), describe(got))
+			}
+		}
+	})
+}
+
+
+func testOptimize(t *testing.T, input string) *ast.Program {
+	return Optimize(parse(t, input))
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Expecting no parser errors, but got %v\n", p.Errors())
+	}
+
+	return program
+}
+
+func describe(obj object.Object) string {
+	switch obj := obj.(type) {
+
+	case nil:
+		return "<nil>"
+
+	case *object.Error:
+		return obj.Kind + " " + obj.StackTrace()
+
+	case *object.Function:
+		return obj.Type().String()
+	}
+
+	return obj.Type().String() + " " + obj.Inspect()
+}
@@ -5,35 +5,57 @@ import (
 	"monkey/internal/ast"
 	"monkey/internal/lexer"
 	"monkey/internal/token"
+	"slices"
 	"strconv"
 )
 
 const (
 	_ 				int = iota
 	LOWEST
+	RANGE // 1..10 or 1..=10
+	BITWISE_OR // |
+	BITWISE_XOR // ^
+	BITWISE_AND // &
 	EQUALS // comparision(==)
 	LESS_OR_GREATER // < or >
 	LESS_GREATER_OR_EQUAL // <= or >=
+	SHIFT // << or >>
 	SUM // addition(+)
 	PRODUCT // * or /
 	REMAINDER // %
 	PREFIX // -x or !x
+	POWER // x ** y
 	FUNC_CALL // myFunc(x)
+	INDEX // array[index]
+	POSTFIX // x++ or x--
+	MEMBER // object.member
 )
 
 var precedences = map[token.TokenType]int{
+	token.DOT_DOT: RANGE,
+	token.DOT_DOT_EQUAL: RANGE,
+	token.PIPE: BITWISE_OR,
+	token.CARET: BITWISE_XOR,
+	token.AMPERSAND: BITWISE_AND,
 	token.EQUAL: EQUALS,
 	token.NOT_EQUAL: EQUALS,
 	token.LESSER_THAN: LESS_OR_GREATER,
 	token.GREATER_THAN: LESS_OR_GREATER,
 	token.LESSER_OR_EQUAL_TO: LESS_GREATER_OR_EQUAL,
 	token.GREATER_OR_EQUAL_TO: LESS_GREATER_OR_EQUAL,
+	token.LEFT_SHIFT: SHIFT,
+	token.RIGHT_SHIFT: SHIFT,
 	token.PLUS: SUM,
 	token.MINUS: SUM,
 	token.SLASH: PRODUCT,
 	token.ASTERISK: PRODUCT,
 	token.MODULO: REMAINDER,
+	token.POWER: POWER,
 	token.LPAREN: FUNC_CALL,
+	token.LBRACKET: INDEX,
+	token.INCREMENT: POSTFIX,
+	token.DECREMENT: POSTFIX,
+	token.DOT: MEMBER,
 }
 
 type (
@@ -51,6 +73,8 @@ type Parser struct {
 	infixParseFns	map[token.TokenType]infixParseFn
 
 	errors			[]string
+
+	blockDepth		int // number of blocks enclosing the current token
 }
 
 func New(lex *lexer.Lexer) *Parser {
@@ -77,10 +101,16 @@ func (p *Parser) registerPrefixesAndInfixes() {
 	p.registerPrefix(token.IDENTIFIER, p.parseIdentifier)
 	p.registerPrefix(token.INTEGER, p.parseInteger)
 	p.registerPrefix(token.FLOAT, p.parseFloat)
+	p.registerPrefix(token.STRING, p.parseString)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
+	p.registerPrefix(token.INCREMENT, p.parsePrefixUpdateExpression)
+	p.registerPrefix(token.DECREMENT, p.parsePrefixUpdateExpression)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunction)
@@ -91,13 +121,25 @@ func (p *Parser) registerPrefixesAndInfixes() {
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.MODULO, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
 	p.registerInfix(token.EQUAL, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQUAL, p.parseInfixExpression)
 	p.registerInfix(token.LESSER_THAN, p.parseInfixExpression)
 	p.registerInfix(token.GREATER_THAN, p.parseInfixExpression)
 	p.registerInfix(token.LESSER_OR_EQUAL_TO, p.parseInfixExpression)
 	p.registerInfix(token.GREATER_OR_EQUAL_TO, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.CARET, p.parseInfixExpression)
+	p.registerInfix(token.LEFT_SHIFT, p.parseInfixExpression)
+	p.registerInfix(token.RIGHT_SHIFT, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseFunctionCall)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT_DOT, p.parseRangeExpression)
+	p.registerInfix(token.DOT_DOT_EQUAL, p.parseRangeExpression)
+	p.registerInfix(token.INCREMENT, p.parsePostfixExpression)
+	p.registerInfix(token.DECREMENT, p.parsePostfixExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 
 }
 
@@ -158,6 +200,29 @@ func (p *Parser) parseStatement() ast.Statement {
 	case token.RETURN:
 		return p.parseReturnStatement()
 
+	case token.FOR:
+		return p.parseForInStatement()
+
+	case token.THROW:
+		return p.parseThrowStatement()
+
+	case token.TRY:
+		return p.parseTryStatement()
+
+	case token.IMPORT:
+		return p.parseImportStatement()
+
+	case token.EXPORT:
+		return p.parseExportStatement()
+
+	case token.FUNCTION:
+		// `fn name(...)` is a declaration, while `fn(...)`
+		// is a function literal used as an expression.
+		if p.peekTokenIs(token.IDENTIFIER) {
+			return p.parseFunctionDeclaration()
+		}
+		return p.parseExpressionStatement()
+
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -172,6 +237,12 @@ func (p *Parser) parseDeclarationStatement() *ast.DeclarationStatement {
 	}
 	
 	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if p.peekTokenIs(token.COLON) {
+		if stmt.Type = p.parseAnnotation(); stmt.Type == nil {
+			return nil
+		}
+	}
 	
 	if !p.expectPeekTokenToBe(token.ASSIGN) {
 		return nil
@@ -279,6 +350,85 @@ func (p *Parser) parseFloat() ast.Expression {
 	return floatLiteral
 }
 
+func (p *Parser) parseString() ast.Expression {
+	strLiteral := &ast.StringLiteral{ Token: p.currentToken }
+
+	value, err := strconv.Unquote("\"" + p.currentToken.Literal + "\"")
+
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse %q as string\n", p.currentToken.Literal)
+		p.addError(msg)
+	}
+	strLiteral.Value = value
+
+	return strLiteral
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{ Token: p.currentToken }
+
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+
+	return array
+}
+
+// parseHashLiteral parse `{ key: value, ... }`, where keys are
+// any expression. A trailing comma is allowed.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{ Token: p.currentToken }
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeekTokenToBe(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Keys = append(hash.Keys, key)
+		hash.Values = append(hash.Values, value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeekTokenToBe(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeekTokenToBe(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
+
+// parseExpressionList parse a comma separated list of
+// expressions, terminated by the end token.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+
+	p.nextToken()
+	list = append(list, p.parseExpression(LOWEST))
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+
+		list = append(list, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeekTokenToBe(end) {
+		return nil
+	}
+
+	return list
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token: p.currentToken,
@@ -292,6 +442,126 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	return expression
 }
 
+// parsePrefixUpdateExpression parse `++x` and `--x`.
+func (p *Parser) parsePrefixUpdateExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token: p.currentToken,
+		Operator: p.currentToken.Literal,
+	}
+
+	p.nextToken()
+
+	expression.Right = p.parseExpression(PREFIX)
+
+	if !p.checkAssignable(expression.Operator, expression.Right) {
+		return nil
+	}
+
+	return expression
+}
+
+// parsePostfixExpression parse `x++` and `x--`.
+func (p *Parser) parsePostfixExpression(left ast.Expression) ast.Expression {
+	expression := &ast.PostfixExpression{
+		Token: p.currentToken,
+		Left: left,
+		Operator: p.currentToken.Literal,
+	}
+
+	if !p.checkAssignable(expression.Operator, left) {
+		return nil
+	}
+
+	return expression
+}
+
+// parseIndexExpression parse `left[index]` as well as the slice
+// forms `left[start:stop]` and `left[start:stop:step]`, where every
+// bound can be omitted like in `str[:5]` or `arr[::-1]`.
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	bracket := p.currentToken
+
+	p.nextToken()
+
+	var start ast.Expression
+
+	if !p.currentTokenIs(token.COLON) {
+		start = p.parseExpression(LOWEST)
+
+		if p.peekTokenIs(token.RBRACKET) {
+			p.nextToken()
+
+			return &ast.IndexExpression{ Token: bracket, Left: left, Index: start }
+		}
+
+		if !p.expectPeekTokenToBe(token.COLON) {
+			return nil
+		}
+	}
+
+	slice := &ast.SliceExpression{ Token: bracket, Left: left, Start: start }
+	slice.Stop = p.parseSliceBound()
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		slice.Step = p.parseSliceBound()
+	}
+
+	if !p.expectPeekTokenToBe(token.RBRACKET) {
+		return nil
+	}
+
+	return slice
+}
+
+// parseSliceBound parse the slice bound following the current ':'
+// and return nil if it was omitted.
+func (p *Parser) parseSliceBound() ast.Expression {
+	if p.peekTokenIs(token.COLON) || p.peekTokenIs(token.RBRACKET) {
+		return nil
+	}
+
+	p.nextToken()
+
+	return p.parseExpression(LOWEST)
+}
+
+func (p *Parser) parseRangeExpression(start ast.Expression) ast.Expression {
+	expression := &ast.RangeExpression{
+		Token: p.currentToken,
+		Start: start,
+		Inclusive: p.currentTokenIs(token.DOT_DOT_EQUAL),
+	}
+
+	p.nextToken()
+
+	expression.End = p.parseExpression(RANGE)
+
+	return expression
+}
+
+// checkAssignable check that the operand of an operator that
+// modify its value, like `++`, is something we can assign to.
+func (p *Parser) checkAssignable(operator string, expr ast.Expression) bool {
+	switch expr.(type) {
+
+	case *ast.Identifier, *ast.IndexExpression:
+		return true
+
+	case nil:
+		return false
+
+	default:
+		msg := fmt.Sprintf(
+			"Invalid operand for '%s': expected an identifier or an index expression, but got %q",
+			operator, expr.String(),
+		)
+		p.addError(msg)
+
+		return false
+	}
+}
+
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token: p.currentToken,
@@ -299,6 +569,12 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Operator: p.currentToken.Literal,
 	}
 	precedence := p.currentPrecedence()
+
+	// `**` is right-associative: lowering the precedence of its right
+	// operand makes `2 ** 3 ** 2` parse as `2 ** (3 ** 2)`.
+	if p.currentTokenIs(token.POWER) {
+		precedence--
+	}
 	
 	p.nextToken()
 
@@ -322,17 +598,14 @@ func (p *Parser) parseIfExpression() ast.Expression {
 		Token: p.currentToken,
 	}
 
-	// NOTE:
-	// It could be interesting making `condition` parenthesis
-	// non mandatory like it is in languages like Go. But for now,
-	// I'll stick to it, and maybe change that in the future.
-	if !p.expectPeekTokenToBe(token.LPAREN) { return nil }
-
+	// Parenthesis around the condition are optional, like it is
+	// in Go. When they are present, the grouped expression parsing
+	// takes care of them.
 	p.nextToken()
 
 	expr.Condition = p.parseExpression(LOWEST)
 
-	if !p.expectPeekTokenToBe(token.RPAREN) || !p.expectPeekTokenToBe(token.LBRACE) {
+	if !p.expectPeekTokenToBe(token.LBRACE) {
 		return nil
 	}
 	expr.Consequence = p.parseBlockStatement()
@@ -345,6 +618,15 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 	p.nextToken()
 
+	// An `else if` is parsed as an alternative block holding
+	// a single nested IfElseExpression.
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		expr.Alternative = p.parseElseIfBlock()
+
+		return expr
+	}
+
 	if !p.expectPeekTokenToBe(token.LBRACE) {
 		return nil
 	}
@@ -354,11 +636,32 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expr
 }
 
+// parseElseIfBlock parse the `if` following an `else` and wrap it
+// in a block statement, so `else if (x) { ... }` and
+// `else { if (x) { ... } }` produce the same tree.
+func (p *Parser) parseElseIfBlock() *ast.BlockStatement {
+	ifToken := p.currentToken
+	nested := p.parseIfExpression()
+
+	if nested == nil {
+		return nil
+	}
+
+	return &ast.BlockStatement{
+		Token: ifToken,
+		Statements: []ast.Statement{
+			&ast.ExpressionStatement{ Token: ifToken, Expression: nested },
+		},
+	}
+}
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{ Token: p.currentToken }
 	block.Statements = []ast.Statement{}
 
+	p.blockDepth++
+	defer func() { p.blockDepth-- }()
+
 	p.nextToken()
 
 	for !p.currentTokenIs(token.RBRACE) && !p.currentTokenIs(token.EOF) {
@@ -371,14 +674,261 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 }
 
 
+// parseForInStatement parse `for item in iterable { ... }`. Like for
+// conditions, the parenthesis around `item in iterable` are optional.
+func (p *Parser) parseForInStatement() ast.Statement {
+	stmt := &ast.ForInStatement{ Token: p.currentToken }
+
+	withParens := p.peekTokenIs(token.LPAREN)
+
+	if withParens {
+		p.nextToken()
+	}
+
+	if !p.expectPeekTokenToBe(token.IDENTIFIER) {
+		return nil
+	}
+	stmt.Variable = &ast.Identifier{ Token: p.currentToken, Value: p.currentToken.Literal }
+
+	if !p.expectPeekTokenToBe(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if withParens && !p.expectPeekTokenToBe(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeekTokenToBe(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseImportStatement parse `import "path" as name`.
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{ Token: p.currentToken }
+
+	if !p.expectPeekTokenToBe(token.STRING) {
+		return nil
+	}
+	path, ok := p.parseString().(*ast.StringLiteral)
+
+	if !ok {
+		return nil
+	}
+	stmt.Path = path
+
+	if !p.expectPeekTokenToBe(token.AS) {
+		return nil
+	}
+
+	if !p.expectPeekTokenToBe(token.IDENTIFIER) {
+		return nil
+	}
+	stmt.Alias = &ast.Identifier{ Token: p.currentToken, Value: p.currentToken.Literal }
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseExportStatement parse a `let`, `const` or `fn` declaration
+// preceded by `export`. Only the top level of a file can export names.
+func (p *Parser) parseExportStatement() ast.Statement {
+	stmt := &ast.ExportStatement{ Token: p.currentToken }
+
+	if p.blockDepth > 0 {
+		p.addError("Export is only allowed at the top level of a module")
+		return nil
+	}
+	p.nextToken()
+
+	switch {
+
+	case p.currentTokenIs(token.LET), p.currentTokenIs(token.CONST):
+		if declaration := p.parseDeclarationStatement(); declaration != nil {
+			stmt.Statement = declaration
+		}
+
+	case p.currentTokenIs(token.FUNCTION) && p.peekTokenIs(token.IDENTIFIER):
+		if declaration := p.parseFunctionDeclaration(); declaration != nil {
+			stmt.Statement = declaration
+		}
+
+	default:
+		p.addError(fmt.Sprintf(
+			"Invalid export: expected a let, const or fn declaration, but got %q",
+			p.currentToken.Literal,
+		))
+	}
+
+	if stmt.Statement == nil {
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseThrowStatement() ast.Statement {
+	stmt := &ast.ThrowStatement{ Token: p.currentToken }
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if stmt.Value == nil {
+		return nil
+	}
+
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseTryStatement parse `try { ... }` followed by a catch block,
+// a finally block or both. Like for conditions, the parentheses
+// around the catch parameter are optional.
+func (p *Parser) parseTryStatement() ast.Statement {
+	stmt := &ast.TryStatement{ Token: p.currentToken }
+
+	if !p.expectPeekTokenToBe(token.LBRACE) {
+		return nil
+	}
+	stmt.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if !p.parseCatchClause(stmt) {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeekTokenToBe(token.LBRACE) {
+			return nil
+		}
+		stmt.Finally = p.parseBlockStatement()
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.addError("Missing catch or finally block after try block")
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseCatchClause(stmt *ast.TryStatement) bool {
+	withParens := p.peekTokenIs(token.LPAREN)
+
+	if withParens {
+		p.nextToken()
+	}
+
+	if p.peekTokenIs(token.IDENTIFIER) {
+		p.nextToken()
+		stmt.CatchParam = &ast.Identifier{ Token: p.currentToken, Value: p.currentToken.Literal }
+	}
+
+	if withParens && !p.expectPeekTokenToBe(token.RPAREN) {
+		return false
+	}
+
+	if !p.expectPeekTokenToBe(token.LBRACE) {
+		return false
+	}
+	stmt.Catch = p.parseBlockStatement()
+
+	return true
+}
+
+
+// parseMemberExpression parse `object.member`, where member must be
+// an identifier.
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	expr := &ast.MemberExpression{ Token: p.currentToken, Object: object }
+
+	if !p.expectPeekTokenToBe(token.IDENTIFIER) {
+		return nil
+	}
+	expr.Member = &ast.Identifier{ Token: p.currentToken, Value: p.currentToken.Literal }
+
+	return expr
+}
+
 func (p *Parser) parseFunction() ast.Expression {
-	fnExpr := &ast.FunctionLiteral{ Token: p.currentToken }
+	fnExpr := p.parseFunctionLiteral(p.currentToken)
+
+	if fnExpr == nil {
+		return nil
+	}
+
+	return fnExpr
+}
+
+// parseFunctionDeclaration parse `fn name(params) { ... }`.
+func (p *Parser) parseFunctionDeclaration() *ast.FunctionDeclaration {
+	stmt := &ast.FunctionDeclaration{ Token: p.currentToken }
+
+	p.nextToken()
+
+	stmt.Name = &ast.Identifier{ Token: p.currentToken, Value: p.currentToken.Literal }
+	stmt.Function = p.parseFunctionLiteral(stmt.Token)
+
+	if stmt.Function == nil {
+		return nil
+	}
+	stmt.Function.Name = stmt.Name.Value
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseFunctionLiteral parse the parameters and the body of a function,
+// the current token being the one right before the parameters.
+func (p *Parser) parseFunctionLiteral(fnToken token.Token) *ast.FunctionLiteral {
+	fnExpr := &ast.FunctionLiteral{ Token: fnToken }
 
 	if !p.expectPeekTokenToBe(token.LPAREN) {
 		return nil
 	}
 	fnExpr.Params = p.parseFunctionParams()
 
+	if fnExpr.Params == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		if fnExpr.ReturnType = p.parseAnnotation(); fnExpr.ReturnType == nil {
+			return nil
+		}
+	}
+
 	if !p.expectPeekTokenToBe(token.LBRACE) {
 		return nil
 	}
@@ -388,32 +938,245 @@ func (p *Parser) parseFunction() ast.Expression {
 	return fnExpr
 }
 
-func (p *Parser) parseFunctionParams() []*ast.Identifier {
-	params := []*ast.Identifier{}
+func (p *Parser) parseFunctionParams() []*ast.Parameter {
+	params := []*ast.Parameter{}
 	p.nextToken()
 
 	if p.currentTokenIs(token.RPAREN) {
 		return params
 	}
 
-	param := &ast.Identifier{ Token: p.currentToken, Value: p.currentToken.Literal }
+	param := p.parseFunctionParam()
+
+	if param == nil {
+		return nil
+	}
 	params = append(params, param)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
 		
-		param := &ast.Identifier{ Token: p.currentToken, Value: p.currentToken.Literal }
+		param := p.parseFunctionParam()
+
+		if param == nil {
+			return nil
+		}
 		params = append(params, param)
 	}
 
-	if !p.expectPeekTokenToBe(token.RPAREN) {
+	if !p.expectPeekTokenToBe(token.RPAREN) || !p.checkFunctionParams(params) {
 		return nil
 	}
 
 	return params
 }
 
+// parseFunctionParam parse a parameter name, optionally preceded
+// by `...` or followed by a type annotation and a default value.
+func (p *Parser) parseFunctionParam() *ast.Parameter {
+	param := &ast.Parameter{ Token: p.currentToken }
+
+	if p.currentTokenIs(token.ELLIPSIS) {
+		param.Rest = true
+		p.nextToken()
+	}
+
+	if !p.currentTokenIs(token.IDENTIFIER) {
+		msg := fmt.Sprintf(
+			"Invalid parameter: expected an identifier, but got %q",
+			p.currentToken.Literal,
+		)
+		p.addError(msg)
+
+		return nil
+	}
+	param.Name = &ast.Identifier{ Token: p.currentToken, Value: p.currentToken.Literal }
+
+	if p.peekTokenIs(token.COLON) {
+		if param.Type = p.parseAnnotation(); param.Type == nil {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+
+		param.Default = p.parseExpression(LOWEST)
+	}
+
+	return param
+}
+
+// basicTypes are the names of the types annotations are built from,
+// along with `array<T>`, `map<K, V>` and function types.
+var basicTypes = []string{ "int", "float", "bool", "string", "any" }
+
+// parseAnnotation parse the type annotation following
+// the peek token, which is the `:` introducing it.
+func (p *Parser) parseAnnotation() ast.TypeExpression {
+	p.nextToken()
+	p.nextToken()
+
+	return p.parseType()
+}
+
+// parseType parse a type, the current token being its first one.
+func (p *Parser) parseType() ast.TypeExpression {
+	tok := p.currentToken
+
+	switch {
+
+	case p.currentTokenIs(token.FUNCTION):
+		return p.parseFunctionType()
+
+	case p.currentTokenIs(token.IDENTIFIER) && tok.Literal == "array":
+		if !p.expectPeekTokenToBe(token.LESSER_THAN) {
+			return nil
+		}
+		p.nextToken()
+		element := p.parseType()
+
+		if element == nil || !p.expectTypeEnd() {
+			return nil
+		}
+		return &ast.ArrayType{ Token: tok, Element: element }
+
+	case p.currentTokenIs(token.IDENTIFIER) && tok.Literal == "map":
+		if !p.expectPeekTokenToBe(token.LESSER_THAN) {
+			return nil
+		}
+		p.nextToken()
+		key := p.parseType()
+
+		if key == nil || !p.expectPeekTokenToBe(token.COMMA) {
+			return nil
+		}
+		p.nextToken()
+		value := p.parseType()
+
+		if value == nil || !p.expectTypeEnd() {
+			return nil
+		}
+		return &ast.MapType{ Token: tok, Key: key, Value: value }
+
+	case p.currentTokenIs(token.IDENTIFIER) && slices.Contains(basicTypes, tok.Literal):
+		return &ast.NamedType{ Token: tok, Name: tok.Literal }
+	}
+
+	p.addError(fmt.Sprintf("Unknown type %q", tok.Literal))
+
+	return nil
+}
+
+// parseFunctionType parse `fn(int, ...array<int>): bool`,
+// the current token being `fn`.
+func (p *Parser) parseFunctionType() ast.TypeExpression {
+	fnType := &ast.FunctionType{ Token: p.currentToken, Params: []ast.TypeExpression{} }
+
+	if !p.expectPeekTokenToBe(token.LPAREN) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		if fnType.Rest {
+			p.addError("Rest parameter must be the last parameter of a function type")
+			return nil
+		}
+		p.nextToken()
+
+		if p.currentTokenIs(token.ELLIPSIS) {
+			fnType.Rest = true
+			p.nextToken()
+		}
+		param := p.parseType()
+
+		if param == nil {
+			return nil
+		}
+		fnType.Params = append(fnType.Params, param)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeekTokenToBe(token.RPAREN) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		if fnType.Return = p.parseAnnotation(); fnType.Return == nil {
+			return nil
+		}
+	}
+
+	return fnType
+}
+
+// expectTypeEnd expect the `>` closing the parameters of a type. The
+// lexer read `>>` and `>=` as single tokens, so the `>` ending a nested
+// type, like in `array<array<int>>`, is first split from them.
+func (p *Parser) expectTypeEnd() bool {
+	if p.peekTokenIs(token.RIGHT_SHIFT) || p.peekTokenIs(token.GREATER_OR_EQUAL_TO) {
+		rest := p.peekToken
+		rest.Literal = rest.Literal[1:]
+		rest.Column++
+		rest.Type = token.GREATER_THAN
+
+		if rest.Literal == "=" {
+			rest.Type = token.ASSIGN
+		}
+
+		p.currentToken = p.peekToken
+		p.currentToken.Type = token.GREATER_THAN
+		p.currentToken.Literal = ">"
+		p.peekToken = rest
+
+		return true
+	}
+
+	return p.expectPeekTokenToBe(token.GREATER_THAN)
+}
+
+// checkFunctionParams check that parameter names are unique, that
+// parameters with a default value come after the ones without, and
+// that a rest parameter, without default value, come last.
+func (p *Parser) checkFunctionParams(params []*ast.Parameter) bool {
+	seen := map[string]bool{}
+	withDefault := false
+
+	for i, param := range params {
+		name := param.Name.Value
+
+		switch {
+
+		case seen[name]:
+			p.addError(fmt.Sprintf("Duplicate parameter name %q", name))
+
+		case param.Rest && i != len(params) - 1:
+			p.addError(fmt.Sprintf("Rest parameter %q must be the last parameter", name))
+
+		case param.Rest && param.Default != nil:
+			p.addError(fmt.Sprintf("Rest parameter %q cannot have a default value", name))
+
+		case !param.Rest && param.Default == nil && withDefault:
+			p.addError(fmt.Sprintf("Parameter %q without default value follows a parameter with one", name))
+
+		default:
+			seen[name] = true
+			withDefault = withDefault || param.Default != nil
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
+
 
 func (p *Parser) parseFunctionCall(function ast.Expression) ast.Expression {
 	fnCall := &ast.FunctionCallExpression{
@@ -425,22 +1188,45 @@ func (p *Parser) parseFunctionCall(function ast.Expression) ast.Expression {
 	return fnCall
 }
 
+// parseFunctionCallArguments parse the arguments of a call, where
+// positional arguments, possibly spread like `...args`, come before
+// named arguments like `y: 2`.
 func (p *Parser) parseFunctionCallArguments() []ast.Expression {
 	args := []ast.Expression{}
+	named := map[string]bool{}
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
 		return args
 	}
 
-	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
-
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
+	for {
 		p.nextToken()
 
-		args = append(args, p.parseExpression(LOWEST))
+		arg := p.parseFunctionCallArgument()
+
+		if arg == nil {
+			return nil
+		}
+
+		if namedArg, ok := arg.(*ast.NamedArgument); ok {
+			if named[namedArg.Name.Value] {
+				p.addError(fmt.Sprintf("Duplicate named argument %q", namedArg.Name.Value))
+				return nil
+			}
+			named[namedArg.Name.Value] = true
+
+		} else if len(named) > 0 {
+			p.addError(fmt.Sprintf("Positional argument %q follows named arguments", arg.String()))
+			return nil
+		}
+
+		args = append(args, arg)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
 	if !p.expectPeekTokenToBe(token.RPAREN) {
@@ -450,6 +1236,46 @@ func (p *Parser) parseFunctionCallArguments() []ast.Expression {
 	return args
 }
 
+func (p *Parser) parseFunctionCallArgument() ast.Expression {
+
+	switch {
+
+	case p.currentTokenIs(token.ELLIPSIS):
+		spread := &ast.SpreadExpression{ Token: p.currentToken }
+		p.nextToken()
+
+		spread.Value = p.parseExpression(LOWEST)
+
+		if spread.Value == nil {
+			return nil
+		}
+		return spread
+
+	case p.currentTokenIs(token.IDENTIFIER) && p.peekTokenIs(token.COLON):
+		namedArg := &ast.NamedArgument{
+			Token: p.currentToken,
+			Name: &ast.Identifier{ Token: p.currentToken, Value: p.currentToken.Literal },
+		}
+		p.nextToken()
+		p.nextToken()
+
+		namedArg.Value = p.parseExpression(LOWEST)
+
+		if namedArg.Value == nil {
+			return nil
+		}
+		return namedArg
+
+	default:
+		arg := p.parseExpression(LOWEST)
+
+		if arg == nil {
+			return nil
+		}
+		return arg
+	}
+}
+
 
 
 // Helper functions next:
@@ -3,8 +3,10 @@ package parser
 import (
 	"fmt"
 	"monkey/internal/ast"
+	"monkey/internal/corpus"
 	"monkey/internal/lexer"
 	"slices"
+	"strings"
 	"testing"
 )
 
@@ -174,6 +176,7 @@ func TestPrefixExpressionParsing(t *testing.T) {
 		{"-1.5;", "!", 1.5},
 		{"!true;", "!", true},
 		{"!false;", "!", false},
+		{"~5;", "~", 5},
 	}
 
 	for _, tt := range tests {
@@ -212,6 +215,107 @@ func TestPrefixExpressionParsing(t *testing.T) {
 	}
 }
 
+func TestUpdateExpressionParsing(t *testing.T) {
+
+	t.Run("it should parse prefix and postfix forms", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			operator string
+			prefix   bool
+		}{
+			{"++i;", "++", true},
+			{"--i;", "--", true},
+			{"i++;", "++", false},
+			{"i--;", "--", false},
+			{"--a[0];", "--", true},
+			{"a[0]++;", "++", false},
+		}
+
+		for _, tt := range tests {
+			lex := lexer.New(tt.input)
+			parser := New(lex)
+
+			program := parser.ParseProgram()
+			checkParserErrors(t, parser)
+
+			stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+			if tt.prefix {
+				expr, ok := stmt.Expression.(*ast.PrefixExpression)
+
+				if !ok {
+					t.Fatalf(
+						"Expecting stmt.Expression to be of type *ast.PrefixExpression, but got %T\n",
+						stmt.Expression,
+					)
+				}
+
+				if expr.Operator != tt.operator {
+					t.Fatalf(
+						"Expecting expr.Operator to be %q, but got %q\n",
+						tt.operator, expr.Operator,
+					)
+				}
+				if expr.Right.String() != "i" && expr.Right.String() != "(a[0])" {
+					t.Fatalf("Unexpected operand %q\n", expr.Right.String())
+				}
+				continue
+			}
+
+			expr, ok := stmt.Expression.(*ast.PostfixExpression)
+
+			if !ok {
+				t.Fatalf(
+					"Expecting stmt.Expression to be of type *ast.PostfixExpression, but got %T\n",
+					stmt.Expression,
+				)
+			}
+
+			if expr.Operator != tt.operator {
+				t.Fatalf(
+					"Expecting expr.Operator to be %q, but got %q\n",
+					tt.operator, expr.Operator,
+				)
+			}
+			if expr.Left.String() != "i" && expr.Left.String() != "(a[0])" {
+				t.Fatalf("Unexpected operand %q\n", expr.Left.String())
+			}
+		}
+	})
+
+	t.Run("it should require an assignable operand", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"5++", "Invalid operand for '++': expected an identifier or an index expression, but got \"5\""},
+			{"--5", "Invalid operand for '--': expected an identifier or an index expression, but got \"5\""},
+			{"--(a + b)", "Invalid operand for '--': expected an identifier or an index expression, but got \"(a + b)\""},
+			{"++i++", "Invalid operand for '++': expected an identifier or an index expression, but got \"(i++)\""},
+			{"a[1:2]++", "Invalid operand for '++': expected an identifier or an index expression, but got \"(a[1:2])\""},
+			{"f()++", "Invalid operand for '++': expected an identifier or an index expression, but got \"f()\""},
+		}
+
+		for i, tt := range tests {
+			lex := lexer.New(tt.input)
+			parser := New(lex)
+
+			parser.ParseProgram()
+
+			if len(parser.Errors()) == 0 {
+				t.Fatalf("[test #%d]: Expecting parser errors for %q, but got none\n", i, tt.input)
+			}
+
+			if parser.Errors()[0] != tt.expected {
+				t.Fatalf(
+					"[test #%d]: Expecting error %q, but got %q\n",
+					i, tt.expected, parser.Errors()[0],
+				)
+			}
+		}
+	})
+}
+
 func TestInfixExpressionParsing(t *testing.T) {
 	tests := []struct {
 		input      string
@@ -227,6 +331,12 @@ func TestInfixExpressionParsing(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5.5;", 5, "!=", 5.5},
+		{"5 ** 2;", 5, "**", 2},
+		{"5 & 3;", 5, "&", 3},
+		{"5 | 3;", 5, "|", 3},
+		{"5 ^ 3;", 5, "^", 3},
+		{"5 << 3;", 5, "<<", 3},
+		{"5 >> 3;", 5, ">>", 3},
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
@@ -372,6 +482,106 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"(2 ** 3) ** 2",
+			"((2 ** 3) ** 2)",
+		},
+		{
+			"a * b ** c % d",
+			"(a * ((b ** c) % d))",
+		},
+		{
+			"-a ** b",
+			"(-(a ** b))",
+		},
+		{
+			"a ** -b",
+			"(a ** (-b))",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b == c",
+			"(a & (b == c))",
+		},
+		{
+			"a << b + c",
+			"(a << (b + c))",
+		},
+		{
+			"a << b < c >> d",
+			"((a << b) < (c >> d))",
+		},
+		{
+			"~a & ~b",
+			"((~a) & (~b))",
+		},
+		{
+			"a | b | c",
+			"((a | b) | c)",
+		},
+		{
+			"a++ + --b",
+			"((a++) + (--b))",
+		},
+		{
+			"lib.f(a.b) * -lib.x",
+			"((lib.f)((a.b)) * (-(lib.x)))",
+		},
+		{
+			"a.b.c[0] ** 2",
+			"((((a.b).c)[0]) ** 2)",
+		},
+		{
+			"-a--",
+			"(-(a--))",
+		},
+		{
+			"a * b++",
+			"(a * (b++))",
+		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"-a[0]++",
+			"(-((a[0])++))",
+		},
+		{
+			"1..n + 1",
+			"(1..(n + 1))",
+		},
+		{
+			"a..=b | c",
+			"(a..=(b | c))",
+		},
+		{
+			"a[1..3]",
+			"(a[(1..3)])",
+		},
+		{
+			"5--3",
+			"(5 - (-3))",
+		},
+		{
+			"2 * 5--3 * 2",
+			"((2 * 5) - ((-3) * 2))",
+		},
+		{
+			"(a)--b",
+			"(a - (-b))",
+		},
 	}
 
 	for i, tt := range tests {
@@ -565,72 +775,115 @@ func TestIfElseExpression(t *testing.T) {
 
 		testIdentifier(t, alternative.Expression, "y")
 	})
-}
 
-func TestFunctionLiteralParsing(t *testing.T) {
-	input := `fn(x, y) { x + y}`
-	lex := lexer.New(input)
-	parser := New(lex)
+	t.Run("IfElseExpression without condition parenthesis", func(t *testing.T) {
+		input := `if x < y { x } else { y }`
+		lex := lexer.New(input)
+		parser := New(lex)
 
-	program := parser.ParseProgram()
-	checkParserErrors(t, parser)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
 
-	if len(program.Statements) != 1 {
-		t.Fatalf(
-			"Expecting program.Statements to contains 1 Statement, but got %d\n",
-			len(program.Statements),
-		)
-	}
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		ifElseExpr, ok := stmt.Expression.(*ast.IfElseExpression)
 
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf(
+				"Expecting stmt.Expression to be of type *ast.IfElseExpression, but got %T\n",
+				stmt.Expression,
+			)
+		}
 
-	if !ok {
-		t.Fatalf(
-			"Expecting program.Statements[0] to be of type *ast.ExpressionStatement, but got %T\n",
-			stmt,
-		)
-	}
+		if !testInfix(t, ifElseExpr.Condition, "x", "<", "y") {
+			return
+		}
 
-	funcLiteral, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if ifElseExpr.Alternative == nil {
+			t.Fatal("Expecting ifElseExpr.Alternative not to be nil")
+		}
+	})
 
-	if !ok {
-		t.Fatalf(
-			"Expecting stmt.Expression to be of type *ast.FunctionLiteral, but got %T\n",
-			funcLiteral,
-		)
-	}
+	t.Run("IfElseExpression with else if chain", func(t *testing.T) {
+		input := `if (x < y) { x } else if (x > y) { y } else { z }`
+		lex := lexer.New(input)
+		parser := New(lex)
 
-	if len(funcLiteral.Params) != 2 {
-		t.Fatalf(
-			"Expecting funcLiteral.Params to contains 2 Params but got %d\n",
-			len(funcLiteral.Params),
-		)
-	}
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
 
-	if !testLiteral(t, funcLiteral.Params[0], "x") || !testLiteral(t, funcLiteral.Params[1], "y") {
-		return
-	}
+		if len(program.Statements) != 1 {
+			t.Fatalf(
+				"Expecting program.Statements to contains 1 Statement, got %d\n",
+				len(program.Statements),
+			)
+		}
 
-	bodyStmt, ok := funcLiteral.Body.Statements[0].(*ast.ExpressionStatement)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		ifElseExpr, ok := stmt.Expression.(*ast.IfElseExpression)
 
-	if !ok {
-		t.Fatalf(
-			"Expecting funcLiteral.Body.Statements[0] to be of type *ast.ExpressionStatement, but got %T\n",
-			bodyStmt,
-		)
-	}
+		if !ok {
+			t.Fatalf(
+				"Expecting stmt.Expression to be of type *ast.IfElseExpression, but got %T\n",
+				stmt.Expression,
+			)
+		}
 
-	testInfix(t, bodyStmt.Expression, "x", "+", "y")
+		if len(ifElseExpr.Alternative.Statements) != 1 {
+			t.Fatalf(
+				"Expecting ifElseExpr.Alternative to contains 1 Statement, but got %d\n",
+				len(ifElseExpr.Alternative.Statements),
+			)
+		}
+
+		nested := ifElseExpr.ElseIf()
+
+		if nested == nil {
+			t.Fatalf(
+				"Expecting ifElseExpr.Alternative to hold an *ast.IfElseExpression, but got %q\n",
+				ifElseExpr.Alternative.String(),
+			)
+		}
+
+		if !testInfix(t, nested.Condition, "x", ">", "y") {
+			return
+		}
+
+		consequence := nested.Consequence.Statements[0].(*ast.ExpressionStatement)
+		alternative := nested.Alternative.Statements[0].(*ast.ExpressionStatement)
+
+		if !testIdentifier(t, consequence.Expression, "y") {
+			return
+		}
+
+		testIdentifier(t, alternative.Expression, "z")
+	})
 }
 
-func TestFunctionParamsParsing(t *testing.T) {
+func TestIfElseExpressionString(t *testing.T) {
 	tests := []struct {
-		input          string
-		expectedParams []string
+		input    string
+		expected string
 	}{
-		{input: "fn() {}", expectedParams: []string{}},
-		{input: "fn(x) {}", expectedParams: []string{"x"}},
-		{input: "fn(x, y, z) {}", expectedParams: []string{"x", "y", "z"}},
+		{
+			"if (x < y) { x }",
+			"if (x < y) { x; }",
+		},
+		{
+			"if x { x } else { y }",
+			"if x { x; } else { y; }",
+		},
+		{
+			"if (x) { let a = 1; a } else { }",
+			"if x { let a = 1; a; } else { }",
+		},
+		{
+			"if a { 1 } else if b { 2 } else if (c) { 3 } else { 4 }",
+			"if a { 1; } else if b { 2; } else if c { 3; } else { 4; }",
+		},
+		{
+			"if a { 1 } else { if b { 2 } }",
+			"if a { 1; } else if b { 2; }",
+		},
 	}
 
 	for i, tt := range tests {
@@ -640,76 +893,1076 @@ func TestFunctionParamsParsing(t *testing.T) {
 		program := parser.ParseProgram()
 		checkParserErrors(t, parser)
 
-		stmt := program.Statements[0].(*ast.ExpressionStatement)
-		function := stmt.Expression.(*ast.FunctionLiteral)
+		actual := program.String()
 
-		if len(function.Params) != len(tt.expectedParams) {
+		if actual != tt.expected {
 			t.Fatalf(
-				"[test #%d]Expecting function.Params to contains %d Params, but got %d\n",
-				i, len(tt.expectedParams), len(function.Params),
+				"[test #%d]: Expected program.String() to return %q, but got %q\n",
+				i, tt.expected, actual,
 			)
 		}
 
-		for i, param := range tt.expectedParams {
-			testLiteral(t, function.Params[i], param)
+		// The canonical form must parse back to the same tree.
+		reparser := New(lexer.New(actual))
+		reparsed := reparser.ParseProgram()
+		checkParserErrors(t, reparser)
+
+		if reparsed.String() != actual {
+			t.Fatalf(
+				"[test #%d]: Expected %q to round-trip, but got %q\n",
+				i, actual, reparsed.String(),
+			)
 		}
 	}
 }
 
-func TestFunctionCallParsing(t *testing.T) {
-	input := `add(1, 2 * 3, 4 + 5);`
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello \"world\"\n";`
 	lex := lexer.New(input)
 	parser := New(lex)
 
 	program := parser.ParseProgram()
 	checkParserErrors(t, parser)
 
-	if len(program.Statements) != 1 {
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+
+	if !ok {
 		t.Fatalf(
-			"Expecting program.Statements to contains 1 Statement, but got %d\n",
-			len(program.Statements),
+			"Expecting stmt.Expression to be of type *ast.StringLiteral, but got %T\n",
+			stmt.Expression,
 		)
 	}
 
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if literal.Value != "hello \"world\"\n" {
+		t.Fatalf(
+			"Expecting literal.Value to be %q, but got %q\n",
+			"hello \"world\"\n", literal.Value,
+		)
+	}
 
-	if !ok {
+	if literal.String() != `"hello \"world\"\n"` {
 		t.Fatalf(
-			"Expecting program.Statements[0] to be of type *ast.ExpressionStatement, but got %T\n",
-			stmt,
+			"Expecting literal.String() to return %q, but got %q\n",
+			`"hello \"world\"\n"`, literal.String(),
 		)
 	}
+}
 
-	fnCallExpr, ok := stmt.Expression.(*ast.FunctionCallExpression)
+func TestArrayLiteralParsing(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+	lex := lexer.New(input)
+	parser := New(lex)
+
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
 
 	if !ok {
 		t.Fatalf(
-			"Expecting stmt.Expression to be of type *ast.FunctionCallExpression, but got %T\n",
-			fnCallExpr,
+			"Expecting stmt.Expression to be of type *ast.ArrayLiteral, but got %T\n",
+			stmt.Expression,
 		)
 	}
 
-	if !testIdentifier(t, fnCallExpr.Function, "add") {
-		return
+	if len(array.Elements) != 3 {
+		t.Fatalf(
+			"Expecting array.Elements to contains 3 Elements, but got %d\n",
+			len(array.Elements),
+		)
 	}
 
-	if len(fnCallExpr.Arguments) != 3 {
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfix(t, array.Elements[1], 2, "*", 2)
+	testInfix(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestIndexExpressionParsing(t *testing.T) {
+	input := "myArray[1 + 1]"
+	lex := lexer.New(input)
+	parser := New(lex)
+
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	indexExpr, ok := stmt.Expression.(*ast.IndexExpression)
+
+	if !ok {
 		t.Fatalf(
-			"Expecting fnCallExpr.Arguments to contains 3 Arguments, but got %d\n",
-			len(fnCallExpr.Arguments),
+			"Expecting stmt.Expression to be of type *ast.IndexExpression, but got %T\n",
+			stmt.Expression,
 		)
 	}
 
-	if !testLiteral(t, fnCallExpr.Arguments[0], 1) ||
-		!testInfix(t, fnCallExpr.Arguments[1], 2, "*", 3) {
+	if !testIdentifier(t, indexExpr.Left, "myArray") {
 		return
 	}
-	testInfix(t, fnCallExpr.Arguments[2], 4, "+", 5)
+
+	testInfix(t, indexExpr.Index, 1, "+", 1)
 }
 
+func TestSliceExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		start    any
+		stop     any
+		step     any
+		expected string
+	}{
+		{"arr[1:3]", 1, 3, nil, "(arr[1:3])"},
+		{"str[:5]", nil, 5, nil, "(str[:5])"},
+		{"str[2:]", 2, nil, nil, "(str[2:])"},
+		{"arr[:]", nil, nil, nil, "(arr[:])"},
+		{"arr[::-1]", nil, nil, -1, "(arr[::(-1)])"},
+		{"arr[1::2]", 1, nil, 2, "(arr[1::2])"},
+		{"arr[a:b:c]", "a", "b", "c", "(arr[a:b:c])"},
+	}
+
+	for i, tt := range tests {
+		lex := lexer.New(tt.input)
+		parser := New(lex)
+
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
 
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		slice, ok := stmt.Expression.(*ast.SliceExpression)
 
-// Helpers functions next:
+		if !ok {
+			t.Fatalf(
+				"[test #%d]: Expecting stmt.Expression to be of type *ast.SliceExpression, but got %T\n",
+				i, stmt.Expression,
+			)
+		}
+
+		bounds := []struct{
+			expr     ast.Expression
+			expected any
+		}{
+			{ slice.Start, tt.start },
+			{ slice.Stop, tt.stop },
+			{ slice.Step, tt.step },
+		}
+
+		for _, bound := range bounds {
+			if bound.expected == nil {
+				if bound.expr != nil {
+					t.Fatalf(
+						"[test #%d]: Expecting omitted bound to be nil, but got %q\n",
+						i, bound.expr.String(),
+					)
+				}
+				continue
+			}
+
+			if expected, ok := bound.expected.(int); ok && expected < 0 {
+				prefix, ok := bound.expr.(*ast.PrefixExpression)
+
+				if !ok || !testLiteral(t, prefix.Right, -expected) {
+					t.Fatalf("[test #%d]: Expecting bound to be %d\n", i, expected)
+				}
+				continue
+			}
+
+			testLiteral(t, bound.expr, bound.expected)
+		}
+
+		if slice.String() != tt.expected {
+			t.Fatalf(
+				"[test #%d]: Expecting slice.String() to return %q, but got %q\n",
+				i, tt.expected, slice.String(),
+			)
+		}
+	}
+}
+
+func TestRangeExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input     string
+		inclusive bool
+	}{
+		{"1..10", false},
+		{"1..=10", true},
+	}
+
+	for i, tt := range tests {
+		lex := lexer.New(tt.input)
+		parser := New(lex)
+
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		rangeExpr, ok := stmt.Expression.(*ast.RangeExpression)
+
+		if !ok {
+			t.Fatalf(
+				"[test #%d]: Expecting stmt.Expression to be of type *ast.RangeExpression, but got %T\n",
+				i, stmt.Expression,
+			)
+		}
+
+		if rangeExpr.Inclusive != tt.inclusive {
+			t.Fatalf(
+				"[test #%d]: Expecting rangeExpr.Inclusive to be %t, but got %t\n",
+				i, tt.inclusive, rangeExpr.Inclusive,
+			)
+		}
+
+		testIntegerLiteral(t, rangeExpr.Start, 1)
+		testIntegerLiteral(t, rangeExpr.End, 10)
+	}
+}
+
+func TestForInStatementParsing(t *testing.T) {
+	tests := []string{
+		"for i in 0..10 { sum + i; }",
+		"for (i in 0..10) { sum + i; }",
+	}
+
+	for i, input := range tests {
+		lex := lexer.New(input)
+		parser := New(lex)
+
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf(
+				"[test #%d]: Expecting program.Statements to contains 1 Statement, but got %d\n",
+				i, len(program.Statements),
+			)
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ForInStatement)
+
+		if !ok {
+			t.Fatalf(
+				"[test #%d]: Expecting program.Statements[0] to be of type *ast.ForInStatement, but got %T\n",
+				i, program.Statements[0],
+			)
+		}
+
+		if !testIdentifier(t, stmt.Variable, "i") {
+			return
+		}
+
+		if _, ok := stmt.Iterable.(*ast.RangeExpression); !ok {
+			t.Fatalf(
+				"[test #%d]: Expecting stmt.Iterable to be of type *ast.RangeExpression, but got %T\n",
+				i, stmt.Iterable,
+			)
+		}
+
+		body := stmt.Body.Statements[0].(*ast.ExpressionStatement)
+		testInfix(t, body.Expression, "sum", "+", "i")
+
+		if stmt.String() != "for i in (0..10) { (sum + i); }" {
+			t.Fatalf(
+				"[test #%d]: Unexpected stmt.String(): %q\n",
+				i, stmt.String(),
+			)
+		}
+	}
+
+	// Like after a function declaration, a semicolon may follow the body.
+	lex := lexer.New("for i in 1..3 { i }; 5")
+	parser := New(lex)
+
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("Expecting program.Statements to contains 2 Statements, but got %d\n", len(program.Statements))
+	}
+
+	if _, ok := program.Statements[0].(*ast.ForInStatement); !ok {
+		t.Fatalf("Expecting program.Statements[0] to be of type *ast.ForInStatement, but got %T\n", program.Statements[0])
+	}
+
+	if program.Statements[1].String() != "5" {
+		t.Fatalf("Expecting program.Statements[1].String() to be %q, but got %q\n", "5", program.Statements[1].String())
+	}
+}
+
+func TestThrowStatementParsing(t *testing.T) {
+	lex := lexer.New(`throw "boom"; throw e`)
+	parser := New(lex)
+
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("Expecting program.Statements to contains 2 Statements, but got %d\n", len(program.Statements))
+	}
+
+	expected := []string{ `throw "boom";`, "throw e;" }
+
+	for i, stmt := range program.Statements {
+		throwStmt, ok := stmt.(*ast.ThrowStatement)
+
+		if !ok {
+			t.Fatalf("[test #%d]: Expecting stmt to be of type *ast.ThrowStatement, but got %T\n", i, stmt)
+		}
+
+		if throwStmt.String() != expected[i] {
+			t.Fatalf("[test #%d]: Expecting stmt.String() to be %q, but got %q\n", i, expected[i], throwStmt.String())
+		}
+	}
+}
+
+func TestTryStatementParsing(t *testing.T) {
+
+	t.Run("it should parse try with catch and finally blocks", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "try { f(); } catch (e) { g(e); }", "try { f(); } catch (e) { g(e); }" },
+			{ "try { f() } catch e { g(e) }", "try { f(); } catch (e) { g(e); }" },
+			{ "try { f() } catch { 0 }", "try { f(); } catch { 0; }" },
+			{ "try { f() } finally { done() }", "try { f(); } finally { done(); }" },
+			{ "try { f() } catch (e) { 0 } finally { done() }", "try { f(); } catch (e) { 0; } finally { done(); }" },
+		}
+
+		for i, tt := range tests {
+			lex := lexer.New(tt.input)
+			parser := New(lex)
+
+			program := parser.ParseProgram()
+			checkParserErrors(t, parser)
+
+			if len(program.Statements) != 1 {
+				t.Fatalf(
+					"[test #%d]: Expecting program.Statements to contains 1 Statement, but got %d\n",
+					i, len(program.Statements),
+				)
+			}
+
+			stmt, ok := program.Statements[0].(*ast.TryStatement)
+
+			if !ok {
+				t.Fatalf(
+					"[test #%d]: Expecting program.Statements[0] to be of type *ast.TryStatement, but got %T\n",
+					i, program.Statements[0],
+				)
+			}
+
+			if stmt.String() != tt.expected {
+				t.Fatalf("[test #%d]: Expecting stmt.String() to be %q, but got %q\n", i, tt.expected, stmt.String())
+			}
+		}
+	})
+
+	t.Run("it should accept a semicolon after the last block", func(t *testing.T) {
+		tests := []string{
+			"try { 1 } catch (e) { 2 }; 5",
+			"try { 1 } finally { 2 }; 5",
+		}
+
+		for i, input := range tests {
+			lex := lexer.New(input)
+			parser := New(lex)
+
+			program := parser.ParseProgram()
+			checkParserErrors(t, parser)
+
+			if len(program.Statements) != 2 {
+				t.Fatalf(
+					"[test #%d]: Expecting program.Statements to contains 2 Statements, but got %d\n",
+					i, len(program.Statements),
+				)
+			}
+
+			if _, ok := program.Statements[0].(*ast.TryStatement); !ok {
+				t.Fatalf(
+					"[test #%d]: Expecting program.Statements[0] to be of type *ast.TryStatement, but got %T\n",
+					i, program.Statements[0],
+				)
+			}
+
+			if program.Statements[1].String() != "5" {
+				t.Fatalf(
+					"[test #%d]: Expecting program.Statements[1].String() to be %q, but got %q\n",
+					i, "5", program.Statements[1].String(),
+				)
+			}
+		}
+	})
+
+	t.Run("it should report malformed try statements", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "try { f() }", "Missing catch or finally block after try block" },
+			{ "try f()", "Expected next token to be '{', but got 'identifier' instead." },
+			{ "try { f() } catch (e { 0 }", "Expected next token to be ')', but got '{' instead." },
+		}
+
+		for i, tt := range tests {
+			lex := lexer.New(tt.input)
+			parser := New(lex)
+
+			parser.ParseProgram()
+
+			if len(parser.Errors()) == 0 {
+				t.Fatalf("[test #%d]: Expecting parser errors for %q, but got none\n", i, tt.input)
+			}
+
+			if parser.Errors()[0] != tt.expected {
+				t.Fatalf(
+					"[test #%d]: Expecting error %q, but got %q\n",
+					i, tt.expected, parser.Errors()[0],
+				)
+			}
+		}
+	})
+}
+
+func TestImportExportParsing(t *testing.T) {
+
+	t.Run("it should parse imports and exports", func(t *testing.T) {
+		input := `
+import "lib/math.mk" as math;
+export let x = 1;
+export const y = 2;
+export fn f(a) { a }
+`
+		expected := []string{
+			`import "lib/math.mk" as math;`,
+			"export let x = 1;",
+			"export const y = 2;",
+			"export fn f(a) { a; }",
+		}
+
+		lex := lexer.New(input)
+		parser := New(lex)
+
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != len(expected) {
+			t.Fatalf(
+				"Expecting program.Statements to contains %d Statements, but got %d\n",
+				len(expected), len(program.Statements),
+			)
+		}
+
+		for i, stmt := range program.Statements {
+			if stmt.String() != expected[i] {
+				t.Fatalf("[test #%d]: Expecting stmt.String() to be %q, but got %q\n", i, expected[i], stmt.String())
+			}
+		}
+
+		names := []string{ "x", "y", "f" }
+
+		for i, name := range names {
+			export, ok := program.Statements[i + 1].(*ast.ExportStatement)
+
+			if !ok {
+				t.Fatalf("[test #%d]: Expecting stmt to be of type *ast.ExportStatement, but got %T\n", i, program.Statements[i + 1])
+			}
+
+			if export.Name() != name {
+				t.Fatalf("[test #%d]: Expecting export.Name() to be %q, but got %q\n", i, name, export.Name())
+			}
+		}
+	})
+
+	t.Run("it should report malformed imports and exports", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ "import lib", "Expected next token to be 'string', but got 'identifier' instead." },
+			{ `import "lib.mk"`, "Expected next token to be 'as', but got 'eof' instead." },
+			{ `import "lib.mk" as "lib"`, "Expected next token to be 'identifier', but got 'string' instead." },
+			{ "export 1", "Invalid export: expected a let, const or fn declaration, but got \"1\"" },
+			{ "export fn(x) { x }", "Invalid export: expected a let, const or fn declaration, but got \"fn\"" },
+			{ "fn f() { export let x = 1; }", "Export is only allowed at the top level of a module" },
+			{ "a.(b)", "Expected next token to be 'identifier', but got '(' instead." },
+		}
+
+		for i, tt := range tests {
+			lex := lexer.New(tt.input)
+			parser := New(lex)
+
+			parser.ParseProgram()
+
+			if len(parser.Errors()) == 0 {
+				t.Fatalf("[test #%d]: Expecting parser errors for %q, but got none\n", i, tt.input)
+			}
+
+			if parser.Errors()[0] != tt.expected {
+				t.Fatalf(
+					"[test #%d]: Expecting error %q, but got %q\n",
+					i, tt.expected, parser.Errors()[0],
+				)
+			}
+		}
+	})
+}
+
+func TestHashLiteralParsing(t *testing.T) {
+
+	t.Run("it should parse hash literals", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+			pairs		int
+		}{
+			{ "{}", "{}", 0 },
+			{ `{"one": 1, "two": 2,}`, `{"one": 1, "two": 2}`, 2 },
+			{ `{"a" + "b": 1 * 2, key: [1][0], 3: true}`, `{("a" + "b"): (1 * 2), key: ([1][0]), 3: true}`, 3 },
+			{ `{"f": fn(x) { x }}.f(1)`, `({"f": fn(x) { x; }}.f)(1)`, -1 },
+		}
+
+		for i, tt := range tests {
+			lex := lexer.New(tt.input)
+			parser := New(lex)
+
+			program := parser.ParseProgram()
+			checkParserErrors(t, parser)
+
+			stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+			if stmt.Expression.String() != tt.expected {
+				t.Fatalf("[test #%d]: Expecting %q, but got %q\n", i, tt.expected, stmt.Expression.String())
+			}
+
+			if tt.pairs < 0 {
+				continue
+			}
+			hash, ok := stmt.Expression.(*ast.HashLiteral)
+
+			if !ok {
+				t.Fatalf("[test #%d]: Expecting expression to be of type *ast.HashLiteral, but got %T\n", i, stmt.Expression)
+			}
+
+			if len(hash.Keys) != tt.pairs || len(hash.Values) != tt.pairs {
+				t.Fatalf("[test #%d]: Expecting hash to have %d pairs, but got %d\n", i, tt.pairs, len(hash.Keys))
+			}
+		}
+	})
+
+	t.Run("it should report malformed hash literals", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	string
+		}{
+			{ `{"a" 1}`, "Expected next token to be ':', but got 'integer' instead." },
+			{ `{"a": 1 "b": 2}`, "Expected next token to be ',', but got 'string' instead." },
+			{ `{"a": 1`, "Expected next token to be ',', but got 'eof' instead." },
+		}
+
+		for i, tt := range tests {
+			lex := lexer.New(tt.input)
+			parser := New(lex)
+
+			parser.ParseProgram()
+
+			if len(parser.Errors()) == 0 {
+				t.Fatalf("[test #%d]: Expecting parser errors for %q, but got none\n", i, tt.input)
+			}
+
+			if parser.Errors()[0] != tt.expected {
+				t.Fatalf(
+					"[test #%d]: Expecting error %q, but got %q\n",
+					i, tt.expected, parser.Errors()[0],
+				)
+			}
+		}
+	})
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fn(x, y) { x + y}`
+	lex := lexer.New(input)
+	parser := New(lex)
+
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"Expecting program.Statements to contains 1 Statement, but got %d\n",
+			len(program.Statements),
+		)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+
+	if !ok {
+		t.Fatalf(
+			"Expecting program.Statements[0] to be of type *ast.ExpressionStatement, but got %T\n",
+			stmt,
+		)
+	}
+
+	funcLiteral, ok := stmt.Expression.(*ast.FunctionLiteral)
+
+	if !ok {
+		t.Fatalf(
+			"Expecting stmt.Expression to be of type *ast.FunctionLiteral, but got %T\n",
+			funcLiteral,
+		)
+	}
+
+	if len(funcLiteral.Params) != 2 {
+		t.Fatalf(
+			"Expecting funcLiteral.Params to contains 2 Params but got %d\n",
+			len(funcLiteral.Params),
+		)
+	}
+
+	if !testLiteral(t, funcLiteral.Params[0].Name, "x") || !testLiteral(t, funcLiteral.Params[1].Name, "y") {
+		return
+	}
+
+	bodyStmt, ok := funcLiteral.Body.Statements[0].(*ast.ExpressionStatement)
+
+	if !ok {
+		t.Fatalf(
+			"Expecting funcLiteral.Body.Statements[0] to be of type *ast.ExpressionStatement, but got %T\n",
+			bodyStmt,
+		)
+	}
+
+	testInfix(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestFunctionParamsParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+	}{
+		{input: "fn() {}", expectedParams: []string{}},
+		{input: "fn(x) {}", expectedParams: []string{"x"}},
+		{input: "fn(x, y, z) {}", expectedParams: []string{"x", "y", "z"}},
+	}
+
+	for i, tt := range tests {
+		lex := lexer.New(tt.input)
+		parser := New(lex)
+
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Params) != len(tt.expectedParams) {
+			t.Fatalf(
+				"[test #%d]Expecting function.Params to contains %d Params, but got %d\n",
+				i, len(tt.expectedParams), len(function.Params),
+			)
+		}
+
+		for i, param := range tt.expectedParams {
+			testLiteral(t, function.Params[i].Name, param)
+		}
+	}
+}
+
+func TestFunctionParamsWithDefaultAndRestParsing(t *testing.T) {
+
+	t.Run("it should parse default values and rest parameters", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"fn(x, y = 10) {}", "fn(x, y = 10) { }"},
+			{"fn(x = 1 + 2, y = x) {}", "fn(x = (1 + 2), y = x) { }"},
+			{"fn(first, ...rest) {}", "fn(first, ...rest) { }"},
+			{"fn(x, y = 2, ...rest) { x }", "fn(x, y = 2, ...rest) { x; }"},
+			{"fn(...args) {}", "fn(...args) { }"},
+		}
+
+		for i, tt := range tests {
+			lex := lexer.New(tt.input)
+			parser := New(lex)
+
+			program := parser.ParseProgram()
+			checkParserErrors(t, parser)
+
+			stmt := program.Statements[0].(*ast.ExpressionStatement)
+			function := stmt.Expression.(*ast.FunctionLiteral)
+
+			if function.String() != tt.expected {
+				t.Fatalf(
+					"[test #%d]: Expecting function.String() to return %q, but got %q\n",
+					i, tt.expected, function.String(),
+				)
+			}
+		}
+
+		lex := lexer.New("fn(x, y = 10, ...z) {}")
+		parser := New(lex)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+		if function.Params[0].Default != nil || function.Params[0].Rest {
+			t.Fatal("Expecting x to be a plain parameter")
+		}
+
+		if !testIntegerLiteral(t, function.Params[1].Default, 10) {
+			return
+		}
+
+		if !function.Params[2].Rest || function.Params[2].Name.Value != "z" {
+			t.Fatal("Expecting z to be a rest parameter")
+		}
+	})
+
+	t.Run("it should reject invalid parameters", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"fn(1, 2) {}", "Invalid parameter: expected an identifier, but got \"1\""},
+			{"fn(x, \"y\") {}", "Invalid parameter: expected an identifier, but got \"y\""},
+			{"fn(x, ...) {}", "Invalid parameter: expected an identifier, but got \")\""},
+			{"fn(x, x) {}", "Duplicate parameter name \"x\""},
+			{"fn(...rest, x) {}", "Rest parameter \"rest\" must be the last parameter"},
+			{"fn(...rest = []) {}", "Rest parameter \"rest\" cannot have a default value"},
+			{"fn(x = 1, y) {}", "Parameter \"y\" without default value follows a parameter with one"},
+		}
+
+		for i, tt := range tests {
+			lex := lexer.New(tt.input)
+			parser := New(lex)
+
+			parser.ParseProgram()
+
+			if len(parser.Errors()) == 0 {
+				t.Fatalf("[test #%d]: Expecting parser errors for %q, but got none\n", i, tt.input)
+			}
+
+			if parser.Errors()[0] != tt.expected {
+				t.Fatalf(
+					"[test #%d]: Expecting error %q, but got %q\n",
+					i, tt.expected, parser.Errors()[0],
+				)
+			}
+		}
+	})
+}
+
+func TestTypeAnnotationParsing(t *testing.T) {
+
+	t.Run("it should parse annotated declarations and functions", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"let x: int = 5;", "let x: int = 5;"},
+			{"const name: string = \"monkey\"", "const name: string = \"monkey\";"},
+			{"let xs: array<float> = []", "let xs: array<float> = [];"},
+			{"let m: map<string, array<int>> = {}", "let m: map<string, array<int>> = {};"},
+			{"let m: map<string, array<array<int>>>= {}", "let m: map<string, array<array<int>>> = {};"},
+			{"let f: fn(int, ...array<int>): bool = g", "let f: fn(int, ...array<int>): bool = g;"},
+			{"let f: fn() = g", "let f: fn() = g;"},
+			{"let f: fn(fn(any): int): fn(): int = g", "let f: fn(fn(any): int): fn(): int = g;"},
+			{"fn(a: int, b: float = 1.5) {}", "fn(a: int, b: float = 1.5) { }"},
+			{"fn(a, ...rest: array<string>): bool { true }", "fn(a, ...rest: array<string>): bool { true; }"},
+			{"fn add(a: int, b: int): int { a + b }", "fn add(a: int, b: int): int { (a + b); }"},
+			{"fn(): fn(int): int { g }", "fn(): fn(int): int { g; }"},
+		}
+
+		for i, tt := range tests {
+			parser := New(lexer.New(tt.input))
+
+			program := parser.ParseProgram()
+			checkParserErrors(t, parser)
+
+			if program.String() != tt.expected {
+				t.Fatalf("[test #%d]: Expecting %q, but got %q\n", i, tt.expected, program.String())
+			}
+		}
+	})
+
+	t.Run("it should keep annotations apart from the expression syntax", func(t *testing.T) {
+		parser := New(lexer.New("let a: array<int> = [1 >> 2, 3 >= 4]; f(x: 1); { \"f\": fn(x: int): int { x } }"))
+
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		expected := "let a: array<int> = [(1 >> 2), (3 >= 4)];f(x: 1){\"f\": fn(x: int): int { x; }}"
+
+		if program.String() != expected {
+			t.Fatalf("Expecting %q, but got %q\n", expected, program.String())
+		}
+
+		declaration := program.Statements[0].(*ast.DeclarationStatement)
+		array, ok := declaration.Type.(*ast.ArrayType)
+
+		if !ok {
+			t.Fatalf("Expecting an *ast.ArrayType, but got %T\n", declaration.Type)
+		}
+
+		if named, ok := array.Element.(*ast.NamedType); !ok || named.Name != "int" {
+			t.Fatalf("Expecting the element type to be int, but got %v\n", array.Element)
+		}
+	})
+
+	t.Run("it should reject invalid types", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"let x: integer = 5", "Unknown type \"integer\""},
+			{"let x: array = []", "Expected next token to be '<', but got '=' instead."},
+			{"let x: map<string> = {}", "Expected next token to be ',', but got '>' instead."},
+			{"let x: array<int = []", "Expected next token to be '>', but got '=' instead."},
+			{"let f: fn(...array<int>, int) = g", "Rest parameter must be the last parameter of a function type"},
+			{"fn(x: 1) {}", "Unknown type \"1\""},
+			{"fn(x): {}", "Unknown type \"{\""},
+		}
+
+		for i, tt := range tests {
+			parser := New(lexer.New(tt.input))
+			parser.ParseProgram()
+
+			if len(parser.Errors()) == 0 {
+				t.Fatalf("[test #%d]: Expecting parser errors for %q, but got none\n", i, tt.input)
+			}
+
+			if parser.Errors()[0] != tt.expected {
+				t.Fatalf("[test #%d]: Expecting error %q, but got %q\n", i, tt.expected, parser.Errors()[0])
+			}
+		}
+	})
+}
+
+func TestFunctionDeclarationParsing(t *testing.T) {
+	input := `fn add(x, y = 1) { x + y }; fn(x) { x };`
+	lex := lexer.New(input)
+	parser := New(lex)
+
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf(
+			"Expecting program.Statements to contains 2 Statements, but got %d\n",
+			len(program.Statements),
+		)
+	}
+
+	declaration, ok := program.Statements[0].(*ast.FunctionDeclaration)
+
+	if !ok {
+		t.Fatalf(
+			"Expecting program.Statements[0] to be of type *ast.FunctionDeclaration, but got %T\n",
+			program.Statements[0],
+		)
+	}
+
+	if !testIdentifier(t, declaration.Name, "add") {
+		return
+	}
+
+	if declaration.Function.Name != "add" || len(declaration.Function.Params) != 2 {
+		t.Fatalf(
+			"Expecting the declared function to be named %q with 2 Params, but got %q with %d\n",
+			"add", declaration.Function.Name, len(declaration.Function.Params),
+		)
+	}
+
+	if declaration.String() != "fn add(x, y = 1) { (x + y); }" {
+		t.Fatalf("Unexpected declaration.String(): %q\n", declaration.String())
+	}
+
+	// Without a name, `fn` is still a function literal expression.
+	stmt, ok := program.Statements[1].(*ast.ExpressionStatement)
+
+	if !ok {
+		t.Fatalf(
+			"Expecting program.Statements[1] to be of type *ast.ExpressionStatement, but got %T\n",
+			program.Statements[1],
+		)
+	}
+
+	if literal, ok := stmt.Expression.(*ast.FunctionLiteral); !ok || literal.Name != "" {
+		t.Fatalf("Expecting an anonymous *ast.FunctionLiteral, but got %T\n", stmt.Expression)
+	}
+}
+
+func TestFunctionCallParsing(t *testing.T) {
+	input := `add(1, 2 * 3, 4 + 5);`
+	lex := lexer.New(input)
+	parser := New(lex)
+
+	program := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"Expecting program.Statements to contains 1 Statement, but got %d\n",
+			len(program.Statements),
+		)
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+
+	if !ok {
+		t.Fatalf(
+			"Expecting program.Statements[0] to be of type *ast.ExpressionStatement, but got %T\n",
+			stmt,
+		)
+	}
+
+	fnCallExpr, ok := stmt.Expression.(*ast.FunctionCallExpression)
+
+	if !ok {
+		t.Fatalf(
+			"Expecting stmt.Expression to be of type *ast.FunctionCallExpression, but got %T\n",
+			fnCallExpr,
+		)
+	}
+
+	if !testIdentifier(t, fnCallExpr.Function, "add") {
+		return
+	}
+
+	if len(fnCallExpr.Arguments) != 3 {
+		t.Fatalf(
+			"Expecting fnCallExpr.Arguments to contains 3 Arguments, but got %d\n",
+			len(fnCallExpr.Arguments),
+		)
+	}
+
+	if !testLiteral(t, fnCallExpr.Arguments[0], 1) ||
+		!testInfix(t, fnCallExpr.Arguments[1], 2, "*", 3) {
+		return
+	}
+	testInfix(t, fnCallExpr.Arguments[2], 4, "+", 5)
+}
+
+
+func TestFunctionCallArgumentsParsing(t *testing.T) {
+
+	t.Run("it should parse spread and named arguments", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"f(...args)", "f(...args)"},
+			{"f(1, ...[2, 3], x)", "f(1, ...[2, 3], x)"},
+			{"f(y: 2)", "f(y: 2)"},
+			{"f(1, y: 2 * 3, z: a)", "f(1, y: (2 * 3), z: a)"},
+			{"f(a[1:2])", "f((a[1:2]))"},
+		}
+
+		for i, tt := range tests {
+			lex := lexer.New(tt.input)
+			parser := New(lex)
+
+			program := parser.ParseProgram()
+			checkParserErrors(t, parser)
+
+			if program.String() != tt.expected {
+				t.Fatalf(
+					"[test #%d]: Expecting program.String() to return %q, but got %q\n",
+					i, tt.expected, program.String(),
+				)
+			}
+		}
+
+		lex := lexer.New("f(...xs, y: 1)")
+		parser := New(lex)
+		program := parser.ParseProgram()
+		checkParserErrors(t, parser)
+
+		call := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionCallExpression)
+
+		spread, ok := call.Arguments[0].(*ast.SpreadExpression)
+
+		if !ok || !testIdentifier(t, spread.Value, "xs") {
+			t.Fatalf("Expecting first argument to spread xs, but got %T\n", call.Arguments[0])
+		}
+
+		named, ok := call.Arguments[1].(*ast.NamedArgument)
+
+		if !ok || !testIdentifier(t, named.Name, "y") || !testIntegerLiteral(t, named.Value, 1) {
+			t.Fatalf("Expecting second argument to be y: 1, but got %T\n", call.Arguments[1])
+		}
+	})
+
+	t.Run("it should reject invalid arguments", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"f(y: 1, 2)", "Positional argument \"2\" follows named arguments"},
+			{"f(y: 1, ...a)", "Positional argument \"...a\" follows named arguments"},
+			{"f(y: 1, y: 2)", "Duplicate named argument \"y\""},
+		}
+
+		for i, tt := range tests {
+			lex := lexer.New(tt.input)
+			parser := New(lex)
+
+			parser.ParseProgram()
+
+			if len(parser.Errors()) == 0 {
+				t.Fatalf("[test #%d]: Expecting parser errors for %q, but got none\n", i, tt.input)
+			}
+
+			if parser.Errors()[0] != tt.expected {
+				t.Fatalf(
+					"[test #%d]: Expecting error %q, but got %q\n",
+					i, tt.expected, parser.Errors()[0],
+				)
+			}
+		}
+	})
+}
+
+
+// Helpers functions next:
+
+
+func BenchmarkParseProgram(b *testing.B) {
+	const DEPTH = 500
+
+	sources := []struct{
+		name	string
+		source	string
+	}{
+		{ "corpus", corpus.Source(1 << 18) },
+		{ "nested arithmetic", strings.Repeat("(1 + ", DEPTH) + "1" + strings.Repeat(") * 2", DEPTH) },
+		{ "nested prefixes", strings.Repeat("-!~", DEPTH) + "x" },
+		{ "nested calls", strings.Repeat("f(a, ", DEPTH) + "x" + strings.Repeat(")", DEPTH) },
+		{ "nested arrays", strings.Repeat("[1, ", DEPTH) + "[]" + strings.Repeat("]", DEPTH) },
+		{ "nested functions", strings.Repeat("fn(x) { ", DEPTH) + "x" + strings.Repeat(" }", DEPTH) },
+		{ "nested conditions", strings.Repeat("if (x) { ", DEPTH) + "x" + strings.Repeat(" } else { y }", DEPTH) },
+	}
+
+	for _, tt := range sources {
+		b.Run(tt.name, func(b *testing.B) {
+			parser := New(lexer.New(tt.source))
+			parser.ParseProgram()
+
+			if len(parser.Errors()) != 0 {
+				b.Fatalf("Expecting no parser errors, but got %v\n", parser.Errors()[0])
+			}
+
+			b.SetBytes(int64(len(tt.source)))
+			b.ReportAllocs()
+
+			for b.Loop() {
+				New(lexer.New(tt.source)).ParseProgram()
+			}
+		})
+	}
+}
 
 
 func checkParserErrors(t *testing.T, parser *Parser) {
@@ -0,0 +1,340 @@
+package resolver
+
+import (
+	"monkey/internal/ast"
+	"monkey/internal/diagnostic"
+	"monkey/internal/token"
+)
+
+
+// Resolve bind every identifier of program to the environment holding
+// its value, so the evaluator can find it without looking its name up:
+//
+//   - a name declared in a block, a function or a loop is LOCAL, in
+//     a slot of the environment of that block, listed in its Scope;
+//   - a name declared at the top level of the program, or not declared
+//     at all, is GLOBAL, looked up by name in the environment of the
+//     program. globals are the names that environment already bind.
+//
+// It return, in the order of the source, an error for each name used
+// before its declaration in the same function, or never declared, and a
+// warning for each declaration hiding another one of an enclosing scope.
+//
+// A program must be resolved after it's optimized, as the optimizer
+// replace blocks.
+func Resolve(program *ast.Program, globals ...string) []diagnostic.Diagnostic {
+	r := &resolver{ globals: map[string]bool{} }
+
+	for _, name := range globals {
+		r.globals[name] = true
+	}
+
+	r.scope = &scope{ global: true, declared: map[string]*declaration{} }
+	r.declareStatements(program.Statements)
+	r.resolveStatements(program.Statements)
+
+	diagnostic.Sort(r.diagnostics)
+
+	return r.diagnostics
+}
+
+type resolver struct {
+	scope			*scope
+	globals			map[string]bool
+	diagnostics		[]diagnostic.Diagnostic
+}
+
+// scope is the environment a block is evaluated in.
+type scope struct {
+	names		[]string
+	declared	map[string]*declaration
+	global		bool // the top level of the program, whose names have no slot
+	function	bool // the body of a function, called after the outer scopes are done
+	outer		*scope
+}
+
+type declaration struct {
+	slot		int
+	token		token.Token
+	done		bool // bound when it's used from the same function
+}
+
+// declare add the name of ident to the current scope, unless it's already
+// there, and bind ident to it. A declaration done from the start of the
+// scope is bound as soon as the scope is entered, like a parameter.
+func (r *resolver) declare(ident *ast.Identifier, done bool) *declaration {
+	decl, ok := r.scope.declared[ident.Value]
+
+	if !ok {
+		r.checkShadowing(ident)
+
+		decl = &declaration{ slot: len(r.scope.names), token: ident.Token }
+		r.scope.names = append(r.scope.names, ident.Value)
+		r.scope.declared[ident.Value] = decl
+	}
+	decl.done = decl.done || done
+
+	if r.scope.global {
+		ident.Binding = ast.Binding{ Kind: ast.GLOBAL }
+	} else {
+		ident.Binding = ast.Binding{ Kind: ast.LOCAL, Slot: decl.slot }
+	}
+
+	return decl
+}
+
+func (r *resolver) checkShadowing(ident *ast.Identifier) {
+	if r.scope.global {
+		return
+	}
+
+	for outer := r.scope.outer; outer != nil; outer = outer.outer {
+		if decl, ok := outer.declared[ident.Value]; ok {
+			r.warn(ident.Token, "%s shadows the declaration at line %d, column %d", ident.Value, decl.token.Line, decl.token.Column)
+			return
+		}
+	}
+
+	if r.globals[ident.Value] {
+		r.warn(ident.Token, "%s shadows a global", ident.Value)
+	}
+}
+
+// declareStatements declare the names bound by a list of statements in
+// the current scope, before resolving any of them, as the statements may
+// refer to a name of the scope before it's declared. Function declarations
+// are hoisted, so they are done from the start.
+func (r *resolver) declareStatements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		if export, ok := stmt.(*ast.ExportStatement); ok {
+			stmt = export.Statement
+		}
+
+		switch stmt := stmt.(type) {
+
+		case *ast.DeclarationStatement:
+			r.declare(stmt.Name, false)
+
+		case *ast.FunctionDeclaration:
+			r.declare(stmt.Name, true)
+
+		case *ast.ImportStatement:
+			r.declare(stmt.Alias, false)
+		}
+	}
+}
+
+// resolveIdentifier bind ident to the innermost scope declaring its name.
+func (r *resolver) resolveIdentifier(ident *ast.Identifier) {
+	depth := 0
+	sameFunction := true
+
+	for s := r.scope; s != nil; s = s.outer {
+		if decl, ok := s.declared[ident.Value]; ok {
+			if sameFunction && !decl.done {
+				r.error(ident.Token, "%s used before its declaration", ident.Value)
+			}
+
+			if s.global {
+				ident.Binding = ast.Binding{ Kind: ast.GLOBAL, Depth: depth }
+			} else {
+				ident.Binding = ast.Binding{ Kind: ast.LOCAL, Depth: depth, Slot: decl.slot }
+			}
+			return
+		}
+
+		if s.global {
+			break
+		}
+
+		if s.function {
+			sameFunction = false
+		}
+		depth++
+	}
+
+	if !r.globals[ident.Value] {
+		r.error(ident.Token, "undefined name: %s", ident.Value)
+	}
+	ident.Binding = ast.Binding{ Kind: ast.GLOBAL, Depth: depth }
+}
+
+// resolveBlock resolve a block evaluated in its own environment, where
+// bindings are first bound, then set its Scope.
+func (r *resolver) resolveBlock(block *ast.BlockStatement, bindings ...*ast.Identifier) {
+	if block == nil {
+		return
+	}
+	r.enter(false)
+
+	for _, ident := range bindings {
+		if ident != nil {
+			r.declare(ident, true)
+		}
+	}
+	r.declareStatements(block.Statements)
+	r.resolveStatements(block.Statements)
+
+	block.Scope = r.leave()
+}
+
+func (r *resolver) resolveFunction(fn *ast.FunctionLiteral) {
+	r.enter(true)
+
+	params := make([]*declaration, len(fn.Params))
+
+	for i, param := range fn.Params {
+		params[i] = r.declare(param.Name, false)
+	}
+	r.declareStatements(fn.Body.Statements)
+
+	// Default values are evaluated in the environment of the call,
+	// where the parameters before theirs are already bound.
+	for i, param := range fn.Params {
+		r.resolveExpression(param.Default)
+		params[i].done = true
+	}
+	r.resolveStatements(fn.Body.Statements)
+
+	fn.Body.Scope = r.leave()
+}
+
+func (r *resolver) enter(function bool) {
+	r.scope = &scope{
+		declared: map[string]*declaration{},
+		function: function,
+		outer: r.scope,
+	}
+}
+
+func (r *resolver) leave() *ast.Scope {
+	names := r.scope.names
+	r.scope = r.scope.outer
+
+	if names == nil {
+		names = []string{}
+	}
+
+	return &ast.Scope{ Names: names }
+}
+
+func (r *resolver) resolveStatements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		r.resolveStatement(stmt)
+	}
+}
+
+func (r *resolver) resolveStatement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+
+	case *ast.ExpressionStatement:
+		r.resolveExpression(stmt.Expression)
+
+	case *ast.DeclarationStatement:
+		r.resolveExpression(stmt.Value)
+		r.declare(stmt.Name, true)
+
+	case *ast.ReturnStatement:
+		r.resolveExpression(stmt.ReturnValue)
+
+	case *ast.BlockStatement:
+		r.resolveStatements(stmt.Statements)
+
+	case *ast.FunctionDeclaration:
+		r.resolveFunction(stmt.Function)
+
+	case *ast.ImportStatement:
+		r.declare(stmt.Alias, true)
+
+	case *ast.ExportStatement:
+		r.resolveStatement(stmt.Statement)
+
+	case *ast.ThrowStatement:
+		r.resolveExpression(stmt.Value)
+
+	case *ast.TryStatement:
+		r.resolveBlock(stmt.Block)
+		r.resolveBlock(stmt.Catch, stmt.CatchParam)
+		r.resolveBlock(stmt.Finally)
+
+	case *ast.ForInStatement:
+		r.resolveExpression(stmt.Iterable)
+		r.resolveBlock(stmt.Body, stmt.Variable)
+	}
+}
+
+func (r *resolver) resolveExpressions(expressions []ast.Expression) {
+	for _, expr := range expressions {
+		r.resolveExpression(expr)
+	}
+}
+
+func (r *resolver) resolveExpression(expr ast.Expression) {
+	switch expr := expr.(type) {
+
+	case *ast.Identifier:
+		r.resolveIdentifier(expr)
+
+	case *ast.PrefixExpression:
+		r.resolveExpression(expr.Right)
+
+	case *ast.InfixExpression:
+		r.resolveExpression(expr.Left)
+		r.resolveExpression(expr.Right)
+
+	case *ast.PostfixExpression:
+		r.resolveExpression(expr.Left)
+
+	case *ast.IfElseExpression:
+		r.resolveExpression(expr.Condition)
+		r.resolveBlock(expr.Consequence)
+		r.resolveBlock(expr.Alternative)
+
+	case *ast.ArrayLiteral:
+		r.resolveExpressions(expr.Elements)
+
+	case *ast.HashLiteral:
+		for i, key := range expr.Keys {
+			r.resolveExpression(key)
+			r.resolveExpression(expr.Values[i])
+		}
+
+	case *ast.FunctionLiteral:
+		r.resolveFunction(expr)
+
+	case *ast.FunctionCallExpression:
+		r.resolveExpression(expr.Function)
+		r.resolveExpressions(expr.Arguments)
+
+	case *ast.SpreadExpression:
+		r.resolveExpression(expr.Value)
+
+	case *ast.NamedArgument:
+		r.resolveExpression(expr.Value)
+
+	case *ast.IndexExpression:
+		r.resolveExpression(expr.Left)
+		r.resolveExpression(expr.Index)
+
+	case *ast.MemberExpression:
+		r.resolveExpression(expr.Object)
+
+	case *ast.SliceExpression:
+		r.resolveExpression(expr.Left)
+		r.resolveExpression(expr.Start)
+		r.resolveExpression(expr.Stop)
+		r.resolveExpression(expr.Step)
+
+	case *ast.RangeExpression:
+		r.resolveExpression(expr.Start)
+		r.resolveExpression(expr.End)
+	}
+}
+
+func (r *resolver) error(tok token.Token, format string, args ...any) {
+	r.diagnostics = append(r.diagnostics, diagnostic.Errorf(tok, format, args...))
+}
+
+func (r *resolver) warn(tok token.Token, format string, args ...any) {
+	r.diagnostics = append(r.diagnostics, diagnostic.Warningf(tok, format, args...))
+}
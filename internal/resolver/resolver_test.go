@@ -0,0 +1,178 @@
+package resolver
+
+import (
+	"fmt"
+	"monkey/internal/ast"
+	"monkey/internal/lexer"
+	"monkey/internal/parser"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+
+func TestResolve(t *testing.T) {
+
+	t.Run("it should bind identifiers to the scope declaring them", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			expected	[]string
+		}{
+			{
+				"let a = 1; fn f(b) { let c = b; if (c) { a + b + c } }",
+				[]string{ "a global 0", "f global 0", "b local 0.0", "c local 0.1", "b local 0.0", "c local 0.1", "a global 2", "b local 1.0", "c local 1.1" },
+			},
+			{
+				"for (i in [1]) { try { i } catch (e) { e } finally { i } }",
+				[]string{ "i local 0.0", "i local 1.0", "e local 0.0", "e local 0.0", "i local 1.0" },
+			},
+			{
+				"fn f(a, b = a) { fn() { let a = b; a } }",
+				[]string{ "f global 0", "a local 0.0", "b local 0.1", "a local 0.0", "a local 0.0", "b local 1.1", "a local 0.0" },
+			},
+			{
+				"fn f(x) { x.y + g(x: x) + missing }",
+				[]string{ "f global 0", "x local 0.0", "x local 0.0", "y unresolved", "g global 1", "x unresolved", "x local 0.0", "missing global 1" },
+			},
+			{
+				"let a = 1; let a = a + 1; if (a) { a++ }",
+				[]string{ "a global 0", "a global 0", "a global 0", "a global 0", "a global 1" },
+			},
+		}
+
+		for i, tt := range tests {
+			program := parse(t, tt.input)
+			Resolve(program)
+
+			got := []string{}
+
+			for _, ident := range identifiers(program) {
+				got = append(got, formatBinding(ident))
+			}
+
+			if !slices.Equal(got, tt.expected) {
+				t.Fatalf("[test #%d]: Expecting %q, but got %q\n", i, tt.expected, got)
+			}
+		}
+	})
+
+	t.Run("it should list the names of each scope in slot order", func(t *testing.T) {
+		program := parse(t, "fn f(a, b) { let c = 1; fn d() { } let c = 2; if (c) { import \"m.mk\" as m; } }")
+		Resolve(program)
+
+		body := program.Statements[0].(*ast.FunctionDeclaration).Function.Body
+		consequence := body.Statements[3].(*ast.ExpressionStatement).Expression.(*ast.IfElseExpression).Consequence
+
+		if !slices.Equal(body.Scope.Names, []string{ "a", "b", "c", "d" }) {
+			t.Fatalf("Expecting the function scope to be [a b c d], but got %v\n", body.Scope.Names)
+		}
+
+		if !slices.Equal(consequence.Scope.Names, []string{ "m" }) {
+			t.Fatalf("Expecting the block scope to be [m], but got %v\n", consequence.Scope.Names)
+		}
+
+		if got := program.Statements[0].(*ast.FunctionDeclaration).Function.Body.Statements[1].(*ast.FunctionDeclaration).Function.Body.Scope; got == nil || len(got.Names) != 0 {
+			t.Fatalf("Expecting an empty scope for an empty function, but got %v\n", got)
+		}
+	})
+
+	t.Run("it should report errors and warnings in source order", func(t *testing.T) {
+		tests := []struct{
+			input		string
+			globals		[]string
+			expected	[]string
+		}{
+			{ "x", nil, []string{ "<input>:1:1: error: undefined name: x" } },
+			{ "let y = x; let x = 1;", nil, []string{ "<input>:1:9: error: x used before its declaration" } },
+			{ "let f = f;", nil, []string{ "<input>:1:9: error: f used before its declaration" } },
+			{ "let c = 1; if (c) { let a = c; let c = 2; a }", nil, []string{ "<input>:1:29: error: c used before its declaration", "<input>:1:36: warning: c shadows the declaration at line 1, column 5" } },
+			{ "fn f(a, b = a, c = d, d = 1) { }", nil, []string{ "<input>:1:20: error: d used before its declaration" } },
+			{ "try { 1 } catch (e) { e } e", nil, []string{ "<input>:1:27: error: undefined name: e" } },
+			{ "let x = 1; fn f(x) { x }", nil, []string{ "<input>:1:17: warning: x shadows the declaration at line 1, column 5" } },
+			{ "fn f() { let z = 1; fn() { let z = 2; y } }", nil, []string{ "<input>:1:32: warning: z shadows the declaration at line 1, column 14", "<input>:1:39: error: undefined name: y" } },
+			{ "fn f(puts) { puts }", []string{ "puts" }, []string{ "<input>:1:6: warning: puts shadows a global" } },
+			{ "puts(1)", []string{ "puts" }, []string{} },
+			{ "fn f() { g() } fn g() { 1 }", nil, []string{} },
+			{ "fn f() { x } let x = 1;", nil, []string{} },
+			{ "let f = fn() { f() };", nil, []string{} },
+			{ "let x = 1; let x = x + 2; x", nil, []string{} },
+			{ "fn f(value) { value } f(value: 1)", nil, []string{} },
+			{ "import \"m.mk\" as m; m.value", nil, []string{} },
+			{ "for (i in 0..3) { let j = i; fn() { i + j } }", nil, []string{} },
+		}
+
+		for i, tt := range tests {
+			got := []string{}
+
+			for _, diagnostic := range Resolve(parse(t, tt.input), tt.globals...) {
+				got = append(got, diagnostic.String())
+			}
+
+			if !slices.Equal(got, tt.expected) {
+				t.Fatalf("[test #%d] %s\nExpecting %q, but got %q\n", i, tt.input, tt.expected, got)
+			}
+		}
+	})
+}
+
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Expecting no parser errors, but got %v\n", p.Errors())
+	}
+
+	return program
+}
+
+// identifiers return the identifiers of node, in the order
+// of the fields holding them, which is the source order.
+func identifiers(node any) []*ast.Identifier {
+	found := []*ast.Identifier{}
+
+	var walk func(value reflect.Value)
+
+	walk = func(value reflect.Value) {
+		switch value.Kind() {
+
+		case reflect.Pointer, reflect.Interface:
+			if value.IsNil() {
+				return
+			}
+
+			if ident, ok := value.Interface().(*ast.Identifier); ok {
+				found = append(found, ident)
+				return
+			}
+			walk(value.Elem())
+
+		case reflect.Struct:
+			for i := 0; i < value.NumField(); i++ {
+				walk(value.Field(i))
+			}
+
+		case reflect.Slice:
+			for i := 0; i < value.Len(); i++ {
+				walk(value.Index(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(node))
+
+	return found
+}
+
+func formatBinding(ident *ast.Identifier) string {
+	switch ident.Binding.Kind {
+
+	case ast.LOCAL:
+		return fmt.Sprintf("%s local %d.%d", ident.Value, ident.Binding.Depth, ident.Binding.Slot)
+
+	case ast.GLOBAL:
+		return fmt.Sprintf("%s global %d", ident.Value, ident.Binding.Depth)
+	}
+
+	return ident.Value + " unresolved"
+}
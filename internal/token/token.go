@@ -16,6 +16,7 @@ const (
 	IDENTIFIER
 	INTEGER
 	FLOAT
+	STRING
 
 	// Operators
 	ASSIGN
@@ -24,6 +25,18 @@ const (
 	ASTERISK
 	SLASH
 	MODULO
+	POWER
+
+	AMPERSAND // &
+	PIPE      // |
+	CARET     // ^
+	TILDE     // ~
+
+	LEFT_SHIFT  // <<
+	RIGHT_SHIFT // >>
+
+	INCREMENT // ++
+	DECREMENT // --
 
 	BANG
 	LESSER_THAN
@@ -34,9 +47,15 @@ const (
 
 	LESSER_OR_EQUAL_TO
 	GREATER_OR_EQUAL_TO
+
+	DOT_DOT       // ..
+	DOT_DOT_EQUAL // ..=
+	ELLIPSIS      // ...
 	// Delimiters
 	COMMA
 	SEMICOLON
+	COLON
+	DOT
 
 	LPAREN   // (
 	RPAREN   // )
@@ -54,6 +73,15 @@ const (
 	IF
 	ELSE
 	RETURN
+	FOR
+	IN
+	THROW
+	TRY
+	CATCH
+	FINALLY
+	IMPORT
+	EXPORT
+	AS
 )
 
 var SPECIAL_CHARS = map[byte]TokenType{
@@ -64,16 +92,22 @@ var SPECIAL_CHARS = map[byte]TokenType{
 	'/': SLASH,
 	'%': MODULO,
 	'!': BANG,
+	'&': AMPERSAND,
+	'|': PIPE,
+	'^': CARET,
+	'~': TILDE,
 	'<': LESSER_THAN,
 	'>': GREATER_THAN,
 	',': COMMA,
 	';': SEMICOLON,
+	':': COLON,
 	'(': LPAREN,
 	')': RPAREN,
 	'{': LBRACE,
 	'}': RBRACE,
 	'[': LBRACKET,
 	']': RBRACKET,
+	'.': DOT,
 }
 
 var FLIPPED_SPECIAL_CHARS = helper.FlipMap(SPECIAL_CHARS)
@@ -86,11 +120,26 @@ var TWO_CHARS = map[string]TokenType{
 	"!=": NOT_EQUAL,
 	"<=": LESSER_OR_EQUAL_TO,
 	">=": GREATER_OR_EQUAL_TO,
+	"**": POWER,
+	"<<": LEFT_SHIFT,
+	">>": RIGHT_SHIFT,
+	"++": INCREMENT,
+	"--": DECREMENT,
+	"..": DOT_DOT,
 }
 
 var FLIPPED_TWO_CHARS = helper.FlipMap(TWO_CHARS)
 
 
+// THREE_CHARS hold the tokens that extend a two character token.
+var THREE_CHARS = map[string]TokenType{
+	"..=": DOT_DOT_EQUAL,
+	"...": ELLIPSIS,
+}
+
+var FLIPPED_THREE_CHARS = helper.FlipMap(THREE_CHARS)
+
+
 var KEYWORDS = map[string]TokenType{
 	"fn": FUNCTION,
 	"let": LET,
@@ -100,6 +149,15 @@ var KEYWORDS = map[string]TokenType{
 	"if": IF,
 	"else": ELSE,
 	"return": RETURN,
+	"for": FOR,
+	"in": IN,
+	"throw": THROW,
+	"try": TRY,
+	"catch": CATCH,
+	"finally": FINALLY,
+	"import": IMPORT,
+	"export": EXPORT,
+	"as": AS,
 }
 
 var FLIPPED_KEYWORDS = helper.FlipMap(KEYWORDS)
@@ -109,6 +167,7 @@ var OTHERS = map[string]TokenType{
 	"identifier": IDENTIFIER,
 	"integer": INTEGER,
 	"float": FLOAT,
+	"string": STRING,
 	"eof": EOF,
 	"illegal": ILLEGAL,
 }
@@ -119,6 +178,9 @@ var FLIPPED_OTHERS = helper.FlipMap(OTHERS)
 type Token struct {
 	Type    TokenType
 	Literal string
+	File    string // name of the source file, empty when the source isn't a file
+	Line    int    // line of the token first character, starting at 1
+	Column  int    // column of the token first character, starting at 1
 }
 
 // LookupWord serach for the type of a given word.
@@ -135,6 +197,7 @@ func GetLiteralByType(_type TokenType) string {
 
 	strValueMaps := []map[TokenType]string{
 		FLIPPED_TWO_CHARS,
+		FLIPPED_THREE_CHARS,
 		FLIPPED_KEYWORDS,
 		FLIPPED_OTHERS,
 	}
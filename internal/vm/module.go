@@ -0,0 +1,24 @@
+package vm
+
+import (
+	"monkey/internal/ast"
+	"monkey/internal/compiler"
+	"monkey/internal/object"
+)
+
+
+// importModule return the module at the imported path, compiling and
+// running it the first time it's imported only, like the evaluator.
+func (vm *VM) importModule(site object.StackFrame, path string) object.Object {
+	run := func(program *ast.Program, env *object.Environment) object.Object {
+		bytecode, err := compiler.Compile(program)
+
+		if err != nil {
+			return newError("cannot import %q: %s", path, err)
+		}
+
+		return vm.Run(bytecode, env)
+	}
+
+	return locateError(vm.modules.Import(path, site.File, vm.ModulePath, run), site)
+}
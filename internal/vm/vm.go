@@ -0,0 +1,1059 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/bits"
+	"monkey/internal/ast"
+	"monkey/internal/code"
+	"monkey/internal/compiler"
+	"monkey/internal/module"
+	"monkey/internal/object"
+	"slices"
+	"unicode/utf8"
+)
+
+
+// DEFAULT_MAX_CALL_DEPTH is the number of nested calls a program can
+// make before its execution fail with a stack overflow error, the same
+// as the evaluator.
+const DEFAULT_MAX_CALL_DEPTH = 10000
+
+
+// VM execute compiled programs with an operand stack. Names are found with
+// the binding the resolver gave them, in the slots of the environments for
+// the local ones, like the evaluator do, so a program give the same results
+// on both. A VM must not be used by several goroutines at once.
+//
+// Calls of Monkey functions are run on a stack of frames, each with its
+// own part of the operand stack, instead of recursing in Go. Tail calls
+// replace the frame of the current call, so tail recursion doesn't grow
+// the stack.
+type VM struct {
+	MaxCallDepth	int // maximum number of nested calls
+	ModulePath		[]string // directories where imported files are looked up
+
+	ctx				context.Context
+	stack			[]object.Object
+	frames			[]*frame
+	depth			int
+	modules			module.Loader
+}
+
+// frame is the execution of a function from ip in env, with the values
+// it pushed above base on the operand stack. It's a call when call is
+// set, made at site, or else a program, a block of a try statement or
+// a default value, run by exec directly.
+type frame struct {
+	fn			*object.CompiledFunction
+	ip			int
+	env			*object.Environment
+	base		int
+	call		bool
+	site		object.StackFrame
+}
+
+func New() *VM {
+	return &VM{
+		MaxCallDepth: DEFAULT_MAX_CALL_DEPTH,
+		ctx: context.Background(),
+	}
+}
+
+// Run run bytecode in env with a new VM using the default settings.
+func Run(bytecode *compiler.Bytecode, env *object.Environment) object.Object {
+	return New().Run(bytecode, env)
+}
+
+// RunContext run bytecode like Run, but stop with a BudgetExceededError
+// as soon as ctx is cancelled or its deadline is passed.
+func (vm *VM) RunContext(ctx context.Context, bytecode *compiler.Bytecode, env *object.Environment) object.Object {
+	previous := vm.ctx
+	vm.ctx = ctx
+	defer func() { vm.ctx = previous }()
+
+	return vm.Run(bytecode, env)
+}
+
+// Run run bytecode in env, returning the value of its last statement
+// or of its `return` statement, like the evaluator.
+//
+// Bytecode read from a file is validated, but the types of the values its
+// instructions are given are only known at run time. An instruction that
+// can't use them, or that pop more values than its frame pushed, stop the
+// program with an invalid bytecode error.
+func (vm *VM) Run(bytecode *compiler.Bytecode, env *object.Environment) object.Object {
+	result, _ := vm.exec(&frame{ fn: bytecode.Main, env: env, base: len(vm.stack) })
+
+	return result
+}
+
+
+// pops is the number of values each instruction pop from the stack,
+// for the ones popping a fixed number of them.
+var pops = [256]int{
+	code.OpPop: 1,
+	code.OpAdd: 2,
+	code.OpSub: 2,
+	code.OpMul: 2,
+	code.OpDiv: 2,
+	code.OpMod: 2,
+	code.OpPow: 2,
+	code.OpEqual: 2,
+	code.OpNotEqual: 2,
+	code.OpLess: 2,
+	code.OpGreater: 2,
+	code.OpLessOrEqual: 2,
+	code.OpGreaterOrEqual: 2,
+	code.OpBitAnd: 2,
+	code.OpBitOr: 2,
+	code.OpBitXor: 2,
+	code.OpShiftLeft: 2,
+	code.OpShiftRight: 2,
+	code.OpMinus: 1,
+	code.OpBang: 1,
+	code.OpBitNot: 1,
+	code.OpUpdate: 1,
+	code.OpDefine: 1,
+	code.OpDefineConst: 1,
+	code.OpBind: 1,
+	code.OpAssign: 1,
+	code.OpIndex: 2,
+	code.OpIndexForUpdate: 2,
+	code.OpSetIndex: 4,
+	code.OpRange: 2,
+	code.OpMember: 1,
+	code.OpJumpNotTruthy: 1,
+	code.OpNamedArgument: 1,
+	code.OpSpread: 1,
+	code.OpReturn: 1,
+	code.OpEndBlock: 1,
+	code.OpIter: 1,
+	code.OpIterNext: 1,
+	code.OpThrow: 1,
+}
+
+var operators = [...]string{
+	code.OpAdd: "+",
+	code.OpSub: "-",
+	code.OpMul: "*",
+	code.OpDiv: "/",
+	code.OpMod: "%",
+	code.OpPow: "**",
+	code.OpEqual: "==",
+	code.OpNotEqual: "!=",
+	code.OpLess: "<",
+	code.OpGreater: ">",
+	code.OpLessOrEqual: "<=",
+	code.OpGreaterOrEqual: ">=",
+	code.OpBitAnd: "&",
+	code.OpBitOr: "|",
+	code.OpBitXor: "^",
+	code.OpShiftLeft: "<<",
+	code.OpShiftRight: ">>",
+	code.OpMinus: "-",
+	code.OpBang: "!",
+	code.OpBitNot: "~",
+}
+
+// exec run f, and the calls made from it, until f return or its block
+// end, and return the value along with whether it's returned. Values
+// pushed on the stack above the base of f are dropped when it's done.
+// An error stop the execution and is returned as value, with the calls
+// it went through, f included, added to its stack.
+func (vm *VM) exec(f *frame) (object.Object, bool) {
+	bottom := len(vm.frames)
+	vm.frames = append(vm.frames, f)
+
+	fn, ins, constants, ip, env := f.fn, f.fn.Instructions, f.fn.Constants, f.ip, f.env
+
+	for {
+		start := ip
+		op := code.Opcode(ins[ip])
+		ip++
+
+		if vm.underflow(f, pops[op]) {
+			return vm.raise(fn, start, bottom, invalidBytecode("stack underflow at instruction %d", start))
+		}
+
+		switch op {
+
+		case code.OpConstant:
+			vm.push(constants[code.ReadUint16(ins[ip:])])
+			ip += 2
+
+		case code.OpNil:
+			vm.push(nil)
+
+		case code.OpNull:
+			vm.push(object.NULL)
+
+		case code.OpTrue:
+			vm.push(object.TRUE)
+
+		case code.OpFalse:
+			vm.push(object.FALSE)
+
+		case code.OpPop:
+			vm.pop()
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+			code.OpEqual, code.OpNotEqual, code.OpLess, code.OpGreater,
+			code.OpLessOrEqual, code.OpGreaterOrEqual,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShiftLeft, code.OpShiftRight:
+			right := vm.pop()
+			left := vm.pop()
+			result := object.Infix(operators[op], left, right)
+
+			if isError(result) {
+				return vm.raise(fn, start, bottom, result)
+			}
+			vm.push(result)
+
+		case code.OpMinus, code.OpBang, code.OpBitNot:
+			result := object.Prefix(operators[op], vm.pop())
+
+			if isError(result) {
+				return vm.raise(fn, start, bottom, result)
+			}
+			vm.push(result)
+
+		case code.OpUpdate:
+			operator := "++"
+
+			if ins[ip] == 1 {
+				operator = "--"
+			}
+			prefix := ins[ip + 1] == 1
+			ip += 2
+
+			old := vm.pop()
+			updated := object.Update(operator, old)
+
+			if isError(updated) {
+				return vm.raise(fn, start, bottom, updated)
+			}
+
+			if prefix {
+				vm.push(updated)
+			} else {
+				vm.push(old)
+			}
+			vm.push(updated)
+
+		case code.OpGetName:
+			name, binding := constantName(constants, ins[ip:]), readBinding(ins[ip + 2:])
+			ip += 7
+
+			value, ok := env.GetResolved(name, binding)
+
+			if !ok {
+				return vm.raise(fn, start, bottom, newError("identifier not found: %s", name))
+			}
+			vm.push(value)
+
+		case code.OpDefine, code.OpDefineConst:
+			name, binding := constantName(constants, ins[ip:]), readBinding(ins[ip + 2:])
+			ip += 7
+
+			if env.IsConstantResolved(name, binding) {
+				return vm.raise(fn, start, bottom, newError("cannot redeclare constant: %s", name))
+			}
+			env.SetResolved(name, binding, vm.pop(), op == code.OpDefineConst)
+
+		case code.OpBind:
+			env.SetResolved(constantName(constants, ins[ip:]), readBinding(ins[ip + 2:]), vm.pop(), false)
+			ip += 7
+
+		case code.OpCheckMutable:
+			name, binding := constantName(constants, ins[ip:]), readBinding(ins[ip + 2:])
+			ip += 7
+
+			if env.IsConstantResolved(name, binding) {
+				return vm.raise(fn, start, bottom, newError("cannot assign to constant: %s", name))
+			}
+
+		case code.OpAssign:
+			env.AssignResolved(constantName(constants, ins[ip:]), readBinding(ins[ip + 2:]), vm.pop())
+			ip += 7
+
+		case code.OpPushScope:
+			env = object.NewScopeEnvironment(env, fn.Blocks[code.ReadUint16(ins[ip:])])
+			ip += 2
+
+		case code.OpPopScope:
+			env = env.Outer()
+
+		case code.OpArray:
+			count := int(code.ReadUint16(ins[ip:]))
+			ip += 2
+
+			if vm.underflow(f, count) {
+				return vm.raise(fn, start, bottom, invalidBytecode("stack underflow at instruction %d", start))
+			}
+			elements := slices.Clone(vm.stack[len(vm.stack) - count:])
+			vm.stack = vm.stack[:len(vm.stack) - count]
+			vm.push(&object.Array{ Elements: elements })
+
+		case code.OpHash:
+			count := int(code.ReadUint16(ins[ip:]))
+			ip += 2
+
+			if vm.underflow(f, 2*count) {
+				return vm.raise(fn, start, bottom, invalidBytecode("stack underflow at instruction %d", start))
+			}
+			pairs := vm.stack[len(vm.stack) - 2*count:]
+			hash := object.NewHash()
+
+			for i := 0; i < len(pairs); i += 2 {
+				key, ok := pairs[i].(object.Hashable)
+
+				if !ok {
+					return vm.raise(fn, start, bottom, newError("unusable as hash key: %s", pairs[i].Type()))
+				}
+				hash.Set(key, pairs[i + 1])
+			}
+			vm.stack = vm.stack[:len(vm.stack) - 2*count]
+			vm.push(hash)
+
+		case code.OpIndex:
+			index := vm.pop()
+			result := object.Index(vm.pop(), index)
+
+			if isError(result) {
+				return vm.raise(fn, start, bottom, result)
+			}
+			vm.push(result)
+
+		case code.OpIndexForUpdate:
+			index := vm.pop()
+			left := vm.pop()
+			array, ok := left.(*object.Array)
+
+			if !ok {
+				return vm.raise(fn, start, bottom, newError("cannot assign to an index of %s", left.Type()))
+			}
+			i, err := object.ResolveIndex(index, int64(len(array.Elements)))
+
+			if err != nil {
+				return vm.raise(fn, start, bottom, err)
+			}
+			vm.push(array)
+			vm.push(object.NewInteger(i))
+			vm.push(array.Elements[i])
+
+		case code.OpSetIndex:
+			updated := vm.pop()
+			result := vm.pop()
+			i, isIndex := vm.pop().(*object.Integer)
+			array, isArray := vm.pop().(*object.Array)
+
+			if !isIndex || !isArray || i.Value < 0 || i.Value >= int64(len(array.Elements)) {
+				return vm.raise(fn, start, bottom, invalidBytecode("%s without an array element to set at instruction %d", op, start))
+			}
+			array.Elements[i.Value] = updated
+			vm.push(result)
+
+		case code.OpSlice:
+			given := ins[ip]
+			ip++
+
+			if vm.underflow(f, 1 + bits.OnesCount8(given & 7)) {
+				return vm.raise(fn, start, bottom, invalidBytecode("stack underflow at instruction %d", start))
+			}
+			bounds := make([]object.Object, 3)
+
+			for i := 2; i >= 0; i-- {
+				if given & (1 << i) != 0 {
+					bounds[i] = vm.pop()
+				}
+			}
+			result := object.Slice(vm.pop(), bounds[0], bounds[1], bounds[2])
+
+			if isError(result) {
+				return vm.raise(fn, start, bottom, result)
+			}
+			vm.push(result)
+
+		case code.OpRange:
+			inclusive := ins[ip] == 1
+			ip++
+
+			end := vm.pop()
+			result := object.NewRange(vm.pop(), end, inclusive)
+
+			if isError(result) {
+				return vm.raise(fn, start, bottom, result)
+			}
+			vm.push(result)
+
+		case code.OpMember:
+			name := constantName(constants, ins[ip:])
+			ip += 2
+
+			result := object.Member(vm.pop(), name)
+
+			if isError(result) {
+				return vm.raise(fn, start, bottom, result)
+			}
+			vm.push(result)
+
+		case code.OpJump:
+			ip = int(code.ReadUint16(ins[ip:]))
+
+		case code.OpJumpNotTruthy:
+			if !object.IsTruthy(vm.pop()) {
+				ip = int(code.ReadUint16(ins[ip:]))
+			} else {
+				ip += 2
+			}
+
+		case code.OpClosure:
+			compiled := constants[code.ReadUint16(ins[ip:])].(*object.CompiledFunction)
+			ip += 2
+
+			vm.push(&object.Closure{ Fn: compiled, Env: env })
+
+		case code.OpNamedArgument:
+			name := constantName(constants, ins[ip:])
+			ip += 2
+
+			vm.push(&namedArgument{ name: name, value: vm.pop() })
+
+		case code.OpSpread:
+			value := vm.pop()
+			array, ok := value.(*object.Array)
+
+			if !ok {
+				return vm.raise(fn, start, bottom, newError("cannot spread %s, expected an ARRAY", value.Type()))
+			}
+			vm.push(&spreadArgument{ array: array })
+
+		case code.OpCall, code.OpTailCall:
+			count := int(ins[ip])
+			ip++
+
+			if vm.underflow(f, count + 1) {
+				return vm.raise(fn, start, bottom, invalidBytecode("stack underflow at instruction %d", start))
+			}
+			site := vm.location(fn, start)
+			positional, named := collectArguments(vm.stack[len(vm.stack) - count:])
+			callee := vm.stack[len(vm.stack) - count - 1]
+			vm.stack = vm.stack[:len(vm.stack) - count - 1]
+
+			closure, ok := callee.(*object.Closure)
+
+			if !ok {
+				var result object.Object
+
+				if method, ok := callee.(*object.BoundMethod); ok {
+					result = vm.callMethod(site, method, positional, named)
+				} else {
+					result = newError("not a function: %s", callee.Type())
+				}
+
+				if isError(result) {
+					return vm.raise(fn, start, bottom, result)
+				}
+				vm.push(result)
+				break
+			}
+			call := &tailCall{ site: site, closure: closure, positional: positional, named: named }
+
+			if op == code.OpTailCall {
+				// A block run by a nested exec pass the call up to its
+				// try statement, which make it in the frame it's run in.
+				if !f.call {
+					vm.stack = vm.stack[:f.base]
+					vm.frames = vm.frames[:bottom]
+
+					return call, true
+				}
+
+				if err := vm.replace(f, call); err != nil {
+					return vm.raise(fn, start, bottom, err)
+				}
+				fn, ins, constants, ip, env = f.fn, f.fn.Instructions, f.fn.Constants, f.ip, f.env
+				break
+			}
+
+			if vm.depth >= vm.MaxCallDepth {
+				err := newErrorAt(site, "maximum call depth exceeded (%d)", vm.MaxCallDepth)
+				err.Kind = object.STACK_OVERFLOW_ERROR
+
+				return vm.raise(fn, start, bottom, err)
+			}
+			fnEnv, err := vm.enter(call)
+
+			if err != nil {
+				return vm.raise(fn, start, bottom, err)
+			}
+			f.ip, f.env = ip, env
+			f = &frame{ fn: closure.Fn, env: fnEnv, base: len(vm.stack), call: true, site: site }
+			vm.frames = append(vm.frames, f)
+			vm.depth++
+
+			fn, ins, constants, ip, env = f.fn, f.fn.Instructions, f.fn.Constants, f.ip, f.env
+
+		case code.OpReturn:
+			result := vm.pop()
+
+			if vm.leave(f, bottom) {
+				return result, true
+			}
+			f = vm.frames[len(vm.frames) - 1]
+			fn, ins, constants, ip, env = f.fn, f.fn.Instructions, f.fn.Constants, f.ip, f.env
+
+			// A body that is empty or ends with a statement
+			// without a value, like a `let`, give null.
+			if result == nil {
+				result = object.NULL
+			}
+			vm.push(result)
+
+		case code.OpEndBlock:
+			result := vm.pop()
+			vm.leave(f, bottom)
+
+			return result, false
+
+		case code.OpIter:
+			iterable := vm.pop()
+			iter, ok := newIterator(iterable)
+
+			if !ok {
+				return vm.raise(fn, start, bottom, newError("cannot iterate over %s", iterable.Type()))
+			}
+			vm.push(iter)
+
+		case code.OpIterNext:
+			iter, ok := vm.stack[len(vm.stack) - 1].(*iterator)
+
+			if !ok {
+				return vm.raise(fn, start, bottom, invalidBytecode("%s without an iterator at instruction %d", op, start))
+			}
+
+			if iter.next >= iter.length {
+				vm.pop()
+				ip = int(code.ReadUint16(ins[ip:]))
+				break
+			}
+			ip += 2
+
+			if err := vm.checkBudgets(vm.location(fn, start)); err != nil {
+				return vm.raise(fn, start, bottom, err)
+			}
+			vm.push(iter.item())
+
+		case code.OpThrow:
+			return vm.raise(fn, start, bottom, object.Throw(vm.pop()))
+
+		case code.OpRaise:
+			message := constantName(constants, ins[ip:])
+
+			return vm.raise(fn, start, bottom, newError("%s", message))
+
+		case code.OpTry:
+			catch := int(code.ReadUint16(ins[ip:]))
+			finally := int(code.ReadUint16(ins[ip + 2:]))
+			end := int(code.ReadUint16(ins[ip + 4:]))
+			ip += 6
+
+			result, returned := vm.execTry(fn, ip, env, catch, finally)
+
+			if isError(result) {
+				return vm.raise(fn, start, bottom, result)
+			}
+			ip = end
+
+			if !returned {
+				vm.push(result)
+				break
+			}
+
+			// A return from one of the blocks return from f,
+			// making the call it pass up as a tail call.
+			if call, ok := result.(*tailCall); ok && f.call {
+				if err := vm.replace(f, call); err != nil {
+					return vm.raise(fn, start, bottom, err)
+				}
+				fn, ins, constants, ip, env = f.fn, f.fn.Instructions, f.fn.Constants, f.ip, f.env
+				break
+			}
+
+			if vm.leave(f, bottom) {
+				return result, true
+			}
+			f = vm.frames[len(vm.frames) - 1]
+			fn, ins, constants, ip, env = f.fn, f.fn.Instructions, f.fn.Constants, f.ip, f.env
+
+			if result == nil {
+				result = object.NULL
+			}
+			vm.push(result)
+
+		case code.OpImport:
+			path := constantName(constants, ins[ip:])
+			ip += 2
+
+			imported := vm.importModule(vm.location(fn, start), path)
+
+			if isError(imported) {
+				return vm.raise(fn, start, bottom, imported)
+			}
+			vm.push(imported)
+
+		default:
+			return vm.raise(fn, start, bottom, newError("unknown opcode: %d", op))
+		}
+	}
+}
+
+// execTry run the try block starting at ip, then the catch block if the
+// try block raised an error, and always the finally block last. An error
+// or a return value of the finally block replace the one pending from
+// the previous blocks. Budget errors can't be caught nor replaced by the
+// finally block, so a script can't escape the limits it's run with.
+func (vm *VM) execTry(fn *object.CompiledFunction, ip int, env *object.Environment, catch, finally int) (object.Object, bool) {
+	result, returned := vm.exec(&frame{ fn: fn, ip: ip, env: env, base: len(vm.stack) })
+
+	if err, ok := result.(*object.Error); ok && catch != 0 && err.Kind != object.BUDGET_EXCEEDED_ERROR {
+		base := len(vm.stack)
+
+		// The catch block start by binding or dropping the error.
+		vm.push(&object.ErrorValue{ Error: err })
+		result, returned = vm.exec(&frame{ fn: fn, ip: catch, env: env, base: base })
+	}
+
+	if finally == 0 {
+		return result, returned
+	}
+
+	if call, ok := result.(*tailCall); ok && returned {
+		result = vm.callFunction(call)
+		returned = !isError(result)
+	}
+	value, finallyReturned := vm.exec(&frame{ fn: fn, ip: finally, env: env, base: len(vm.stack) })
+
+	if err, ok := result.(*object.Error); ok && err.Kind == object.BUDGET_EXCEEDED_ERROR {
+		return err, false
+	}
+
+	if isError(value) || finallyReturned {
+		return value, finallyReturned
+	}
+
+	return result, returned
+}
+
+// leave drop the frame f and the values it pushed, reporting whether it's
+// the frame exec was called with, the last one that exec has to run.
+func (vm *VM) leave(f *frame, bottom int) bool {
+	vm.stack = vm.stack[:f.base]
+	vm.frames = vm.frames[:len(vm.frames) - 1]
+
+	if f.call {
+		vm.depth--
+	}
+
+	return len(vm.frames) == bottom
+}
+
+// replace make call, a tail call, in the frame f of the call it's made
+// from. An error binding its arguments is raised from the caller of f,
+// as f is already done.
+func (vm *VM) replace(f *frame, call *tailCall) *object.Error {
+	fnEnv, err := vm.enter(call)
+
+	if err != nil {
+		f.call = false
+		vm.depth--
+
+		return err
+	}
+	vm.stack = vm.stack[:f.base]
+	f.fn, f.ip, f.env = call.closure.Fn, 0, fnEnv
+
+	return nil
+}
+
+// raise locate err at the instruction at offset of fn, when it has no
+// position yet, then unwind the frames exec run from bottom, adding the
+// calls to the stack of err and dropping the values they pushed.
+func (vm *VM) raise(fn *object.CompiledFunction, offset, bottom int, err object.Object) (object.Object, bool) {
+	if err, ok := err.(*object.Error); ok && err.Line == 0 {
+		site := vm.location(fn, offset)
+
+		err.File = site.File
+		err.Line = site.Line
+		err.Column = site.Column
+	}
+
+	for len(vm.frames) > bottom {
+		f := vm.frames[len(vm.frames) - 1]
+
+		if err, ok := err.(*object.Error); ok && f.call {
+			addStackFrame(err, f.fn, f.site)
+		}
+		vm.leave(f, bottom)
+	}
+
+	return err, false
+}
+
+// location return the source position of the instruction at offset,
+// as a stack frame to be named.
+func (vm *VM) location(fn *object.CompiledFunction, offset int) object.StackFrame {
+	position, _ := fn.PositionAt(offset)
+
+	return object.StackFrame{ File: fn.File, Line: position.Line, Column: position.Column }
+}
+
+// underflow report whether the frame f pushed less than n values on the
+// stack, which only happen with bytecode that isn't validated.
+func (vm *VM) underflow(f *frame, n int) bool {
+	return len(vm.stack) - f.base < n
+}
+
+func (vm *VM) push(obj object.Object) {
+	vm.stack = append(vm.stack, obj)
+}
+
+func (vm *VM) pop() object.Object {
+	obj := vm.stack[len(vm.stack) - 1]
+	vm.stack = vm.stack[:len(vm.stack) - 1]
+
+	return obj
+}
+
+func constantName(constants []object.Object, operand code.Instructions) string {
+	return constants[code.ReadUint16(operand)].(*object.String).Value
+}
+
+// readBinding decode the binding operands of an instruction on a name:
+// its kind, depth and slot.
+func readBinding(operand code.Instructions) ast.Binding {
+	return ast.Binding{
+		Kind: ast.BindingKind(operand[0]),
+		Depth: int(code.ReadUint16(operand[1:])),
+		Slot: int(code.ReadUint16(operand[3:])),
+	}
+}
+
+
+// tailCall is a call whose callee and arguments are evaluated but which
+// is not made yet. A function ending with a call return it that way to
+// callFunction, which make it in its loop instead of recursing.
+type tailCall struct {
+	site		object.StackFrame
+	closure		*object.Closure
+	positional	[]object.Object
+	named		map[string]object.Object
+}
+
+func (tc *tailCall) Type() object.ObjectType { return object.TAIL_CALL_OBJ }
+func (tc *tailCall) Inspect() string { return tc.closure.Inspect() }
+
+// namedArgument is an argument passed by name, as pushed before a call.
+type namedArgument struct {
+	name		string
+	value		object.Object
+}
+
+func (na *namedArgument) Type() object.ObjectType { return object.ARGUMENT_OBJ }
+func (na *namedArgument) Inspect() string { return na.name + ": " + na.value.Inspect() }
+
+// spreadArgument is an array whose elements are passed as arguments.
+type spreadArgument struct {
+	array		*object.Array
+}
+
+func (sa *spreadArgument) Type() object.ObjectType { return object.ARGUMENT_OBJ }
+func (sa *spreadArgument) Inspect() string { return "..." + sa.array.Inspect() }
+
+// collectArguments sort the arguments of a call into the positional
+// and the named ones, expanding spread arrays.
+func collectArguments(args []object.Object) ([]object.Object, map[string]object.Object) {
+	positional := []object.Object{}
+	named := map[string]object.Object{}
+
+	for _, arg := range args {
+
+		switch arg := arg.(type) {
+
+		case *namedArgument:
+			named[arg.name] = arg.value
+
+		case *spreadArgument:
+			positional = append(positional, arg.array.Elements...)
+
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	return positional, named
+}
+
+// callFunction make call, then every tail call its body end with,
+// until a body produce an actual value. It's used to call functions
+// from Go, as exec make the calls of the instructions itself.
+//
+// An error raised by a body get the call added to its stack. As a tail
+// call replace the call it's made from, only the last function of a
+// chain of tail calls appear in the stack, at the site of the first call.
+func (vm *VM) callFunction(call *tailCall) object.Object {
+	if vm.depth >= vm.MaxCallDepth {
+		err := newErrorAt(call.site, "maximum call depth exceeded (%d)", vm.MaxCallDepth)
+		err.Kind = object.STACK_OVERFLOW_ERROR
+
+		return err
+	}
+	fnEnv, err := vm.enter(call)
+
+	if err != nil {
+		return err
+	}
+	vm.depth++
+
+	result, _ := vm.exec(&frame{ fn: call.closure.Fn, env: fnEnv, base: len(vm.stack), call: true, site: call.site })
+
+	// A body that is empty or ends with a statement
+	// without a value, like a `let`, give null.
+	if result == nil {
+		return object.NULL
+	}
+
+	return result
+}
+
+// enter check the budgets before call, then return the environment
+// its body is run in.
+func (vm *VM) enter(call *tailCall) (*object.Environment, *object.Error) {
+	if err := vm.checkBudgets(call.site); err != nil {
+		return nil, err
+	}
+
+	return vm.bindArguments(call)
+}
+
+// callMethod call a builtin method, giving it the way to call back the
+// functions it receive. Errors it raise are located at the call.
+func (vm *VM) callMethod(site object.StackFrame, method *object.BoundMethod, positional []object.Object, named map[string]object.Object) object.Object {
+	if len(named) != 0 {
+		return newErrorAt(site, "method %s does not accept named arguments", method.Name)
+	}
+	apply := func(fn object.Object, args ...object.Object) object.Object {
+		var result object.Object
+
+		switch fn := fn.(type) {
+
+		case *object.Closure:
+			result = vm.callFunction(&tailCall{ site: site, closure: fn, positional: args, named: map[string]object.Object{} })
+
+		case *object.BoundMethod:
+			result = vm.callMethod(site, fn, args, nil)
+
+		default:
+			return newErrorAt(site, "not a function: %s", fn.Type())
+		}
+
+		if result == nil {
+			return object.NULL
+		}
+		return result
+	}
+
+	return locateError(method.Method(apply, method.Receiver, positional...), site)
+}
+
+// bindArguments return the environment of a call, where each parameter
+// is bound to, in order of priority, its positional argument, its named
+// argument or its default value. Default values are evaluated in that
+// environment, so they can refer to the previous parameters. A rest
+// parameter collect the remaining positional arguments in an array.
+func (vm *VM) bindArguments(call *tailCall) (*object.Environment, *object.Error) {
+	fn := call.closure.Fn
+	fnEnv := object.NewScopeEnvironment(call.closure.Env, fn.Scope)
+	positional, named := call.positional, call.named
+	hasRest := false
+
+	for i, param := range fn.Parameters {
+		name := param.Name
+
+		if param.Rest {
+			hasRest = true
+			rest := []object.Object{}
+
+			if i < len(positional) {
+				rest = append(rest, positional[i:]...)
+			}
+			fnEnv.SetResolved(name, paramBinding(i), &object.Array{ Elements: rest }, false)
+
+			if _, ok := named[name]; ok {
+				return nil, newErrorAt(call.site, "cannot pass rest parameter %s by name", name)
+			}
+			continue
+		}
+
+		value, isNamed := named[name]
+		delete(named, name)
+
+		switch {
+
+		case i < len(positional) && isNamed:
+			return nil, newErrorAt(call.site, "got multiple values for parameter %s", name)
+
+		case i < len(positional):
+			value = positional[i]
+
+		case isNamed:
+
+		case param.Default != nil:
+			value, _ = vm.exec(&frame{ fn: param.Default, env: fnEnv, base: len(vm.stack) })
+
+			if isError(value) {
+				return nil, value.(*object.Error)
+			}
+
+		default:
+			return nil, newErrorAt(call.site, "missing argument for parameter %s", name)
+		}
+
+		fnEnv.SetResolved(name, paramBinding(i), value, false)
+	}
+
+	if !hasRest && len(positional) > len(fn.Parameters) {
+		return nil, newErrorAt(
+			call.site,
+			"too many arguments: want at most %d, got %d",
+			len(fn.Parameters), len(positional),
+		)
+	}
+
+	if len(named) > 0 {
+		name := slices.Sorted(maps.Keys(named))[0]
+
+		return nil, newErrorAt(call.site, "unknown parameter name: %s", name)
+	}
+
+	return fnEnv, nil
+}
+
+// paramBinding return the binding of the parameter at index i, the
+// resolver giving the parameters the first slots of the function scope.
+func paramBinding(i int) ast.Binding {
+	return ast.Binding{ Kind: ast.LOCAL, Slot: i }
+}
+
+// checkBudgets return a BudgetExceededError located at site if the
+// context of the execution is done.
+func (vm *VM) checkBudgets(site object.StackFrame) *object.Error {
+	if vm.ctx.Err() == nil {
+		return nil
+	}
+	err := newErrorAt(site, "evaluation stopped: %s", vm.ctx.Err())
+	err.Kind = object.BUDGET_EXCEEDED_ERROR
+
+	return err
+}
+
+func addStackFrame(err *object.Error, fn *object.CompiledFunction, site object.StackFrame) *object.Error {
+	frame := site
+	frame.Function = fn.Name
+
+	if frame.Function == "" {
+		frame.Function = "<anonymous>"
+	}
+	err.Stack = append(err.Stack, frame)
+
+	return err
+}
+
+
+// iterator walk the items of an array, the one-character strings of
+// a string or the integers of a range, for a `for-in` loop. Over a
+// string, next and length are offsets in bytes.
+type iterator struct {
+	iterable	object.Object
+	length		int64
+	next		int64
+}
+
+func newIterator(iterable object.Object) (*iterator, bool) {
+
+	switch iterable := iterable.(type) {
+
+	case *object.Array:
+		return &iterator{ iterable: iterable, length: int64(len(iterable.Elements)) }, true
+
+	case *object.String:
+		return &iterator{ iterable: iterable, length: int64(len(iterable.Value)) }, true
+
+	case *object.Range:
+		return &iterator{ iterable: iterable, length: iterable.Len() }, true
+	}
+
+	return nil, false
+}
+
+func (it *iterator) Type() object.ObjectType { return object.ITERATOR_OBJ }
+func (it *iterator) Inspect() string { return "<iterator " + it.iterable.Inspect() + ">" }
+
+// item return the next item and move past it.
+func (it *iterator) item() object.Object {
+	i := it.next
+	it.next++
+
+	switch iterable := it.iterable.(type) {
+
+	case *object.Array:
+		return iterable.Elements[i]
+
+	case *object.String:
+		_, size := utf8.DecodeRuneInString(iterable.Value[i:])
+		it.next = i + int64(size)
+
+		return &object.String{ Value: iterable.Value[i:it.next] }
+
+	default:
+		return object.NewInteger(iterable.(*object.Range).Start + i)
+	}
+}
+
+
+func newError(format string, args ...any) *object.Error {
+	return &object.Error{ Message: fmt.Sprintf(format, args...) }
+}
+
+// invalidBytecode return the error of an instruction that can't be run.
+func invalidBytecode(format string, args ...any) *object.Error {
+	return newError("invalid bytecode: " + format, args...)
+}
+
+// newErrorAt return an error located at site.
+func newErrorAt(site object.StackFrame, format string, args ...any) *object.Error {
+	err := newError(format, args...)
+	err.File = site.File
+	err.Line = site.Line
+	err.Column = site.Column
+
+	return err
+}
+
+// locateError set the position of obj to site when obj is an error
+// raised without a position, like the ones of builtin methods.
+func locateError(obj object.Object, site object.StackFrame) object.Object {
+	if err, ok := obj.(*object.Error); ok && err.Line == 0 {
+		err.File = site.File
+		err.Line = site.Line
+		err.Column = site.Column
+	}
+
+	return obj
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR_OBJ
+}
@@ -0,0 +1,395 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"monkey/internal/ast"
+	"monkey/internal/code"
+	"monkey/internal/compiler"
+	"monkey/internal/corpus"
+	"monkey/internal/evaluator"
+	"monkey/internal/lexer"
+	"monkey/internal/object"
+	"monkey/internal/optimizer"
+	"monkey/internal/parser"
+	"monkey/internal/resolver"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+
+// The snippets of the tests of the evaluator must give the same results
+// when compiled and run by the VM.
+func TestRunParity(t *testing.T) {
+	inputs := make([]string, len(corpus.SNIPPETS))
+	evaluated := make([]string, len(corpus.SNIPPETS))
+
+	for i, snippet := range corpus.SNIPPETS {
+		inputs[i] = snippet.Input
+		evaluated[i] = describe(evaluator.Eval(parse(t, snippet.Input), object.NewEnvironment()))
+	}
+
+	t.Run("it should give the same results as the evaluator", func(t *testing.T) {
+		for i, input := range inputs {
+			got := describe(testRun(t, parse(t, input)))
+
+			if got != evaluated[i] {
+				t.Errorf("[test #%d] %s\nExpecting %q, but got %q\n", i, input, evaluated[i], got)
+			}
+		}
+	})
+
+	t.Run("it should give the same results once optimized", func(t *testing.T) {
+		for i, input := range inputs {
+			got := describe(testRun(t, optimizer.Optimize(parse(t, input))))
+
+			if got != evaluated[i] {
+				t.Errorf("[test #%d] %s\nExpecting %q, but got %q\n", i, input, evaluated[i], got)
+			}
+		}
+	})
+
+	t.Run("it should give the same results with the names resolved to slots", func(t *testing.T) {
+		for i, input := range inputs {
+			program := parse(t, input)
+			resolver.Resolve(program)
+
+			got := describe(testRun(t, program))
+
+			if got != evaluated[i] {
+				t.Errorf("[test #%d] %s\nExpecting %q, but got %q\n", i, input, evaluated[i], got)
+			}
+		}
+	})
+
+	t.Run("it should give the same results once written to and read from a file", func(t *testing.T) {
+		for i, input := range inputs {
+			program := parse(t, input)
+			resolver.Resolve(program)
+
+			bytecode, err := compiler.Compile(program)
+
+			if err != nil {
+				t.Fatalf("[test #%d]: Expecting no compilation error, but got %s\n", i, err)
+			}
+			data, err := compiler.Marshal(bytecode)
+
+			if err != nil {
+				t.Fatalf("[test #%d]: Expecting no error, but got %s\n", i, err)
+			}
+			decoded, err := compiler.Unmarshal(data)
+
+			if err != nil {
+				t.Fatalf("[test #%d] %s\nExpecting no error, but got %s\n", i, input, err)
+			}
+			got := describe(Run(decoded, object.NewEnvironment()))
+
+			if got != evaluated[i] {
+				t.Errorf("[test #%d] %s\nExpecting %q, but got %q\n", i, input, evaluated[i], got)
+			}
+		}
+	})
+}
+
+// TestRunDeepParity is TestRunParity for the snippets going through deep
+// calls, which are run once, with the names resolved to slots.
+func TestRunDeepParity(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the deep snippets in short mode")
+	}
+
+	for i, snippet := range corpus.DEEP_SNIPPETS {
+		expected := describe(evaluator.Eval(parse(t, snippet.Input), object.NewEnvironment()))
+
+		program := parse(t, snippet.Input)
+		resolver.Resolve(program)
+
+		if got := describe(testRun(t, program)); got != expected {
+			t.Errorf("[test #%d] %s\nExpecting %q, but got %q\n", i, snippet.Input, expected, got)
+		}
+	}
+}
+
+func TestRunTailCall(t *testing.T) {
+
+	t.Run("it should run tail calls without growing the stack", func(t *testing.T) {
+		input := "fn count(n, acc) { if n == 0 { acc } else { count(n - 1, acc + 1) } } count(100000, 0)"
+		evaluated := testRun(t, parse(t, input))
+
+		integer, ok := evaluated.(*object.Integer)
+
+		if !ok || integer.Value != 100000 {
+			t.Fatalf("Expecting 100000, but got %s\n", describe(evaluated))
+		}
+	})
+
+	t.Run("it should keep the last call of a chain of tail calls at the site of the first one", func(t *testing.T) {
+		input := "fn a() { b() }\nfn b() { missing }\na()"
+		evaluated := testRun(t, parse(t, input))
+
+		err, ok := evaluated.(*object.Error)
+
+		if !ok {
+			t.Fatalf("Expecting an error, but got %s\n", describe(evaluated))
+		}
+
+		if len(err.Stack) != 1 || err.Stack[0].String() != "b (<input>:3:2)" {
+			t.Fatalf("Expecting the stack to be [b (<input>:3:2)], but got %v\n", err.Stack)
+		}
+	})
+}
+
+func TestRunCallDepth(t *testing.T) {
+
+	t.Run("it should stop a runaway recursion with a StackOverflowError", func(t *testing.T) {
+		program := parse(t, "fn f(n) { 1 + f(n + 1) } f(0)")
+		bytecode, _ := compiler.Compile(program)
+
+		machine := New()
+		machine.MaxCallDepth = 50
+		evaluated := machine.Run(bytecode, object.NewEnvironment())
+
+		err, ok := evaluated.(*object.Error)
+
+		if !ok || err.Kind != object.STACK_OVERFLOW_ERROR {
+			t.Fatalf("Expecting a StackOverflowError, but got %s\n", describe(evaluated))
+		}
+
+		if len(err.Stack) != 50 {
+			t.Fatalf("Expecting 50 frames in the stack, but got %d\n", len(err.Stack))
+		}
+	})
+}
+
+func TestRunContext(t *testing.T) {
+
+	t.Run("it should stop when the context is cancelled", func(t *testing.T) {
+		program := parse(t, "for i in 0..1000000000 { }")
+		bytecode, _ := compiler.Compile(program)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		evaluated := New().RunContext(ctx, bytecode, object.NewEnvironment())
+		err, ok := evaluated.(*object.Error)
+
+		if !ok || err.Kind != object.BUDGET_EXCEEDED_ERROR {
+			t.Fatalf("Expecting a BudgetExceededError, but got %s\n", describe(evaluated))
+		}
+	})
+
+	t.Run("it should not let a finally block replace a budget error", func(t *testing.T) {
+		program := parse(t, "fn f() { try { for i in 0..1000000000 { } } finally { return 1 } } f()")
+		bytecode, _ := compiler.Compile(program)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Millisecond)
+		defer cancel()
+
+		evaluated := New().RunContext(ctx, bytecode, object.NewEnvironment())
+		err, ok := evaluated.(*object.Error)
+
+		if !ok || err.Kind != object.BUDGET_EXCEEDED_ERROR {
+			t.Fatalf("Expecting a BudgetExceededError, but got %s\n", describe(evaluated))
+		}
+	})
+}
+
+func TestRunCorruptedBytecode(t *testing.T) {
+
+	t.Run("it should return an error for an instruction it can't run", func(t *testing.T) {
+		tests := []struct{
+			instructions	[][]byte
+			expected		string
+		}{
+			{
+				[][]byte{ code.Make(code.OpPop) },
+				"invalid bytecode: stack underflow at instruction 0",
+			},
+			{
+				[][]byte{ code.Make(code.OpNull), code.Make(code.OpArray, 2) },
+				"invalid bytecode: stack underflow at instruction 1",
+			},
+			{
+				[][]byte{ code.Make(code.OpNull), code.Make(code.OpCall, 1) },
+				"invalid bytecode: stack underflow at instruction 1",
+			},
+			{
+				[][]byte{ code.Make(code.OpTrue), code.Make(code.OpIterNext, 0) },
+				"invalid bytecode: OpIterNext without an iterator at instruction 1",
+			},
+			{
+				[][]byte{
+					code.Make(code.OpTrue), code.Make(code.OpTrue), code.Make(code.OpTrue), code.Make(code.OpTrue),
+					code.Make(code.OpSetIndex),
+				},
+				"invalid bytecode: OpSetIndex without an array element to set at instruction 4",
+			},
+		}
+
+		for i, tt := range tests {
+			instructions := slices.Concat(append(tt.instructions, code.Make(code.OpReturn))...)
+			bytecode := &compiler.Bytecode{ Main: &object.CompiledFunction{ Instructions: instructions } }
+
+			evaluated := Run(bytecode, object.NewEnvironment())
+			err, ok := evaluated.(*object.Error)
+
+			if !ok || err.Message != tt.expected {
+				t.Fatalf("[test #%d]: Expecting the error %q, but got %s\n", i, tt.expected, describe(evaluated))
+			}
+		}
+	})
+
+	t.Run("it should not panic on the corrupted files the format accept", func(t *testing.T) {
+		program := parse(t, strings.Join([]string{
+			`fn f(n, step = 1) { if n > 0 { f(n - step) } else { [n, "a"][0] } }`,
+			`let h = { "a": 1 }; let a = [1, 2]; a[0]++;`,
+			`try { for x in 0..3 { h.a + x } } catch e { e } finally { f(2) }`,
+		}, "\n"))
+		resolver.Resolve(program)
+
+		bytecode, _ := compiler.Compile(program)
+		data, _ := compiler.Marshal(bytecode)
+		body := data[:len(data) - 4]
+
+		for i := range body {
+			corrupted := bytes.Clone(body)
+			corrupted[i] ^= 0x5a
+			corrupted = binary.BigEndian.AppendUint32(corrupted, crc32.ChecksumIEEE(corrupted))
+
+			decoded, err := compiler.Unmarshal(corrupted)
+
+			if err != nil {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Millisecond)
+
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Fatalf("Expecting no panic for a file corrupted at byte %d, but got %v\n", i, r)
+					}
+				}()
+				New().RunContext(ctx, decoded, object.NewEnvironment())
+			}()
+			cancel()
+		}
+	})
+}
+
+func TestRunModules(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"lib/math.mk": "export const base = 7; export fn square(x) { x * x } let loads = 0; export fn load() { loads++; loads }",
+		"a.mk": `import "b.mk" as b`,
+		"b.mk": `import "a.mk" as a`,
+		"main.mk": `import "back.mk" as back`,
+		"back.mk": `import "main.mk" as main`,
+	}
+
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("it should import modules like the evaluator", func(t *testing.T) {
+		tests := []string{
+			`import "lib/math.mk" as math math.square(math.base)`,
+			`import "lib/math.mk" as first import "lib/math.mk" as second first.load(); second.load()`,
+			`import "lib/math.mk" as math math.loads`,
+			`import "a.mk" as a`,
+			`import "back.mk" as back`,
+			`import "nope.mk" as nope`,
+		}
+
+		for i, input := range tests {
+			file := filepath.Join(dir, "main.mk")
+			program := parser.New(lexer.NewFile(file, input)).ParseProgram()
+
+			expected := describe(evaluator.Eval(program, object.NewEnvironment()))
+			got := describe(testRun(t, program))
+
+			if got != expected {
+				t.Errorf("[test #%d] %s\nExpecting %q, but got %q\n", i, input, expected, got)
+			}
+		}
+	})
+}
+
+func BenchmarkRunCorpus(b *testing.B) {
+	for _, program := range corpus.Programs() {
+		b.Run(program.Name, func(b *testing.B) {
+			parsed := parser.New(lexer.NewFile(program.File, program.Source)).ParseProgram()
+			resolver.Resolve(parsed)
+
+			bytecode, err := compiler.Compile(parsed)
+
+			if err != nil {
+				b.Fatalf("Expecting no compilation error, but got %s\n", err)
+			}
+
+			b.ReportAllocs()
+
+			for b.Loop() {
+				Run(bytecode, object.NewEnvironment())
+			}
+		})
+	}
+}
+
+
+// Helpers functions:
+
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("Expecting no parser errors for %q, but got %v\n", input, p.Errors())
+	}
+
+	return program
+}
+
+func testRun(t *testing.T, program *ast.Program) object.Object {
+	bytecode, err := compiler.Compile(program)
+
+	if err != nil {
+		t.Fatalf("Expecting no compilation error, but got %s\n", err)
+	}
+
+	return Run(bytecode, object.NewEnvironment())
+}
+
+// describe return what is compared between the engines: the type and
+// printed value of a result, or the stack trace of an error. Functions
+// are only compared by type, as compiled functions have no source.
+func describe(obj object.Object) string {
+	switch obj := obj.(type) {
+
+	case nil:
+		return "<nil>"
+
+	case *object.Error:
+		return obj.Kind + " " + obj.StackTrace()
+
+	case *object.Function, *object.Closure:
+		return obj.Type().String()
+	}
+
+	return obj.Type().String() + " " + obj.Inspect()
+}